@@ -0,0 +1,88 @@
+package abicodec
+
+import (
+	"net/http"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+type Var = simulator.Var
+
+type Input struct {
+	// Signature is a method, event or error, e.g.
+	// "transfer(address to, uint256 amount)", or arguments as ethabidecode
+	// takes them, e.g. "uint256 answer, uint256 updatedAt"
+	Signature string
+	// Args are encoded, one for each of the signature's arguments, unless
+	// there is Data or Topics to decode
+	Args   []Var
+	Data   string
+	Topics []string
+}
+
+type Response struct {
+	Encoded *contracts.Encoded `json:"encoded,omitempty"`
+	Decoded *contracts.Decoded `json:"decoded,omitempty"`
+	Error   string             `json:"error"`
+}
+
+// Handler ABI-encodes typed values against a signature, returning the
+// calldata, selector and topics, or decodes hex back against it, so that
+// inputs for ethabidecode and ethabidecodelog can be prepared and the
+// output of ethabiencode checked.
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
+
+	response := Response{}
+
+	var err error
+	if input.Data != "" || len(input.Topics) > 0 {
+		response.Decoded, err = decode(input)
+	} else {
+		response.Encoded, err = encode(input)
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	jsonSer := pipeline.JSONSerializable{
+		Valid: true,
+		Val:   response,
+	}
+
+	jData, errJson := jsonSer.MarshalJSON()
+	if errJson != nil {
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jData)
+}
+
+func encode(input Input) (*contracts.Encoded, error) {
+	signature, err := contracts.ParseSignature(input.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(input.Args))
+	for i, arg := range input.Args {
+		if values[i], err = simulator.ConvertVar(arg); err != nil {
+			return nil, err
+		}
+	}
+	return signature.Encode(values)
+}
+
+func decode(input Input) (*contracts.Decoded, error) {
+	signature, err := contracts.ParseSignature(input.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return signature.DecodeHex(input.Data, input.Topics)
+}
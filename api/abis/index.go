@@ -0,0 +1,57 @@
+package abis
+
+import (
+	"net/http"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/middleware"
+)
+
+type Input struct {
+	Contracts []contracts.Source
+}
+
+type Contract struct {
+	Name    string             `json:"name"`
+	Members []contracts.Member `json:"members"`
+}
+
+type Response struct {
+	Contracts []Contract `json:"contracts"`
+	Error     string     `json:"error"`
+}
+
+// Handler parses uploaded contract ABIs and lists their methods, events and
+// errors with the Contract.method references specs can use for them. The
+// registry isn't kept; the frontend sends it along with the specs and tasks
+// that refer to it.
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
+
+	response := Response{Contracts: []Contract{}}
+
+	registry, err := contracts.Load(input.Contracts)
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		for _, c := range registry.Contracts() {
+			response.Contracts = append(response.Contracts, Contract{Name: c.Name, Members: c.Members()})
+		}
+	}
+
+	jsonSer := pipeline.JSONSerializable{
+		Valid: true,
+		Val:   response,
+	}
+
+	jData, errJson := jsonSer.MarshalJSON()
+	if errJson != nil {
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jData)
+}
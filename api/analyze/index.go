@@ -0,0 +1,73 @@
+package analyze
+
+import (
+	"net/http"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+// Input is a run whose BackoffCompression and Faults apply to every trial.
+type Input struct {
+	simulator.RunRequest
+	Sources []simulator.SourceModel
+	Target  string
+	Trials  int
+	Seed    int64
+}
+
+type Response struct {
+	Analysis *simulator.Analysis `json:"analysis"`
+	Error    string              `json:"error"`
+}
+
+// Handler runs a Monte Carlo analysis of how robust an aggregated answer is to
+// noisy, faulty and failing sources.
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
+
+	response := Response{}
+
+	analysis, err := analyze(r, input)
+	if err != nil {
+		response.Error = err.Error()
+	}
+	response.Analysis = analysis
+
+	jsonSer := pipeline.JSONSerializable{
+		Valid: true,
+		Val:   response,
+	}
+
+	jData, errJson := jsonSer.MarshalJSON()
+	if errJson != nil {
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jData)
+}
+
+func analyze(r *http.Request, input Input) (*simulator.Analysis, error) {
+	// Prepared like a single run, so that the spec's Contract.method
+	// references resolve, with every trial starting from its pipeline, vars
+	// and mocks
+	run, err := input.Prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	return simulator.Analyze(r.Context(), run.Pipeline, run.Vars, run.Mocks, simulator.AnalysisConfig{
+		Sources: input.Sources,
+		Target:  input.Target,
+		Trials:  input.Trials,
+		Seed:    input.Seed,
+	}, simulator.RunOptions{
+		BackoffCompression: input.BackoffCompression,
+		Faults:             input.Faults,
+		Chains:             run.Options.Chains,
+	})
+}
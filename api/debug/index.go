@@ -0,0 +1,148 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+const (
+	CommandStart    = "start"
+	CommandStep     = "step"
+	CommandContinue = "continue"
+	CommandSet      = "set"
+	CommandRerun    = "rerun"
+)
+
+// The debugger is stateless. Every response carries the vars and the task
+// results so far, and the client sends them back with the next command.
+type Input struct {
+	simulator.RunRequest
+	Command     string
+	Results     []simulator.TaskRunResult
+	Breakpoints []simulator.Breakpoint
+	// PausedAt is the task a breakpoint paused the run before, as returned
+	// by the previous command, which "continue" runs rather than pausing
+	// at again
+	PausedAt string
+	// Target is the var to overwrite for "set", e.g. "fetch.price", or the
+	// task to re-run for "rerun"
+	Target   string
+	Value64  string
+	Inputs64 []string
+}
+
+type Response struct {
+	Results    []simulator.TaskRunResult `json:"results"`
+	Vars64     string                    `json:"vars64"`
+	Next       string                    `json:"next"`
+	Breakpoint *simulator.Breakpoint     `json:"breakpoint"`
+	PausedAt   string                    `json:"pausedAt"`
+	Done       bool                      `json:"done"`
+	Summary    *simulator.Summary        `json:"summary"`
+	Error      string                    `json:"error"`
+}
+
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
+
+	response := Response{}
+
+	run, err := restoreRun(input)
+	if err == nil {
+		response.Breakpoint, err = execute(r.Context(), run, input)
+	}
+
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	if run != nil {
+		response.Results, _ = run.EncodeResults()
+		response.Vars64, _ = run.EncodeVars()
+		if next := run.Next(); next != nil {
+			response.Next = next.DotID()
+		}
+		response.PausedAt = run.PausedAt
+		response.Done = run.Done()
+		summary := run.Summarize()
+		response.Summary = &summary
+	}
+
+	jsonSer := pipeline.JSONSerializable{
+		Valid: true,
+		Val:   response,
+	}
+
+	jData, errJson := jsonSer.MarshalJSON()
+	if errJson != nil {
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jData)
+}
+
+func restoreRun(input Input) (*simulator.Run, error) {
+	run, err := input.Prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	// A fresh start ignores any results sent by the client
+	if input.Command != CommandStart {
+		if err := run.LoadResults(input.Results); err != nil {
+			return nil, err
+		}
+		run.PausedAt = input.PausedAt
+	}
+
+	return run, nil
+}
+
+func execute(ctx context.Context, run *simulator.Run, input Input) (*simulator.Breakpoint, error) {
+	switch input.Command {
+	case CommandStart:
+		// Runs start paused before the first task
+		return nil, nil
+	case CommandStep:
+		run.Step(ctx)
+		return nil, nil
+	case CommandContinue:
+		return run.Continue(ctx, input.Breakpoints)
+	case CommandSet:
+		value, err := simulator.FromBase64(input.Value64)
+		if err != nil {
+			return nil, err
+		}
+		return nil, run.SetVar(input.Target, value)
+	case CommandRerun:
+		task, err := run.Task(input.Target)
+		if err != nil {
+			return nil, err
+		}
+
+		var inputs []pipeline.Result
+		if input.Inputs64 != nil {
+			inputs = make([]pipeline.Result, 0, len(input.Inputs64))
+			for _, in64 := range input.Inputs64 {
+				val, err := simulator.FromBase64(in64)
+				if err != nil {
+					return nil, err
+				}
+				inputs = append(inputs, pipeline.Result{Value: val})
+			}
+		}
+
+		_, err = run.Rerun(ctx, task, inputs)
+		return nil, err
+	default:
+		return nil, fmt.Errorf(`unknown command: "%v"`, input.Command)
+	}
+}
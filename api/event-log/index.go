@@ -0,0 +1,70 @@
+package eventlog
+
+import (
+	"net/http"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+type Var = simulator.Var
+
+type Input struct {
+	// Event is in the form ethabidecodelog's abi takes, or a Contract.Event
+	// reference to one of Contracts
+	Event     string
+	Contracts []contracts.Source
+	// Args are the event's arguments, indexed or not, in order
+	Args []Var
+}
+
+type Response struct {
+	Log   *simulator.EventLog `json:"log"`
+	Error string              `json:"error"`
+}
+
+// Handler builds the topics and data of an event log from typed argument
+// values, along with the jobRun vars and ethabidecodelog options that feed it
+// to the task.
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
+
+	response := Response{}
+
+	eventLog, err := buildLog(input)
+	if err != nil {
+		response.Error = err.Error()
+	}
+	response.Log = eventLog
+
+	jsonSer := pipeline.JSONSerializable{
+		Valid: true,
+		Val:   response,
+	}
+
+	jData, errJson := jsonSer.MarshalJSON()
+	if errJson != nil {
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jData)
+}
+
+func buildLog(input Input) (*simulator.EventLog, error) {
+	event := input.Event
+	if contracts.IsReference(event) {
+		registry, err := contracts.Load(input.Contracts)
+		if err != nil {
+			return nil, err
+		}
+		if event, err = registry.Resolve(event, contracts.TaskETHABIDecodeLog); err != nil {
+			return nil, err
+		}
+	}
+	return simulator.BuildEventLog(event, input.Args)
+}
@@ -1,63 +1,31 @@
-package varhelper
+package graph
 
 import (
-	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/jobspec"
 	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
 )
 
 type Input struct {
 	Spec string
 }
 
-type TaskDependency struct {
-	Id              string `json:"id"`
-	PropagateResult bool   `json:"propagateResult"`
-}
-
-type Task struct {
-	Id     string           `json:"id"`
-	Inputs []TaskDependency `json:"inputs"`
-}
-
 type Response struct {
-	Tasks []Task `json:"tasks"`
-	Error string `json:"error"`
+	Tasks []jobspec.Task `json:"tasks"`
+	Error string         `json:"error"`
 }
 
 func Handler(w http.ResponseWriter, r *http.Request) {
 
 	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
 
-	parsed, err := pipeline.Parse(input.Spec)
-
-	if err != nil {
-		fmt.Printf("%v", err)
-	}
-
-	taskArr := []Task{}
-
-	if parsed != nil {
-		for _, element := range parsed.Tasks {
-			task := Task{
-				Id:     element.DotID(),
-				Inputs: []TaskDependency{},
-			}
-			for _, input := range element.Inputs() {
-				task.Inputs = append(task.Inputs, TaskDependency{
-					Id:              input.InputTask.DotID(),
-					PropagateResult: input.PropagateResult,
-				})
-			}
-			taskArr = append(taskArr, task)
-		}
-	}
+	parsed, err := simulator.Parse(input.Spec)
 
 	response := Response{
-		Tasks: taskArr,
+		Tasks: jobspec.Graph(parsed),
 	}
 
 	if err != nil {
@@ -71,7 +39,8 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 	jData, errJson := jsonSer.MarshalJSON()
 	if errJson != nil {
-		log.Fatal("Error marshalling response object to json", errJson)
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,63 @@
+package run
+
+import (
+	"net/http"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+type Input struct {
+	simulator.RunRequest
+}
+
+type Response struct {
+	Results []simulator.TaskRunResult `json:"results"`
+	Vars64  string                    `json:"vars64"`
+	Summary *simulator.Summary        `json:"summary"`
+	// Precision traces the numeric values through the run and flags where
+	// they are rounded, truncated or overflow
+	Precision []simulator.PrecisionTrace `json:"precision"`
+	Error     string                     `json:"error"`
+}
+
+// Handler runs the whole pipeline and reports every task's result along with
+// how errors propagated through the run.
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
+
+	response := Response{}
+
+	run, err := input.Prepare()
+	if err == nil {
+		err = run.Execute(r.Context())
+	}
+
+	if run != nil {
+		summary := run.Summarize()
+		response.Summary = &summary
+		response.Results, _ = run.EncodeResults()
+		response.Vars64, _ = run.EncodeVars()
+		response.Precision = run.AnalyzePrecision()
+	}
+
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	jsonSer := pipeline.JSONSerializable{
+		Valid: true,
+		Val:   response,
+	}
+
+	jData, errJson := jsonSer.MarshalJSON()
+	if errJson != nil {
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jData)
+}
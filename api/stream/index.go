@@ -0,0 +1,99 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+const (
+	EventTaskStarted  = "task-started"
+	EventTaskFinished = "task-finished"
+	EventRunFinished  = "run-finished"
+)
+
+type Input struct {
+	simulator.RunRequest
+}
+
+type TaskStarted struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type RunFinished struct {
+	Results []simulator.TaskRunResult `json:"results"`
+	Vars64  string                    `json:"vars64"`
+	Summary *simulator.Summary        `json:"summary"`
+	Error   string                    `json:"error"`
+}
+
+// Handler runs the whole pipeline and streams its progress as Server-Sent
+// Events. The request is a POST like the other endpoints, so clients read the
+// stream from the fetch response body rather than with EventSource. Closing
+// the connection cancels the run, including any in-flight task.
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(event string, data interface{}) {
+		jData, err := json.Marshal(data)
+		if err != nil {
+			jData, _ = json.Marshal(map[string]string{"error": err.Error()})
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, jData)
+		flusher.Flush()
+	}
+
+	run, err := input.Prepare()
+	if err != nil {
+		send(EventRunFinished, RunFinished{Error: err.Error()})
+		return
+	}
+
+	ctx := r.Context()
+
+	for task := run.Next(); task != nil; task = run.Next() {
+		if ctx.Err() != nil {
+			// The client has gone away so there is no one to tell
+			return
+		}
+
+		send(EventTaskStarted, TaskStarted{
+			Id:   task.DotID(),
+			Type: task.Type().String(),
+		})
+
+		taskRun := run.RunTask(ctx, task, run.Inputs(task))
+
+		encoded, err := taskRun.Encode()
+		if err != nil {
+			encoded = simulator.TaskRunResult{Id: task.DotID(), Error: err.Error()}
+		}
+		send(EventTaskFinished, encoded)
+	}
+
+	summary := run.Summarize()
+	finished := RunFinished{Summary: &summary}
+	finished.Results, err = run.EncodeResults()
+	if err == nil {
+		finished.Vars64, err = run.EncodeVars()
+	}
+	if err != nil {
+		finished.Error = err.Error()
+	}
+	send(EventRunFinished, finished)
+}
@@ -0,0 +1,118 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+// event is a Server-Sent Event as the handler writes it.
+type event struct {
+	name string
+	data string
+}
+
+func readEvents(t *testing.T, body string) []event {
+	t.Helper()
+	events := []event{}
+	var current event
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, current)
+			current = event{}
+		}
+	}
+	return events
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		// wantEvents are the names of the events, each followed by the task
+		// it is about, if any
+		wantEvents []string
+		wantError  bool
+	}{
+		{
+			name: "two tasks",
+			spec: `
+				a [type=multiply input="2" times="3"]
+				b [type=multiply input="$(a)" times="2"]
+				a -> b
+			`,
+			wantEvents: []string{
+				EventTaskStarted + " a", EventTaskFinished + " a",
+				EventTaskStarted + " b", EventTaskFinished + " b",
+				EventRunFinished,
+			},
+		},
+		{
+			name:       "spec that doesn't parse",
+			spec:       `a [type=multiply`,
+			wantEvents: []string{EventRunFinished},
+			wantError:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, err := json.Marshal(Input{RunRequest: simulator.RunRequest{Spec: test.spec}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/stream", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			Handler(rec, req)
+
+			if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("content type %q", ct)
+			}
+
+			events := readEvents(t, rec.Body.String())
+			got := make([]string, 0, len(events))
+			for _, e := range events {
+				var task struct {
+					Id string `json:"id"`
+				}
+				if err := json.Unmarshal([]byte(e.data), &task); err != nil {
+					t.Fatalf("%s: %v", e.data, err)
+				}
+				if task.Id != "" {
+					got = append(got, e.name+" "+task.Id)
+				} else {
+					got = append(got, e.name)
+				}
+			}
+			if !reflect.DeepEqual(got, test.wantEvents) {
+				t.Fatalf("events %v, want %v", got, test.wantEvents)
+			}
+
+			var finished RunFinished
+			if err := json.Unmarshal([]byte(events[len(events)-1].data), &finished); err != nil {
+				t.Fatal(err)
+			}
+			if (finished.Error != "") != test.wantError {
+				t.Errorf("error %q, want an error: %v", finished.Error, test.wantError)
+			}
+			if !test.wantError && (finished.Summary == nil || len(finished.Results) != 2) {
+				t.Errorf("run finished with summary %+v and %d results", finished.Summary, len(finished.Results))
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package sweep
+
+import (
+	"net/http"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+type Input struct {
+	simulator.RunRequest
+	Axes []simulator.Axis
+}
+
+type Response struct {
+	Sweep *simulator.Sweep `json:"sweep"`
+	Error string           `json:"error"`
+}
+
+// Handler re-runs the pipeline while varying one or two vars or mocked task
+// outputs, and returns a table of the results.
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	var input = middleware.ProcessRequestAndTryDecode[Input](w, r)
+
+	response := Response{}
+
+	sweep, err := runSweep(r, input)
+	if err != nil {
+		response.Error = err.Error()
+	}
+	response.Sweep = sweep
+
+	jsonSer := pipeline.JSONSerializable{
+		Valid: true,
+		Val:   response,
+	}
+
+	jData, errJson := jsonSer.MarshalJSON()
+	if errJson != nil {
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jData)
+}
+
+func runSweep(r *http.Request, input Input) (*simulator.Sweep, error) {
+	// Prepared like a single run, so that the spec's Contract.method
+	// references resolve, with every row starting from its pipeline, vars and
+	// mocks
+	run, err := input.Prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	return simulator.RunSweep(r.Context(), run.Pipeline, run.Vars, run.Mocks, input.Axes, simulator.RunOptions{
+		BackoffCompression: input.BackoffCompression,
+		Faults:             input.Faults,
+		FaultSeed:          input.FaultSeed,
+		Chains:             run.Options.Chains,
+	})
+}
@@ -0,0 +1,92 @@
+package sweep
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+const feed = "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"
+
+func TestHandlerChains(t *testing.T) {
+	spec := `call [type=ethcall contract="` + feed + `" data="$(calldata)" evmChainID="1"]`
+	axes := []simulator.Axis{{Var: "calldata", Values: []string{"0x50d25bcd", "0x313ce567"}}}
+
+	tests := []struct {
+		name   string
+		chains []chain.Config
+		// wantValues are the values of the call by row, and wantErrors its
+		// errors
+		wantValues []string
+		wantErrors []string
+	}{
+		{
+			name: "scripted chain",
+			chains: []chain.Config{{
+				ChainID: "1",
+				Responses: []chain.Response{
+					{Contract: feed, Method: "latestAnswer()", Result: "0x2a"},
+					{Contract: feed, Method: "decimals()", Result: "0x08"},
+				},
+			}},
+			wantValues: []string{"[42]", "[8]"},
+			wantErrors: []string{"", ""},
+		},
+		{
+			name: "no response for the call",
+			chains: []chain.Config{{
+				ChainID:   "1",
+				Responses: []chain.Response{{Contract: feed, Method: "latestAnswer()", Result: "0x2a"}},
+			}},
+			wantValues: []string{"[42]", ""},
+			wantErrors: []string{"", "while calling contract"},
+		},
+		{
+			name:       "no chain with the ID",
+			chains:     []chain.Config{{ChainID: "137"}},
+			wantValues: []string{"", ""},
+			wantErrors: []string{"no mock chain has ID 1", "no mock chain has ID 1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, err := json.Marshal(Input{
+				RunRequest: simulator.RunRequest{Spec: spec, Chains: test.chains},
+				Axes:       axes,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/sweep", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			Handler(rec, req)
+
+			var response Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("%s: %v", rec.Body.String(), err)
+			}
+			if response.Error != "" {
+				t.Fatal(response.Error)
+			}
+			if len(response.Sweep.Rows) != len(test.wantValues) {
+				t.Fatalf("%d rows, want %d", len(response.Sweep.Rows), len(test.wantValues))
+			}
+			for i, row := range response.Sweep.Rows {
+				if got := row.Values["call"]; got != test.wantValues[i] {
+					t.Errorf("row %d: value %q, want %q", i, got, test.wantValues[i])
+				}
+				if got := row.Errors["call"]; !strings.Contains(got, test.wantErrors[i]) || (got == "") != (test.wantErrors[i] == "") {
+					t.Errorf("row %d: error %q, want %q", i, got, test.wantErrors[i])
+				}
+			}
+		})
+	}
+}
@@ -1,17 +1,14 @@
 package task
 
 import (
-	"context"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 
-	"github.com/pickleyd/chainlink/core/config"
-	"github.com/pickleyd/chainlink/core/logger"
 	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/contracts"
 	"github.com/pickleyd/jobspecviz/middleware"
+	"github.com/pickleyd/jobspecviz/simulator"
 )
 
 type Task struct {
@@ -22,85 +19,147 @@ type Task struct {
 	Options      map[string]interface{}
 	Vars64       string
 	MockResponse interface{}
+	// Fault is injected into the task, see simulator.Fault
+	Fault *simulator.Fault
+	// Divides the delays between retries, for fast testing
+	BackoffCompression float64
+	// Contracts are the ABI registry, which the abi option can refer to as
+	// Contract.method and ethcall and ethtx data are decoded against
+	Contracts []contracts.Source
+	// Chains answer ethcall and ethtx by evmChainID, see chain.Config
+	Chains []chain.Config
 }
 
 type Response struct {
-	Value            string                 `json:"value"`
-	Val64            string                 `json:"val64"`
-	Vars             map[string]interface{} `json:"vars"`
-	Vars64           string                 `json:"vars64"`
-	Error            string                 `json:"error"`
-	SideEffectData   string                 `json:"sideEffectData"`
-	SideEffectData64 string                 `json:"sideEffectData64"`
+	Value            string                    `json:"value"`
+	Val64            string                    `json:"val64"`
+	Vars             map[string]interface{}    `json:"vars"`
+	Vars64           string                    `json:"vars64"`
+	Error            string                    `json:"error"`
+	SideEffectData   string                    `json:"sideEffectData"`
+	SideEffectData64 string                    `json:"sideEffectData64"`
+	TimedOut         bool                      `json:"timedOut"`
+	Cancelled        bool                      `json:"cancelled"`
+	Attempts         []simulator.AttemptResult `json:"attempts,omitempty"`
+	Faulted          bool                      `json:"faulted"`
+	Dropped          bool                      `json:"dropped"`
+	// Contract is the ethcall or ethtx decoded against the ABI registry
+	Contract *simulator.ContractCall `json:"contract,omitempty"`
+	// Revert is why an ethcall reverted: the reason of Error(string), the
+	// code of Panic(uint256) or a custom error of the ABI registry
+	Revert *contracts.Revert `json:"revert,omitempty"`
 }
 
 func Handler(w http.ResponseWriter, r *http.Request) {
 
 	var t = middleware.ProcessRequestAndTryDecode[Task](w, r)
 
-	ctx := context.Background()
-
-	vars := make(map[string]interface{})
+	vars, err := simulator.VarsFromBase64(t.Vars64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if t.Vars64 != "" {
-		varsDec, _ := base64.StdEncoding.DecodeString(t.Vars64)
+	inputs := make([]interface{}, 0, len(t.Inputs64))
+	for _, in64 := range t.Inputs64 {
+		input, err := simulator.FromBase64(in64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		inputs = append(inputs, input)
+	}
 
-		inputVars := pipeline.JSONSerializable{}
-		inputVars.UnmarshalJSON(varsDec)
+	registry, err := contracts.Load(t.Contracts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskType := simulator.TaskType(t.Name)
+	options, err := registry.ResolveOptions(t.Name, t.Options)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		vars = inputVars.Val.(map[string]interface{})
+	chains, err := chain.NewChains(t.Chains)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	response := Response{}
+	opts := simulator.ExecuteOptions{
+		BackoffCompression: t.BackoffCompression,
+		Chains:             chains,
+	}
 
-	pipelineVars := pipeline.NewVarsFrom(vars)
+	if t.Fault != nil {
+		if err := t.Fault.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Faults = []simulator.Fault{*t.Fault}
+	}
 
-	task, taskErr := getTask(TaskType(t.Name), t.Options)
+	// Aborting the request cancels the simulation
+	result, taskErr := simulator.RunTask(r.Context(), taskType, options, vars, inputs, opts)
 
 	if taskErr != nil {
 		// TODO: Define and return different error types
 		msg := "Bad request"
 		http.Error(w, msg, http.StatusBadRequest)
+		return
 	}
 
-	inputs := make([]pipeline.Result, 0, len(t.Inputs64))
-	for _, r := range t.Inputs64 {
-		inputsDec, _ := base64.StdEncoding.DecodeString(r)
-
-		inputsTemp := pipeline.JSONSerializable{}
-		inputsTemp.UnmarshalJSON(inputsDec)
-
-		inputs = append(inputs, pipeline.Result{Value: inputsTemp.Val})
-	}
-
-	result, _ := task.Run(ctx, logger.NullLogger, pipelineVars, inputs)
+	contractCall := simulator.DecodeContractCall(registry, taskType, options, vars, result)
+	revert := simulator.DecodeRevert(registry, taskType, result)
 
 	// Append the result to the vars
 	// TODO - existence check and warning for overwrite?
-	if t.MockResponse != nil {
+	if result.Dropped() {
+		delete(vars, t.Id)
+	} else if t.MockResponse != nil {
 		vars[t.Id] = t.MockResponse
 	} else {
 		vars[t.Id] = result.Value
 	}
 
-	varsEnc := customToBase64(vars)
+	varsEnc, err := simulator.ToBase64(vars)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	resultValEnc := customToBase64(vars[t.Id])
+	resultValEnc, err := simulator.ToBase64(vars[t.Id])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	response = Response{
-		Value:  fmt.Sprintf("%v", vars[t.Id]),
-		Val64:  resultValEnc,
-		Vars:   vars,
-		Vars64: varsEnc,
+	response := Response{
+		Value:    fmt.Sprintf("%v", vars[t.Id]),
+		Val64:    resultValEnc,
+		Vars:     vars,
+		Vars64:   varsEnc,
+		Attempts: simulator.EncodeAttempts(result.Attempts),
+		Faulted:  simulator.Faulted(result.Attempts),
+		Dropped:  result.Dropped(),
+		Contract: contractCall,
+		Revert:   revert,
 	}
 
 	if result.Error != nil {
 		response.Error = result.Error.Error()
+		response.TimedOut = result.TimedOut()
+		response.Cancelled = result.Cancelled()
 	}
 
 	if result.SideEffectData != nil {
 		response.SideEffectData = fmt.Sprintf("%v", result.SideEffectData)
-		response.SideEffectData64 = customToBase64(result.SideEffectData)
+		if response.SideEffectData64, err = simulator.ToBase64(result.SideEffectData); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	jsonSer := pipeline.JSONSerializable{
@@ -110,422 +169,11 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 	jData, errJson := jsonSer.MarshalJSON()
 	if errJson != nil {
-		log.Fatal("Error marshalling response object to json", errJson)
+		http.Error(w, errJson.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(jData)
 
 }
-
-func customToBase64(input interface{}) string {
-	return base64.StdEncoding.EncodeToString(marshalAsJsonSerializable(input))
-}
-
-// Marshal the input using Chainlink's custom marshalling logic
-func marshalAsJsonSerializable(input interface{}) []byte {
-	asJsonSerializable := pipeline.JSONSerializable{
-		Valid: true,
-		Val:   input,
-	}
-
-	jData, errJson := asJsonSerializable.MarshalJSON()
-	if errJson != nil {
-		log.Fatal("Error marshalling object to json", errJson)
-	}
-
-	return jData
-}
-
-type TaskType string
-
-func (t TaskType) String() string {
-	return string(t)
-}
-
-const (
-	TaskTypeHTTP      TaskType = "http"
-	TaskTypeBridge    TaskType = "bridge"
-	TaskTypeMean      TaskType = "mean"
-	TaskTypeMedian    TaskType = "median"
-	TaskTypeMode      TaskType = "mode"
-	TaskTypeSum       TaskType = "sum"
-	TaskTypeMultiply  TaskType = "multiply"
-	TaskTypeDivide    TaskType = "divide"
-	TaskTypeJSONParse TaskType = "jsonparse"
-	TaskTypeCBORParse TaskType = "cborparse"
-	TaskTypeAny       TaskType = "any"
-	// TaskTypeVRF              TaskType = "vrf"
-	// TaskTypeVRFV2            TaskType = "vrfv2"
-	// TaskTypeEstimateGasLimit TaskType = "estimategaslimit"
-	TaskTypeETHCall      TaskType = "ethcall"
-	TaskTypeETHTx        TaskType = "ethtx"
-	TaskTypeETHABIEncode TaskType = "ethabiencode"
-	// TaskTypeETHABIEncode2    TaskType = "ethabiencode2"
-	TaskTypeETHABIDecode    TaskType = "ethabidecode"
-	TaskTypeETHABIDecodeLog TaskType = "ethabidecodelog"
-	TaskTypeMerge           TaskType = "merge"
-	TaskTypeLowercase       TaskType = "lowercase"
-	TaskTypeUppercase       TaskType = "uppercase"
-	TaskTypeConditional     TaskType = "conditional"
-	TaskTypeHexDecode       TaskType = "hexdecode"
-	TaskTypeHexEncode       TaskType = "hexencode"
-	TaskTypeBase64Decode    TaskType = "base64decode"
-	TaskTypeBase64Encode    TaskType = "base64encode"
-	TaskTypeLessThan        TaskType = "lessthan"
-	TaskTypeLength          TaskType = "length"
-	TaskTypeLookup          TaskType = "lookup"
-	// // Testing only.
-	// TaskTypePanic TaskType = "panic"
-	// TaskTypeMemo  TaskType = "memo"
-	// TaskTypeFail  TaskType = "fail"
-)
-
-func getTask(taskType TaskType, options map[string]interface{}) (pipeline.Task, error) {
-
-	// convert map to json
-	jsonString, _ := json.Marshal(options)
-
-	// seeing as we're just running a single task with no context
-	// we can just use an empty base task
-	baseTask := pipeline.NewBaseTask(0, "", nil, nil, 0)
-
-	var task pipeline.Task
-	switch taskType {
-	// case TaskTypePanic:
-	// 	task = &PanicTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
-	case TaskTypeHTTP:
-		var opts pipeline.HTTPTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		httpTask := pipeline.HTTPTask{
-			BaseTask:                       baseTask,
-			Method:                         opts.Method,
-			URL:                            opts.URL,
-			RequestData:                    opts.RequestData,
-			AllowUnrestrictedNetworkAccess: opts.AllowUnrestrictedNetworkAccess,
-			Headers:                        opts.Headers,
-		}
-
-		config := config.NewGeneralConfig(logger.NullLogger)
-
-		c := http.DefaultClient
-		httpTask.HelperSetDependencies(config, c, c)
-
-		task = &httpTask
-
-	case TaskTypeBridge:
-		var opts pipeline.BridgeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.BridgeTask{
-			BaseTask:          baseTask,
-			Name:              opts.Name,
-			RequestData:       opts.RequestData,
-			IncludeInputAtKey: opts.IncludeInputAtKey,
-			Async:             opts.Async,
-			CacheTTL:          opts.CacheTTL,
-		}
-	case TaskTypeMean:
-		var opts pipeline.MeanTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.MeanTask{
-			BaseTask:      baseTask,
-			Values:        opts.Values,
-			AllowedFaults: opts.AllowedFaults,
-			Precision:     opts.Precision,
-		}
-	case TaskTypeMedian:
-		var opts pipeline.MedianTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.MedianTask{
-			BaseTask:      baseTask,
-			Values:        opts.Values,
-			AllowedFaults: opts.AllowedFaults,
-		}
-	case TaskTypeMode:
-		var opts pipeline.ModeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.ModeTask{
-			BaseTask:      baseTask,
-			Values:        opts.Values,
-			AllowedFaults: opts.AllowedFaults,
-		}
-	case TaskTypeSum:
-		var opts pipeline.SumTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.SumTask{
-			BaseTask:      baseTask,
-			Values:        opts.Values,
-			AllowedFaults: opts.AllowedFaults,
-		}
-	case TaskTypeAny:
-		task = &pipeline.AnyTask{}
-	case TaskTypeJSONParse:
-		var opts pipeline.JSONParseTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.JSONParseTask{
-			BaseTask:  baseTask,
-			Path:      opts.Path,
-			Separator: opts.Separator,
-			Data:      opts.Data,
-			Lax:       opts.Lax,
-		}
-	// case TaskTypeMemo:
-	// 	task = &MemoTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
-	case TaskTypeMultiply:
-		var opts pipeline.MultiplyTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.MultiplyTask{
-			BaseTask: baseTask,
-			Times:    opts.Times,
-		}
-	case TaskTypeDivide:
-		var opts pipeline.DivideTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.DivideTask{
-			BaseTask:  baseTask,
-			Divisor:   opts.Divisor,
-			Precision: opts.Precision,
-		}
-	// case TaskTypeVRF:
-	// 	task = &VRFTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
-	// case TaskTypeVRFV2:
-	// 	task = &VRFTaskV2{BaseTask: BaseTask{id: ID, dotID: dotID}}
-	// case TaskTypeEstimateGasLimit:
-	// 	task = &EstimateGasLimitTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
-	case TaskTypeETHCall:
-		var opts pipeline.ETHCallTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.ETHCallTask{
-			BaseTask:            baseTask,
-			From:                opts.From,
-			Data:                opts.Data,
-			EVMChainID:          opts.EVMChainID,
-			Contract:            opts.Contract,
-			Gas:                 opts.Gas,
-			GasPrice:            opts.GasPrice,
-			GasTipCap:           opts.GasTipCap,
-			GasFeeCap:           opts.GasFeeCap,
-			GasUnlimited:        opts.GasUnlimited,
-			ExtractRevertReason: opts.ExtractRevertReason,
-
-			// CUSTOM
-			SpecGasLimit: opts.SpecGasLimit,
-		}
-	case TaskTypeETHTx:
-		var opts pipeline.ETHTxTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.ETHTxTask{
-			BaseTask:         baseTask,
-			From:             opts.From,
-			To:               opts.To,
-			Data:             opts.Data,
-			GasLimit:         opts.GasLimit,
-			TxMeta:           opts.TxMeta,
-			MinConfirmations: opts.MinConfirmations,
-			FailOnRevert:     opts.FailOnRevert,
-			EVMChainID:       opts.EVMChainID,
-			TransmitChecker:  opts.TransmitChecker,
-		}
-	case TaskTypeETHABIEncode:
-		var opts pipeline.ETHABIEncodeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.ETHABIEncodeTask{
-			BaseTask: baseTask,
-			ABI:      opts.ABI,
-			Data:     opts.Data,
-		}
-	// case TaskTypeETHABIEncode2:
-	// 	task = &ETHABIEncodeTask2{BaseTask: BaseTask{id: ID, dotID: dotID}}
-	case TaskTypeETHABIDecode:
-		var opts pipeline.ETHABIDecodeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.ETHABIDecodeTask{
-			BaseTask: baseTask,
-			ABI:      opts.ABI,
-			Data:     opts.Data,
-		}
-	case TaskTypeETHABIDecodeLog:
-		var opts pipeline.ETHABIDecodeLogTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.ETHABIDecodeLogTask{
-			BaseTask: baseTask,
-			ABI:      opts.ABI,
-			Data:     opts.Data,
-			Topics:   opts.Topics,
-		}
-	case TaskTypeCBORParse:
-		var opts pipeline.CBORParseTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.CBORParseTask{
-			BaseTask: baseTask,
-			Data:     opts.Data,
-			Mode:     opts.Mode,
-		}
-	// case TaskTypeFail:
-	// 	task = &FailTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
-	case TaskTypeMerge:
-		var opts pipeline.MergeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.MergeTask{
-			BaseTask: baseTask,
-			Left:     opts.Left,
-			Right:    opts.Right,
-		}
-	case TaskTypeLowercase:
-		var opts pipeline.LowercaseTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.LowercaseTask{
-			BaseTask: baseTask,
-			Input:    opts.Input,
-		}
-	case TaskTypeUppercase:
-		var opts pipeline.UppercaseTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.UppercaseTask{
-			BaseTask: baseTask,
-			Input:    opts.Input,
-		}
-	case TaskTypeConditional:
-		var opts pipeline.ConditionalTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.ConditionalTask{
-			BaseTask: baseTask,
-			Data:     opts.Data,
-		}
-	case TaskTypeHexEncode:
-		var opts pipeline.HexEncodeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.HexEncodeTask{
-			BaseTask: baseTask,
-			Input:    opts.Input,
-		}
-	case TaskTypeHexDecode:
-		var opts pipeline.HexDecodeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.HexDecodeTask{
-			BaseTask: baseTask,
-			Input:    opts.Input,
-		}
-	case TaskTypeBase64Encode:
-		var opts pipeline.Base64EncodeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.Base64EncodeTask{
-			BaseTask: baseTask,
-			Input:    opts.Input,
-		}
-	case TaskTypeBase64Decode:
-		var opts pipeline.Base64DecodeTask
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.Base64DecodeTask{
-			BaseTask: baseTask,
-			Input:    opts.Input,
-		}
-	case TaskTypeLessThan:
-		var opts pipeline.LessThanTask
-
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.LessThanTask{
-			BaseTask: baseTask,
-			Left:     opts.Left,
-			Right:    opts.Right,
-		}
-	case TaskTypeLength:
-		var opts pipeline.LengthTask
-
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.LengthTask{
-			BaseTask: baseTask,
-			Input:    opts.Input,
-		}
-	case TaskTypeLookup:
-		var opts pipeline.LookupTask
-
-		if err := json.Unmarshal(jsonString, &opts); err != nil {
-			log.Fatal(err)
-		}
-
-		task = &pipeline.LookupTask{
-			BaseTask: baseTask,
-			Key:      opts.Key,
-		}
-	default:
-		return nil, fmt.Errorf(`unknown task type: "%v"`, taskType)
-	}
-
-	return task, nil
-}
@@ -0,0 +1,335 @@
+// Package chain is a scripted stand-in for an EVM chain, so that ethcall and
+// ethtx tasks can be simulated without contract bytecode. Rather than
+// executing calls, a chain answers them from canned responses keyed by the
+// contract address and method selector, and optionally the call's arguments.
+// Calls that no response matches fail with an error saying so.
+//
+// Chains are picked by the tasks' evmChainID. The simulator calls them
+// directly, and Handler serves them over JSON-RPC so that a node can be
+// pointed at them too.
+package chain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pickleyd/jobspecviz/contracts"
+)
+
+// Config scripts a chain.
+type Config struct {
+	// ChainID is the chain's ID in decimal, which tasks pick it by with
+	// evmChainID
+	ChainID     string `json:"chainID" yaml:"chainID"`
+	BlockNumber uint64 `json:"blockNumber" yaml:"blockNumber"`
+	// GasPrice is in wei, in decimal
+	GasPrice string `json:"gasPrice" yaml:"gasPrice"`
+	// Nonce is the transaction count of every account, which goes up with
+	// every transaction sent
+	Nonce     uint64     `json:"nonce" yaml:"nonce"`
+	Responses []Response `json:"responses" yaml:"responses"`
+}
+
+// Response is what the chain answers calls to a contract's method with.
+type Response struct {
+	Contract string `json:"contract" yaml:"contract"`
+	// Method is the method's selector, e.g. 0xfeaf968c, or its signature,
+	// e.g. latestRoundData()
+	Method string `json:"method" yaml:"method"`
+	// Args, if set, only answers calls with exactly these arguments, the
+	// calldata after the selector in hex. Responses with Args take
+	// precedence over those without.
+	Args string `json:"args,omitempty" yaml:"args,omitempty"`
+	// Result is the hex the call returns
+	Result string `json:"result,omitempty" yaml:"result,omitempty"`
+	// Revert makes the call revert with the reason, as Error(string) does
+	Revert string `json:"revert,omitempty" yaml:"revert,omitempty"`
+	// RevertData makes the call revert with the hex data, e.g. a custom
+	// error or Panic(uint256)
+	RevertData string `json:"revertData,omitempty" yaml:"revertData,omitempty"`
+}
+
+// response is a Response parsed.
+type response struct {
+	contract common.Address
+	selector []byte
+	// args is nil for a response to any arguments
+	args   []byte
+	result []byte
+	// revert is nil unless the call reverts
+	revert []byte
+}
+
+// Transaction is a transaction sent to the chain.
+type Transaction struct {
+	Hash  common.Hash
+	From  common.Address
+	To    common.Address
+	Nonce uint64
+	Data  []byte
+	// Err is the revert the transaction's response scripts, if any
+	Err error
+}
+
+// Chain answers calls and accepts transactions from its responses. It is
+// safe for concurrent use.
+type Chain struct {
+	ID          *big.Int
+	BlockNumber uint64
+	GasPrice    *big.Int
+
+	responses []response
+
+	mu           sync.Mutex
+	nonce        uint64
+	transactions []Transaction
+}
+
+// RevertError is a call that reverted, worded as geth words it, with the
+// revert data in hex so that the reason can be decoded from the message.
+type RevertError struct {
+	Data []byte
+}
+
+func (e *RevertError) Error() string {
+	if len(e.Data) == 0 {
+		return "execution reverted"
+	}
+	return "execution reverted: " + hexutil.Encode(e.Data)
+}
+
+// ErrUnknownCall is the error for calls that none of the chain's responses
+// answer.
+var ErrUnknownCall = errors.New("the mock chain has no response")
+
+// New parses the config into a chain.
+func New(config Config) (*Chain, error) {
+	id, ok := new(big.Int).SetString(config.ChainID, 10)
+	if !ok || id.Sign() <= 0 {
+		return nil, fmt.Errorf("chainID %q is not a chain ID", config.ChainID)
+	}
+
+	c := &Chain{ID: id, BlockNumber: config.BlockNumber, GasPrice: new(big.Int), nonce: config.Nonce}
+	if config.GasPrice != "" {
+		if _, ok := c.GasPrice.SetString(config.GasPrice, 10); !ok {
+			return nil, fmt.Errorf("chain %s: gasPrice %q is not an amount of wei", id, config.GasPrice)
+		}
+	}
+
+	for i, r := range config.Responses {
+		parsed, err := parseResponse(r)
+		if err != nil {
+			return nil, fmt.Errorf("chain %s: responses[%d]: %w", id, i, err)
+		}
+		c.responses = append(c.responses, parsed)
+	}
+	return c, nil
+}
+
+func parseResponse(r Response) (response, error) {
+	parsed := response{}
+	if !common.IsHexAddress(r.Contract) {
+		return parsed, fmt.Errorf("contract %q is not an address", r.Contract)
+	}
+	parsed.contract = common.HexToAddress(r.Contract)
+
+	var err error
+	if parsed.selector, err = selector(r.Method); err != nil {
+		return parsed, err
+	}
+	if r.Args != "" {
+		if parsed.args, err = hexutil.Decode(r.Args); err != nil {
+			return parsed, fmt.Errorf("args: %w", err)
+		}
+	}
+
+	switch {
+	case r.Revert != "" && r.RevertData != "":
+		return parsed, fmt.Errorf("give either revert or revertData")
+	case r.Revert != "":
+		data, err := abi.Arguments{{Type: stringType}}.Pack(r.Revert)
+		if err != nil {
+			return parsed, err
+		}
+		parsed.revert = append(crypto.Keccak256([]byte("Error(string)"))[:4], data...)
+	case r.RevertData != "":
+		if parsed.revert, err = hexutil.Decode(r.RevertData); err != nil {
+			return parsed, fmt.Errorf("revertData: %w", err)
+		}
+	default:
+		parsed.result = []byte{}
+		if r.Result != "" {
+			if parsed.result, err = hexutil.Decode(r.Result); err != nil {
+				return parsed, fmt.Errorf("result: %w", err)
+			}
+		}
+	}
+	return parsed, nil
+}
+
+var stringType, _ = abi.NewType("string", "", nil)
+
+// selector is the 4 byte selector of a method, given as hex or as its
+// signature.
+func selector(method string) ([]byte, error) {
+	method = strings.TrimSpace(method)
+	if strings.HasPrefix(method, "0x") {
+		b, err := hexutil.Decode(method)
+		if err != nil || len(b) != 4 {
+			return nil, fmt.Errorf("method %q is not a 4 byte selector", method)
+		}
+		return b, nil
+	}
+
+	signature, err := contracts.ParseSignature(method)
+	if err != nil {
+		return nil, fmt.Errorf("method: %w", err)
+	}
+	if signature.Kind != "function" {
+		return nil, fmt.Errorf("method %q is not a function", method)
+	}
+	return signature.ID, nil
+}
+
+// Call answers a call to the contract with the data, from the response with
+// matching arguments, or else the one for any arguments. A scripted revert
+// is returned as a *RevertError.
+func (c *Chain) Call(to common.Address, data []byte) ([]byte, error) {
+	r, err := c.respond(to, data)
+	if err != nil {
+		return nil, err
+	}
+	if r.revert != nil {
+		return nil, &RevertError{Data: r.revert}
+	}
+	return r.result, nil
+}
+
+func (c *Chain) respond(to common.Address, data []byte) (*response, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w for calls to %s without a selector, data %s", ErrUnknownCall, to.Hex(), hexutil.Encode(data))
+	}
+
+	var fallback *response
+	for i, r := range c.responses {
+		if r.contract != to || !bytes.Equal(r.selector, data[:4]) {
+			continue
+		}
+		if r.args == nil {
+			if fallback == nil {
+				fallback = &c.responses[i]
+			}
+		} else if bytes.Equal(r.args, data[4:]) {
+			return &c.responses[i], nil
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+
+	args := ""
+	if len(data) > 4 {
+		args = " and args " + hexutil.Encode(data[4:])
+	}
+	return nil, fmt.Errorf("%w for calls to %s with selector %s%s on chain %s, add one to its responses",
+		ErrUnknownCall, to.Hex(), hexutil.Encode(data[:4]), args, c.ID)
+}
+
+// SendTransaction sends a transaction calling the contract with the data.
+// The call must have a response, whose revert, if any, is the
+// transaction's Err. Every transaction takes the next nonce.
+func (c *Chain) SendTransaction(from, to common.Address, data []byte) (Transaction, error) {
+	r, err := c.respond(to, data)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tx := Transaction{From: from, To: to, Nonce: c.nonce, Data: data}
+	if r.revert != nil {
+		tx.Err = &RevertError{Data: r.revert}
+	}
+	tx.Hash = crypto.Keccak256Hash(c.ID.Bytes(), from.Bytes(), to.Bytes(), new(big.Int).SetUint64(tx.Nonce).Bytes(), data)
+	c.nonce++
+	c.transactions = append(c.transactions, tx)
+	return tx, nil
+}
+
+// record keeps a transaction sent over JSON-RPC, which is already signed
+// with its own nonce and hash.
+func (c *Chain) record(tx Transaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if tx.Nonce >= c.nonce {
+		c.nonce = tx.Nonce + 1
+	}
+	c.transactions = append(c.transactions, tx)
+}
+
+// Nonce is the nonce of the next transaction.
+func (c *Chain) Nonce() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nonce
+}
+
+// Transactions are the transactions sent so far, in order.
+func (c *Chain) Transactions() []Transaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Transaction{}, c.transactions...)
+}
+
+// Chains are mock chains by chain ID.
+type Chains map[string]*Chain
+
+// NewChains parses each of the configs into a chain.
+func NewChains(configs []Config) (Chains, error) {
+	chains := Chains{}
+	for _, config := range configs {
+		c, err := New(config)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := chains[c.ID.String()]; ok {
+			return nil, fmt.Errorf("chain %s is configured twice", c.ID)
+		}
+		chains[c.ID.String()] = c
+	}
+	return chains, nil
+}
+
+// Chain picks the chain with the ID. A task without an evmChainID uses the
+// only chain, if there is just one.
+func (cs Chains) Chain(evmChainID string) (*Chain, error) {
+	if evmChainID == "" && len(cs) == 1 {
+		for _, c := range cs {
+			return c, nil
+		}
+	}
+	if c, ok := cs[evmChainID]; ok {
+		return c, nil
+	}
+
+	ids := make([]string, 0, len(cs))
+	for id := range cs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if evmChainID == "" {
+		return nil, fmt.Errorf("set evmChainID to pick one of the mock chains %s", strings.Join(ids, ", "))
+	}
+	return nil, fmt.Errorf("no mock chain has ID %s, only %s", evmChainID, strings.Join(ids, ", "))
+}
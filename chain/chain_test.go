@@ -0,0 +1,178 @@
+package chain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	feed = "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"
+	// latestAnswer() and balanceOf(address)
+	latestAnswer = "0x50d25bcd"
+	balanceOf    = "0x70a08231"
+)
+
+const account = "000000000000000000000000000000000000000000000000000000000000dead"
+
+func testChain(t *testing.T) *Chain {
+	t.Helper()
+	c, err := New(Config{
+		ChainID: "1",
+		Nonce:   7,
+		Responses: []Response{
+			{Contract: feed, Method: "latestAnswer()", Result: "0x2a"},
+			{Contract: feed, Method: balanceOf, Result: "0x01"},
+			{Contract: feed, Method: balanceOf, Args: "0x" + account, Result: "0x02"},
+			{Contract: feed, Method: "withdraw()", Revert: "not the owner"},
+			{Contract: feed, Method: "pause()", RevertData: "0x4e487b71"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "no chain ID", config: Config{}},
+		{name: "zero chain ID", config: Config{ChainID: "0"}},
+		{name: "bad gas price", config: Config{ChainID: "1", GasPrice: "1 gwei"}},
+		{name: "bad contract", config: Config{ChainID: "1", Responses: []Response{{Contract: "0x12", Method: "f()"}}}},
+		{name: "short selector", config: Config{ChainID: "1", Responses: []Response{{Contract: feed, Method: "0x1234"}}}},
+		{name: "event as method", config: Config{ChainID: "1", Responses: []Response{{Contract: feed, Method: "event E(uint256 a)"}}}},
+		{name: "revert and revertData", config: Config{ChainID: "1", Responses: []Response{{Contract: feed, Method: "f()", Revert: "no", RevertData: "0x00"}}}},
+		{name: "bad result", config: Config{ChainID: "1", Responses: []Response{{Contract: feed, Method: "f()", Result: "2a"}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := New(test.config); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name        string
+		to          string
+		data        string
+		want        string
+		wantRevert  string
+		wantUnknown bool
+	}{
+		{name: "by signature", to: feed, data: latestAnswer, want: "0x2a"},
+		{name: "any args", to: feed, data: balanceOf + "00000000000000000000000000000000000000000000000000000000000000ff", want: "0x01"},
+		{name: "matching args take precedence", to: feed, data: balanceOf + account, want: "0x02"},
+		{
+			name:       "revert reason",
+			to:         feed,
+			data:       "0x3ccfd60b",
+			wantRevert: "0x08c379a0",
+		},
+		{name: "revert data", to: feed, data: "0x8456cb59", wantRevert: "0x4e487b71"},
+		{name: "unknown selector", to: feed, data: "0x12345678", wantUnknown: true},
+		{name: "other contract", to: "0x000000000000000000000000000000000000dEaD", data: latestAnswer, wantUnknown: true},
+		{name: "no selector", to: feed, data: "0x50d2", wantUnknown: true},
+	}
+
+	c := testChain(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := c.Call(common.HexToAddress(test.to), hexutil.MustDecode(test.data))
+
+			var revert *RevertError
+			switch {
+			case test.wantUnknown:
+				if !errors.Is(err, ErrUnknownCall) {
+					t.Errorf("error %v, want ErrUnknownCall", err)
+				}
+			case test.wantRevert != "":
+				if !errors.As(err, &revert) {
+					t.Fatalf("error %v, want a revert", err)
+				}
+				if data := hexutil.Encode(revert.Data); data[:10] != test.wantRevert {
+					t.Errorf("revert data %s, want it to start with %s", data, test.wantRevert)
+				}
+			case err != nil:
+				t.Errorf("error %v", err)
+			case hexutil.Encode(got) != test.want:
+				t.Errorf("%s, want %s", hexutil.Encode(got), test.want)
+			}
+		})
+	}
+}
+
+func TestSendTransaction(t *testing.T) {
+	c := testChain(t)
+	from, to := common.HexToAddress("0x01"), common.HexToAddress(feed)
+
+	first, err := c.SendTransaction(from, to, hexutil.MustDecode(latestAnswer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverted, err := c.SendTransaction(from, to, hexutil.MustDecode("0x3ccfd60b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SendTransaction(from, to, hexutil.MustDecode("0x12345678")); !errors.Is(err, ErrUnknownCall) {
+		t.Errorf("error %v, want ErrUnknownCall", err)
+	}
+
+	if first.Nonce != 7 || reverted.Nonce != 8 || c.Nonce() != 9 {
+		t.Errorf("nonces %d and %d, next %d, want 7, 8 and 9", first.Nonce, reverted.Nonce, c.Nonce())
+	}
+	if first.Err != nil || reverted.Err == nil {
+		t.Errorf("errors %v and %v, want only the second to revert", first.Err, reverted.Err)
+	}
+	if first.Hash == reverted.Hash {
+		t.Error("both transactions have the same hash")
+	}
+	if txs := c.Transactions(); len(txs) != 2 {
+		t.Errorf("%d transactions, want 2", len(txs))
+	}
+}
+
+func TestChains(t *testing.T) {
+	tests := []struct {
+		name       string
+		configs    []Config
+		evmChainID string
+		wantID     string
+		wantErr    bool
+	}{
+		{name: "the only chain", configs: []Config{{ChainID: "1"}}, wantID: "1"},
+		{name: "by ID", configs: []Config{{ChainID: "1"}, {ChainID: "137"}}, evmChainID: "137", wantID: "137"},
+		{name: "no ID with two chains", configs: []Config{{ChainID: "1"}, {ChainID: "137"}}, wantErr: true},
+		{name: "unknown ID", configs: []Config{{ChainID: "1"}}, evmChainID: "5", wantErr: true},
+		{name: "no chains", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chains, err := NewChains(test.configs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := chains.Chain(test.evmChainID)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err == nil && c.ID.String() != test.wantID {
+				t.Errorf("chain %s, want %s", c.ID, test.wantID)
+			}
+		})
+	}
+
+	if _, err := NewChains([]Config{{ChainID: "1"}, {ChainID: "1"}}); err == nil {
+		t.Error("no error for a chain configured twice")
+	}
+}
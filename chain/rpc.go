@@ -0,0 +1,271 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// gasEstimate is what eth_estimateGas answers calls that don't revert with.
+const gasEstimate = 500000
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// callArgs are the fields of eth_call and eth_estimateGas the chain looks
+// at.
+type callArgs struct {
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Data  hexutil.Bytes  `json:"data"`
+	Input hexutil.Bytes  `json:"input"`
+}
+
+func (a callArgs) data() []byte {
+	if len(a.Input) > 0 {
+		return a.Input
+	}
+	return a.Data
+}
+
+// Handler serves the chain over JSON-RPC, with the methods a node uses for
+// ethcall and ethtx: eth_chainId, net_version, eth_blockNumber,
+// eth_getBlockByNumber, eth_gasPrice, eth_maxPriorityFeePerGas,
+// eth_getTransactionCount, eth_call, eth_estimateGas,
+// eth_sendRawTransaction and eth_getTransactionReceipt. Batches are
+// supported.
+func (c *Chain) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "JSON-RPC requests are POSTed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: err.Error()}})
+			return
+		}
+
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+			var batch []rpcRequest
+			if err := json.Unmarshal(body, &batch); err != nil {
+				writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: err.Error()}})
+				return
+			}
+			responses := make([]rpcResponse, 0, len(batch))
+			for _, req := range batch {
+				responses = append(responses, c.serve(req))
+			}
+			writeRPC(w, responses)
+			return
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeRPC(w, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: err.Error()}})
+			return
+		}
+		writeRPC(w, c.serve(req))
+	})
+}
+
+func writeRPC(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (c *Chain) serve(req rpcRequest) rpcResponse {
+	res := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	result, err := c.dispatch(req.Method, req.Params)
+	if err == nil {
+		// A nil result, e.g. an unknown receipt, is null rather than left out
+		if res.Result, err = json.Marshal(result); err == nil {
+			return res
+		}
+	}
+
+	var revert *RevertError
+	switch {
+	case errors.As(err, &revert):
+		// As geth reports reverts
+		res.Error = &rpcError{Code: 3, Message: "execution reverted", Data: hexutil.Encode(revert.Data)}
+	case errors.Is(err, errUnknownMethod):
+		res.Error = &rpcError{Code: -32601, Message: err.Error()}
+	case errors.Is(err, errBadParams):
+		res.Error = &rpcError{Code: -32602, Message: err.Error()}
+	default:
+		res.Error = &rpcError{Code: -32000, Message: err.Error()}
+	}
+	return res
+}
+
+var (
+	errUnknownMethod = errors.New("the mock chain doesn't implement")
+	errBadParams     = errors.New("invalid params")
+)
+
+func (c *Chain) dispatch(method string, params []json.RawMessage) (interface{}, error) {
+	param := func(i int, v interface{}) error {
+		if i >= len(params) {
+			return fmt.Errorf("%w: %s takes at least %d", errBadParams, method, i+1)
+		}
+		if err := json.Unmarshal(params[i], v); err != nil {
+			return fmt.Errorf("%w: %s: %v", errBadParams, method, err)
+		}
+		return nil
+	}
+
+	switch method {
+	case "eth_chainId":
+		return (*hexutil.Big)(c.ID), nil
+	case "net_version":
+		return c.ID.String(), nil
+	case "eth_blockNumber":
+		return hexutil.Uint64(c.BlockNumber), nil
+	case "eth_gasPrice", "eth_maxPriorityFeePerGas":
+		return (*hexutil.Big)(c.GasPrice), nil
+	case "eth_getTransactionCount":
+		return hexutil.Uint64(c.Nonce()), nil
+
+	case "eth_getBlockByNumber":
+		return c.block(), nil
+
+	case "eth_call", "eth_estimateGas":
+		var args callArgs
+		if err := param(0, &args); err != nil {
+			return nil, err
+		}
+		result, err := c.Call(args.To, args.data())
+		if err != nil {
+			return nil, err
+		}
+		if method == "eth_estimateGas" {
+			return hexutil.Uint64(gasEstimate), nil
+		}
+		return hexutil.Bytes(result), nil
+
+	case "eth_sendRawTransaction":
+		var raw hexutil.Bytes
+		if err := param(0, &raw); err != nil {
+			return nil, err
+		}
+		return c.sendRaw(raw)
+
+	case "eth_getTransactionReceipt":
+		var hash common.Hash
+		if err := param(0, &hash); err != nil {
+			return nil, err
+		}
+		return c.receipt(hash), nil
+	}
+	return nil, fmt.Errorf("%w %s", errUnknownMethod, method)
+}
+
+// sendRaw sends a signed transaction, checking that its call has a response.
+func (c *Chain) sendRaw(raw []byte) (common.Hash, error) {
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return common.Hash{}, fmt.Errorf("%w: %v", errBadParams, err)
+	}
+	if tx.To() == nil {
+		return common.Hash{}, fmt.Errorf("the mock chain doesn't deploy contracts")
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(c.ID), &tx)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("%w: %v", errBadParams, err)
+	}
+	r, err := c.respond(*tx.To(), tx.Data())
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	sent := Transaction{Hash: tx.Hash(), From: from, To: *tx.To(), Nonce: tx.Nonce(), Data: tx.Data()}
+	if r.revert != nil {
+		sent.Err = &RevertError{Data: r.revert}
+	}
+	c.record(sent)
+	return sent.Hash, nil
+}
+
+func (c *Chain) block() map[string]interface{} {
+	number := new(big.Int).SetUint64(c.BlockNumber)
+	return map[string]interface{}{
+		"number":        (*hexutil.Big)(number),
+		"hash":          common.BigToHash(number),
+		"parentHash":    common.BigToHash(new(big.Int).Sub(number, big.NewInt(1))),
+		"timestamp":     hexutil.Uint64(0),
+		"baseFeePerGas": (*hexutil.Big)(c.GasPrice),
+		"gasLimit":      hexutil.Uint64(30000000),
+		"gasUsed":       hexutil.Uint64(0),
+		"transactions":  []interface{}{},
+	}
+}
+
+// receipt is the receipt of a transaction sent to the chain, mined in the
+// current block, or nil for a hash the chain doesn't know.
+func (c *Chain) receipt(hash common.Hash) interface{} {
+	for _, tx := range c.Transactions() {
+		if tx.Hash != hash {
+			continue
+		}
+		status := hexutil.Uint64(1)
+		if tx.Err != nil {
+			status = 0
+		}
+		return map[string]interface{}{
+			"transactionHash":   tx.Hash,
+			"from":              tx.From,
+			"to":                tx.To,
+			"blockNumber":       hexutil.Uint64(c.BlockNumber),
+			"blockHash":         common.BigToHash(new(big.Int).SetUint64(c.BlockNumber)),
+			"gasUsed":           hexutil.Uint64(gasEstimate),
+			"cumulativeGasUsed": hexutil.Uint64(gasEstimate),
+			"effectiveGasPrice": (*hexutil.Big)(c.GasPrice),
+			"status":            status,
+			"logs":              []interface{}{},
+			"type":              hexutil.Uint64(0),
+		}
+	}
+	return nil
+}
+
+// Handler serves the chains over JSON-RPC, each at its chain ID under the
+// prefix, e.g. /rpc/1 and /rpc/137.
+func (cs Chains) Handler(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		c, ok := cs[id]
+		if !ok {
+			http.Error(w, fmt.Sprintf("no mock chain has ID %q", id), http.StatusNotFound)
+			return
+		}
+		c.Handler().ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,173 @@
+package chain
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// post sends the JSON-RPC body to the handler and returns the decoded
+// response.
+func post(t *testing.T, h http.Handler, path, body string, v interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%v: %s", err, rec.Body)
+		}
+	}
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	call := func(data string) string {
+		return `{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{"to":"` + feed + `","data":"` + data + `"},"latest"]}`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantResult  string
+		wantCode    int
+		wantErrData string
+	}{
+		{name: "chain ID", body: `{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`, wantResult: `"0x1"`},
+		{name: "nonce", body: `{"jsonrpc":"2.0","id":1,"method":"eth_getTransactionCount","params":["0x01","latest"]}`, wantResult: `"0x7"`},
+		{name: "call", body: call(latestAnswer), wantResult: `"0x2a"`},
+		{name: "call with input", body: `{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{"to":"` + feed + `","input":"` + latestAnswer + `"}]}`, wantResult: `"0x2a"`},
+		{name: "revert", body: call("0x8456cb59"), wantCode: 3, wantErrData: "0x4e487b71"},
+		{name: "unknown call", body: call("0x12345678"), wantCode: -32000},
+		{name: "unknown method", body: `{"jsonrpc":"2.0","id":1,"method":"eth_mining"}`, wantCode: -32601},
+		{name: "missing params", body: `{"jsonrpc":"2.0","id":1,"method":"eth_call"}`, wantCode: -32602},
+		{name: "unknown receipt", body: `{"jsonrpc":"2.0","id":1,"method":"eth_getTransactionReceipt","params":["0x` + strings.Repeat("00", 32) + `"]}`, wantResult: "null"},
+		{name: "not JSON", body: `{`, wantCode: -32700},
+	}
+
+	h := testChain(t).Handler()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var res rpcResponse
+			if status := post(t, h, "/", test.body, &res); status != http.StatusOK {
+				t.Fatalf("status %d", status)
+			}
+			if test.wantCode != 0 {
+				if res.Error == nil || res.Error.Code != test.wantCode {
+					t.Fatalf("error %+v, want code %d", res.Error, test.wantCode)
+				}
+				if res.Error.Data != test.wantErrData {
+					t.Errorf("error data %q, want %q", res.Error.Data, test.wantErrData)
+				}
+				return
+			}
+			if res.Error != nil {
+				t.Fatalf("error %+v", res.Error)
+			}
+			if string(res.Result) != test.wantResult {
+				t.Errorf("result %s, want %s", res.Result, test.wantResult)
+			}
+		})
+	}
+}
+
+func TestHandlerBatch(t *testing.T) {
+	var res []rpcResponse
+	body := `[{"jsonrpc":"2.0","id":1,"method":"eth_chainId"},{"jsonrpc":"2.0","id":2,"method":"net_version"}]`
+	if status := post(t, testChain(t).Handler(), "/", body, &res); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if len(res) != 2 || string(res[0].Result) != `"0x1"` || string(res[1].Result) != `"1"` {
+		t.Errorf("%+v", res)
+	}
+}
+
+func TestHandlerSendRawTransaction(t *testing.T) {
+	c := testChain(t)
+	h := c.Handler()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(nonce uint64, data string) rpcResponse {
+		to := common.HexToAddress(feed)
+		tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			To:       &to,
+			Gas:      gasEstimate,
+			GasPrice: big.NewInt(1),
+			Data:     hexutil.MustDecode(data),
+		}), types.LatestSignerForChainID(c.ID), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var res rpcResponse
+		post(t, h, "/", `{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["`+hexutil.Encode(raw)+`"]}`, &res)
+		return res
+	}
+	receipt := func(hash json.RawMessage) map[string]interface{} {
+		var res rpcResponse
+		post(t, h, "/", `{"jsonrpc":"2.0","id":1,"method":"eth_getTransactionReceipt","params":[`+string(hash)+`]}`, &res)
+		r := map[string]interface{}{}
+		if err := json.Unmarshal(res.Result, &r); err != nil {
+			t.Fatalf("%v: %s", err, res.Result)
+		}
+		return r
+	}
+
+	ok := send(7, latestAnswer)
+	if ok.Error != nil {
+		t.Fatal(ok.Error)
+	}
+	if status := receipt(ok.Result)["status"]; status != "0x1" {
+		t.Errorf("status %v, want 0x1", status)
+	}
+
+	reverted := send(8, "0x3ccfd60b")
+	if reverted.Error != nil {
+		t.Fatal(reverted.Error)
+	}
+	if status := receipt(reverted.Result)["status"]; status != "0x0" {
+		t.Errorf("status %v of a reverted transaction, want 0x0", status)
+	}
+
+	if unknown := send(9, "0x12345678"); unknown.Error == nil {
+		t.Error("no error for a transaction without a response")
+	}
+	if c.Nonce() != 9 {
+		t.Errorf("next nonce %d, want 9", c.Nonce())
+	}
+}
+
+func TestChainsHandler(t *testing.T) {
+	chains, err := NewChains([]Config{{ChainID: "1"}, {ChainID: "137"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := chains.Handler("/rpc/")
+
+	var res rpcResponse
+	if status := post(t, h, "/rpc/137", `{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`, &res); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if string(res.Result) != `"0x89"` {
+		t.Errorf("chain ID %s, want 0x89", res.Result)
+	}
+	if status := post(t, h, "/rpc/5", `{}`, &res); status != http.StatusNotFound {
+		t.Errorf("status %d for an unknown chain, want 404", status)
+	}
+}
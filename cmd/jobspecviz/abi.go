@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/simulator"
+	"gopkg.in/yaml.v3"
+)
+
+type abiResult struct {
+	Encoded *contracts.Encoded `json:"encoded,omitempty"`
+	Decoded *contracts.Decoded `json:"decoded,omitempty"`
+	Error   string             `json:"error"`
+}
+
+func abiCmd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("abi", "<signature> [type:value ...]")
+	data := fs.String("decode", "", "hex to decode against the signature rather than encoding values")
+	var topics stringFlags
+	fs.Var(&topics, "topic", "a topic of the event log to decode (repeatable)")
+	eventLog := fs.Bool("log", false, "build a log of the event as jobRun vars for ethabidecodelog, printed as a -vars file")
+	abis := abisFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !checkFormat(*format) {
+		return exitUsage
+	}
+
+	if *eventLog {
+		return eventLogCmd(fs.Arg(0), fs.Args()[1:], *abis, *format)
+	}
+
+	signature, err := contracts.ParseSignature(fs.Arg(0))
+	if err != nil {
+		return usageError(err)
+	}
+
+	result := abiResult{}
+	if *data != "" || len(topics) > 0 {
+		if fs.NArg() > 1 {
+			return usageError(fmt.Errorf("values are only given when encoding"))
+		}
+		result.Decoded, err = signature.DecodeHex(*data, topics)
+	} else {
+		values := make([]interface{}, 0, fs.NArg()-1)
+		for _, arg := range fs.Args()[1:] {
+			value, err := simulator.ConvertVar(abiArg(arg))
+			if err != nil {
+				return usageError(fmt.Errorf("%s: %w", arg, err))
+			}
+			values = append(values, value)
+		}
+		result.Encoded, err = signature.Encode(values)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if *format == "json" {
+		if err := writeJSON(os.Stdout, result); err != nil {
+			return usageError(err)
+		}
+	} else {
+		printABIResult(result)
+	}
+	if result.Error != "" {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// eventLogCmd builds a log of the event, either a signature or a
+// Contract.Event reference, and prints it as a vars file that task, run and
+// test suites take, headed by the ethabidecodelog task that reads it.
+func eventLogCmd(event string, args []string, abis, format string) int {
+	if contracts.IsReference(event) {
+		registry, err := loadRegistry(abis)
+		if err != nil {
+			return usageError(err)
+		}
+		if event, err = registry.Resolve(event, contracts.TaskETHABIDecodeLog); err != nil {
+			return usageError(err)
+		}
+	}
+
+	vars := make([]simulator.Var, 0, len(args))
+	for _, arg := range args {
+		vars = append(vars, abiArg(arg))
+	}
+	eventLog, err := simulator.BuildEventLog(event, vars)
+	if err != nil {
+		if format == "json" {
+			writeJSON(os.Stdout, map[string]string{"error": err.Error()})
+		} else {
+			fmt.Printf("error: %s\n", err)
+		}
+		return exitFailed
+	}
+
+	if format == "json" {
+		if err := writeJSON(os.Stdout, eventLog); err != nil {
+			return usageError(err)
+		}
+		return exitOK
+	}
+
+	out, err := yaml.Marshal(map[string]interface{}{"jobRun": eventLog.JobRun})
+	if err != nil {
+		return usageError(err)
+	}
+	fmt.Printf("# decode_log [type=ethabidecodelog abi=%q data=%q topics=%q]\n%s",
+		eventLog.Options["abi"], eventLog.Options["data"], eventLog.Options["topics"], out)
+	return exitOK
+}
+
+// abiArg parses a value as type:value, as -input does. Arrays and tuples
+// are given as JSON, e.g. '["0x01", "0x02"]' or '{"a": "1", "b": true}'.
+func abiArg(s string) simulator.Var {
+	var inputs inputFlags
+	inputs.Set(s)
+	v := inputs[0]
+
+	var keep interface{}
+	if v.Type == "string" && (strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{")) && json.Unmarshal([]byte(s), &keep) == nil {
+		return simulator.Var{Keep: keep}
+	}
+	return v
+}
+
+func printABIResult(result abiResult) {
+	if result.Error != "" {
+		fmt.Printf("error: %s\n", result.Error)
+		return
+	}
+
+	if e := result.Encoded; e != nil {
+		if e.Selector != "" {
+			fmt.Printf("selector  %s\n", e.Selector)
+		}
+		if e.Calldata != "" {
+			fmt.Printf("calldata  %s\n", e.Calldata)
+		}
+		fmt.Printf("data      %s\n", e.Data)
+		for i, topic := range e.Topics {
+			label := ""
+			if i == 0 {
+				label = "topics"
+			}
+			fmt.Printf("%-9s %s\n", label, topic)
+		}
+		return
+	}
+
+	d := result.Decoded
+	if d.Signature != "" {
+		fmt.Printf("%s %s\n", d.Kind, d.Signature)
+	}
+	for _, arg := range d.Args {
+		value, _ := json.Marshal(arg.Value)
+		fmt.Printf("  %s %s = %s\n", arg.Type, arg.Name, value)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pickleyd/jobspecviz/fuzz"
+	"github.com/pickleyd/jobspecviz/simulator"
+	"github.com/pickleyd/jobspecviz/testsuite"
+	"gopkg.in/yaml.v3"
+)
+
+// stringFlags collects a repeated flag.
+type stringFlags []string
+
+func (s *stringFlags) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func fuzzCmd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("fuzz", "<task type>...")
+	iterations := fs.Int("iterations", 1000, "number of cases to run per task type")
+	seed := fs.Int64("seed", 0, "seed for the cases, 0 for a random one")
+	timeout := fs.Duration("timeout", 2*time.Second, "how long a task may run before it is reported as hung")
+	allowNetwork := fs.Bool("allow-network", false, "also fuzz tasks that make requests, such as http and ethcall")
+	var options, expected stringFlags
+	fs.Var(&options, "option", "fix an option rather than generating it, as name=value (repeatable)")
+	fs.Var(&expected, "expect", "a substring of errors that aren't findings (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !checkFormat(*format) {
+		return exitUsage
+	}
+
+	fixed := map[string]string{}
+	for _, option := range options {
+		name, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return usageError(fmt.Errorf("-option %q is not name=value", option))
+		}
+		fixed[name] = value
+	}
+
+	reports := []*fuzz.Report{}
+	for _, arg := range fs.Args() {
+		taskType := simulator.TaskType(arg)
+		if !*allowNetwork && isNetworkTask(taskType) {
+			return usageError(fmt.Errorf("%s makes requests, pass -allow-network to fuzz it", taskType))
+		}
+
+		report, err := fuzz.Fuzz(ctx, fuzz.Config{
+			TaskType:   taskType,
+			Options:    fixed,
+			Iterations: *iterations,
+			Seed:       *seed,
+			Timeout:    *timeout,
+			Expected:   expected,
+		})
+		if err != nil {
+			return usageError(fmt.Errorf("%s: %w", taskType, err))
+		}
+		reports = append(reports, report)
+	}
+
+	findings := 0
+	for _, report := range reports {
+		findings += len(report.Findings)
+	}
+
+	if *format == "json" {
+		if err := writeJSON(os.Stdout, reports); err != nil {
+			return usageError(err)
+		}
+	} else if err := printFuzz(reports); err != nil {
+		return usageError(err)
+	}
+
+	if findings > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+func isNetworkTask(taskType simulator.TaskType) bool {
+	for _, t := range fuzz.NetworkTasks {
+		if t == taskType {
+			return true
+		}
+	}
+	return false
+}
+
+// printFuzz lists the findings of each task type, followed by a suite of
+// their reproducers that can be saved and replayed with jobspecviz test.
+func printFuzz(reports []*fuzz.Report) error {
+	for _, report := range reports {
+		fmt.Printf("%s: %d cases, %d invalid vars, %d rejected options, %d findings (seed %d)\n",
+			report.TaskType, report.Iterations, report.Invalid, report.Rejected, len(report.Findings), report.Seed)
+		for _, finding := range report.Findings {
+			fmt.Printf("  %s, %d hits: %s\n", finding.Kind, finding.Hits, finding.Message)
+		}
+	}
+
+	suite := testsuite.Suite{Name: "fuzz"}
+	for _, report := range reports {
+		suite.Tests = append(suite.Tests, report.Reproducers()...)
+	}
+	if len(suite.Tests) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(suite)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n# Reproducers, replay with jobspecviz test\n%s", data)
+	return nil
+}
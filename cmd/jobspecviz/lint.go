@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/pickleyd/jobspecviz/lint"
+)
+
+type lintResult struct {
+	Files   []lint.FileFindings `json:"files"`
+	Rules   []lint.RuleGroup    `json:"rules"`
+	Summary lint.Summary        `json:"summary"`
+}
+
+func lintCmd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("lint", "<spec or directory>...")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of specs to lint in parallel")
+	failOn := fs.String("fail-on", "error", "lowest severity that fails the lint: error, warning or info")
+	abis := abisFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !checkFormat(*format, "sarif") {
+		return exitUsage
+	}
+	switch lint.Severity(*failOn) {
+	case lint.SeverityError, lint.SeverityWarning, lint.SeverityInfo:
+	default:
+		return usageError(fmt.Errorf("unknown severity %q", *failOn))
+	}
+
+	registry, err := loadRegistry(*abis)
+	if err != nil {
+		return usageError(err)
+	}
+	files, err := lint.Find(fs.Args())
+	if err != nil {
+		return usageError(err)
+	}
+
+	results := lint.LintFiles(files, *workers, registry)
+	summary := lint.Summarize(results)
+
+	switch *format {
+	case "json":
+		err = writeJSON(os.Stdout, lintResult{
+			Files:   results,
+			Rules:   lint.ByRule(results),
+			Summary: summary,
+		})
+	case "sarif":
+		err = lint.WriteSARIF(os.Stdout, results)
+	default:
+		printLint(results, summary)
+	}
+	if err != nil {
+		return usageError(err)
+	}
+
+	failed := summary.Errors > 0
+	switch lint.Severity(*failOn) {
+	case lint.SeverityWarning:
+		failed = failed || summary.Warnings > 0
+	case lint.SeverityInfo:
+		failed = failed || summary.Warnings > 0 || summary.Infos > 0
+	}
+
+	if failed {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// printLint lists a single spec's findings by line, and a batch's grouped by
+// rule, followed by a summary.
+func printLint(results []lint.FileFindings, summary lint.Summary) {
+	if len(results) == 1 {
+		for _, f := range results[0].Findings {
+			fmt.Printf("%s:%d: %s: %s [%s]\n", results[0].File, f.Line, f.Severity, f.Message, f.Rule)
+		}
+	} else {
+		for _, group := range lint.ByRule(results) {
+			fmt.Printf("%s (%s, %d): %s\n", group.Rule, group.Severity, len(group.Findings), group.Description)
+			for _, f := range group.Findings {
+				fmt.Printf("  %s:%d: %s\n", f.File, f.Line, f.Message)
+			}
+			fmt.Println()
+		}
+	}
+
+	fmt.Printf("%d specs, %d with errors: %d errors, %d warnings, %d infos\n",
+		summary.Files, summary.FilesWithErrs, summary.Errors, summary.Warnings, summary.Infos)
+}
@@ -0,0 +1,196 @@
+// Command jobspecviz parses, lints, runs and tests job specs offline, using
+// the same simulator as the app's API, and can serve that API locally.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/simulator"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes
+const (
+	exitOK = 0
+	// exitFailed means the command ran but found a problem, e.g. a lint
+	// error, a failing test or a failed run
+	exitFailed = 1
+	// exitUsage means the command couldn't run, e.g. because of a bad flag
+	// or an unreadable file
+	exitUsage = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) int
+}
+
+var commands = []command{
+	{"parse", "print the pipeline's task graph", parseCmd},
+	{"lint", "check specs for common problems", lintCmd},
+	{"run", "run the whole pipeline", runCmd},
+	{"task", "run a single task", taskCmd},
+	{"test", "run test suites", testCmd},
+	{"mutate", "check that test suites catch mutated specs", mutateCmd},
+	{"fuzz", "run tasks with generated inputs to find crashes", fuzzCmd},
+	{"abi", "ABI-encode values against a signature, or decode hex", abiCmd},
+	{"serve", "serve the API locally, without Vercel", serveCmd},
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	name, args := flag.Arg(0), flag.Args()[1:]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			code := cmd.run(ctx, args)
+			stop()
+			os.Exit(code)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "jobspecviz: unknown command %q\n\n", name)
+	usage()
+	os.Exit(exitUsage)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: jobspecviz <command> [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun jobspecviz <command> -h for the command's flags. Specs are read from\nfiles, or from stdin if the file is \"-\".\n")
+}
+
+// newFlagSet returns the flags of a command, with the -format flag every
+// command has.
+func newFlagSet(name, args string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: text or json, or sarif for lint and junit for test")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: jobspecviz %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs, format
+}
+
+// abisFlag adds the -abis flag of the commands that resolve Contract.method
+// references in abi attributes.
+func abisFlag(fs *flag.FlagSet) *string {
+	return fs.String("abis", "", "file or directory of contract ABIs that abi attributes can refer to as Contract.method")
+}
+
+// loadRegistry loads the ABI registry at the path, or returns nil if there
+// is none.
+func loadRegistry(path string) (*contracts.Registry, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return contracts.LoadPath(path)
+}
+
+func checkFormat(format string, allowed ...string) bool {
+	allowed = append([]string{"text", "json"}, allowed...)
+	for _, f := range allowed {
+		if format == f {
+			return true
+		}
+	}
+	fmt.Fprintf(os.Stderr, "jobspecviz: unknown format %q, expected one of %v\n", format, allowed)
+	return false
+}
+
+// writeJSON writes the value using Chainlink's custom marshalling logic, so
+// that values like big ints and decimals come out as the node would.
+func writeJSON(w io.Writer, value interface{}) error {
+	jData, err := simulator.MarshalAsJsonSerializable(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", jData)
+	return err
+}
+
+func usageError(err error) int {
+	fmt.Fprintf(os.Stderr, "jobspecviz: %v\n", err)
+	return exitUsage
+}
+
+// varFile holds the vars for a run or task, the mock chains their ethcall
+// and ethtx tasks use, and for runs the mocks and faults. It is written in
+// YAML or JSON, with each var typed as for the var-helper, e.g.
+//
+//	jobRun:
+//	  requestBody: { value: '{"price": 1}', type: string }
+//	mocks:
+//	  fetch: { keep: { price: 1 } }
+//	chains:
+//	  - chainID: "1"
+//	    responses:
+//	      - { contract: "0x…", method: "latestAnswer()", result: "0x…" }
+type varFile struct {
+	Vars    map[string]simulator.Var `yaml:"vars" json:"vars"`
+	JobRun  map[string]simulator.Var `yaml:"jobRun" json:"jobRun"`
+	JobSpec map[string]simulator.Var `yaml:"jobSpec" json:"jobSpec"`
+	Mocks   map[string]simulator.Var `yaml:"mocks" json:"mocks"`
+	Faults  []simulator.Fault        `yaml:"faults" json:"faults"`
+	Chains  []chain.Config           `yaml:"chains" json:"chains"`
+}
+
+func loadVarFile(path string) (*varFile, error) {
+	vf := &varFile{}
+	if path == "" {
+		return vf, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(data, vf)
+	} else {
+		err = yaml.Unmarshal(data, vf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return vf, nil
+}
+
+// vars converts the vars as the var-helper does, see simulator.LoadVars.
+func (vf *varFile) vars() (map[string]interface{}, error) {
+	return simulator.LoadVars(vf.Vars, vf.JobRun, vf.JobSpec)
+}
+
+func (vf *varFile) mocks() (map[string]interface{}, error) {
+	mocks := make(map[string]interface{}, len(vf.Mocks))
+	for id, mock := range vf.Mocks {
+		value, err := simulator.LoadVar(mock)
+		if err != nil {
+			return nil, fmt.Errorf("mock %s: %w", id, err)
+		}
+		mocks[id] = value
+	}
+	return mocks, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pickleyd/jobspecviz/testsuite"
+)
+
+func mutateCmd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("mutate", "<suite or directory>...")
+	timeout := fs.Duration("timeout", 10*time.Second, "longest the tests of a spec may take against one mutant")
+	minScore := fs.Float64("min-score", 0, "fail if fewer than this percentage of mutants are killed")
+	abis := abisFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !checkFormat(*format) {
+		return exitUsage
+	}
+
+	registry, err := loadRegistry(*abis)
+	if err != nil {
+		return usageError(err)
+	}
+	files, err := testsuite.Find(fs.Args())
+	if err != nil {
+		return usageError(err)
+	}
+
+	suites := []*testsuite.Suite{}
+	for _, file := range files {
+		suite, err := testsuite.LoadFile(file)
+		if err != nil {
+			return usageError(err)
+		}
+		if suite.Registry == nil {
+			suite.Registry = registry
+		}
+		suites = append(suites, suite)
+	}
+
+	report := testsuite.Mutate(ctx, suites, *timeout)
+
+	if *format == "json" {
+		if err := writeJSON(os.Stdout, report); err != nil {
+			return usageError(err)
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "jobspecviz: interrupted")
+		return exitFailed
+	}
+	if report.Score() < *minScore {
+		return exitFailed
+	}
+	return exitOK
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/jobspec"
+)
+
+type parseResult struct {
+	Tasks []jobspec.Task `json:"tasks"`
+	Error string         `json:"error"`
+}
+
+func parseCmd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("parse", "<spec>")
+	abis := abisFlag(fs)
+	expand := fs.Bool("expand", false, "print the spec with the abi references expanded, for giving it to a node")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !checkFormat(*format) {
+		return exitUsage
+	}
+
+	registry, err := loadRegistry(*abis)
+	if err != nil {
+		return usageError(err)
+	}
+	spec, err := jobspec.LoadFile(fs.Arg(0))
+	if err != nil {
+		return usageError(err)
+	}
+
+	if *expand {
+		return expandSpec(spec, registry)
+	}
+
+	result := parseResult{Tasks: jobspec.Graph(spec.Pipeline)}
+	if spec.ParseErr != nil {
+		result.Error = spec.ParseErr.Error()
+	}
+
+	if *format == "json" {
+		if err := writeJSON(os.Stdout, result); err != nil {
+			return usageError(err)
+		}
+	} else {
+		for _, task := range result.Tasks {
+			inputs := []string{}
+			for _, input := range task.Inputs {
+				inputs = append(inputs, input.Id)
+			}
+			if len(inputs) == 0 {
+				fmt.Println(task.Id)
+			} else {
+				fmt.Printf("%s <- %s\n", task.Id, strings.Join(inputs, ", "))
+			}
+		}
+		if result.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", fs.Arg(0), result.Error)
+		}
+	}
+
+	if result.Error != "" {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// expandSpec prints the spec with the references in its abi attributes
+// replaced by the signatures they refer to.
+func expandSpec(spec *jobspec.Spec, registry *contracts.Registry) int {
+	if spec.ParseErr != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", spec.File, spec.ParseErr)
+		return exitFailed
+	}
+
+	expanded, errs := registry.Expand(spec.Text(), spec.Pipeline)
+	fmt.Print(expanded)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", spec.File, err)
+	}
+	if len(errs) > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
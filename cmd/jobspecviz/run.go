@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/jobspec"
+	"github.com/pickleyd/jobspecviz/simulator"
+	"github.com/pickleyd/jobspecviz/testsuite"
+)
+
+type runResult struct {
+	Results   []simulator.TaskRunResult  `json:"results"`
+	Summary   *simulator.Summary         `json:"summary"`
+	Precision []simulator.PrecisionTrace `json:"precision"`
+	// Snapshot are the changes from the golden snapshot, if one was given
+	Snapshot []testsuite.SnapshotChange `json:"snapshot,omitempty"`
+	Error    string                     `json:"error"`
+}
+
+func runCmd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("run", "<spec>")
+	varsPath := fs.String("vars", "", "YAML or JSON file of vars, jobRun, jobSpec, mocks, faults and chains")
+	compression := fs.Float64("backoff-compression", 0, "divide the delays between retries by this")
+	faultSeed := fs.Int64("fault-seed", 0, "seed for faults with a rate, 0 for a random one")
+	snapshot := fs.String("snapshot", "", "golden file of every task's output to compare the run with, written if it doesn't exist")
+	update := fs.Bool("update", false, "rewrite the golden snapshot instead of comparing with it")
+	abis := abisFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !checkFormat(*format) {
+		return exitUsage
+	}
+
+	registry, err := loadRegistry(*abis)
+	if err != nil {
+		return usageError(err)
+	}
+	spec, err := jobspec.LoadFile(fs.Arg(0))
+	if err != nil {
+		return usageError(err)
+	}
+	if spec.ParseErr != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), spec.ParseErr)
+		return exitFailed
+	}
+	if errs := registry.ResolvePipeline(spec.Pipeline); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		}
+		return exitFailed
+	}
+
+	vf, err := loadVarFile(*varsPath)
+	if err != nil {
+		return usageError(err)
+	}
+	vars, err := vf.vars()
+	if err != nil {
+		return usageError(err)
+	}
+	mocks, err := vf.mocks()
+	if err != nil {
+		return usageError(err)
+	}
+	chains, err := chain.NewChains(vf.Chains)
+	if err != nil {
+		return usageError(err)
+	}
+
+	run, err := simulator.PrepareRun(spec.Pipeline, vars, mocks, simulator.RunOptions{
+		BackoffCompression: *compression,
+		Faults:             vf.Faults,
+		FaultSeed:          *faultSeed,
+		Chains:             chains,
+	})
+	if err != nil {
+		return usageError(err)
+	}
+
+	result := runResult{}
+	if err := run.Execute(ctx); err != nil {
+		result.Error = err.Error()
+	}
+
+	summary := run.Summarize()
+	result.Summary = &summary
+	if result.Results, err = run.EncodeResults(); err != nil {
+		return usageError(err)
+	}
+	result.Precision = run.AnalyzePrecision()
+
+	if *snapshot != "" {
+		if result.Snapshot, err = testsuite.CheckSnapshot(run, *snapshot, *update); err != nil {
+			return usageError(err)
+		}
+	}
+
+	if *format == "json" {
+		if err := writeJSON(os.Stdout, result); err != nil {
+			return usageError(err)
+		}
+	} else {
+		printRun(result)
+	}
+
+	if result.Error != "" || !summary.Succeeded || len(result.Snapshot) > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+func printRun(result runResult) {
+	for _, task := range result.Results {
+		switch {
+		case task.Skipped:
+			fmt.Printf("%s (%s): skipped\n", task.Id, task.Type)
+		case task.Error != "":
+			fmt.Printf("%s (%s): error: %s\n", task.Id, task.Type, task.Error)
+		default:
+			note := ""
+			if task.Mocked {
+				note = " (mocked)"
+			}
+			fmt.Printf("%s (%s): %s%s\n", task.Id, task.Type, task.Value, note)
+		}
+	}
+
+	for _, trace := range result.Precision {
+		for _, warning := range trace.Warnings {
+			fmt.Printf("warning: %s: %s\n", trace.Id, warning.Message)
+		}
+	}
+
+	for _, change := range result.Snapshot {
+		fmt.Printf("snapshot: %s\n", change)
+	}
+
+	switch {
+	case result.Error != "":
+		fmt.Printf("\nrun failed: %s\n", result.Error)
+	case result.Summary.Succeeded:
+		fmt.Printf("\nrun succeeded\n")
+	default:
+		fmt.Printf("\nrun failed: %s\n", result.Summary.Reason)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	abicodec "github.com/pickleyd/jobspecviz/api/abi-codec"
+	"github.com/pickleyd/jobspecviz/api/abis"
+	"github.com/pickleyd/jobspecviz/api/analyze"
+	"github.com/pickleyd/jobspecviz/api/debug"
+	eventlog "github.com/pickleyd/jobspecviz/api/event-log"
+	"github.com/pickleyd/jobspecviz/api/graph"
+	"github.com/pickleyd/jobspecviz/api/run"
+	"github.com/pickleyd/jobspecviz/api/stream"
+	"github.com/pickleyd/jobspecviz/api/sweep"
+	"github.com/pickleyd/jobspecviz/api/task"
+	varhelper "github.com/pickleyd/jobspecviz/api/var-helper"
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/middleware"
+)
+
+// routes mount the handlers on the same routes Vercel serves them on, from
+// their directories under api. Add new endpoints here too.
+var routes = []struct {
+	path    string
+	handler http.HandlerFunc
+}{
+	{"/api/abi-codec", abicodec.Handler},
+	{"/api/abis", abis.Handler},
+	{"/api/analyze", analyze.Handler},
+	{"/api/debug", debug.Handler},
+	{"/api/event-log", eventlog.Handler},
+	{"/api/graph", graph.Handler},
+	{"/api/run", run.Handler},
+	{"/api/stream", stream.Handler},
+	{"/api/sweep", sweep.Handler},
+	{"/api/task", task.Handler},
+	{"/api/var-helper", varhelper.Handler},
+}
+
+func serveCmd(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	port := fs.Int("port", 8080, "port to listen on")
+	host := fs.String("host", "localhost", "host to listen on")
+	cors := fs.String("cors", "", `origin to allow cross-origin requests from, e.g. "http://localhost:3000" or "*"`)
+	quiet := fs.Bool("quiet", false, "don't log requests")
+	chainsPath := fs.String("chains", "", "YAML or JSON file whose chains are served over JSON-RPC at /rpc/<chainID>, for a node's ethcall and ethtx")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	vf, err := loadVarFile(*chainsPath)
+	if err != nil {
+		return usageError(err)
+	}
+	chains, err := chain.NewChains(vf.Chains)
+	if err != nil {
+		return usageError(err)
+	}
+
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.Handle(route.path, route.handler)
+	}
+	if len(chains) > 0 {
+		mux.Handle("/rpc/", chains.Handler("/rpc/"))
+	}
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	var handler http.Handler = mux
+	if *cors != "" {
+		handler = middleware.CORS(*cors, handler)
+	}
+	if !*quiet {
+		handler = middleware.Logging(logger, handler)
+	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", *host, *port),
+		Handler: handler,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Printf("serving the API on http://%s", server.Addr)
+	for id := range chains {
+		logger.Printf("serving mock chain %s on http://%s/rpc/%s", id, server.Addr, id)
+	}
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return usageError(err)
+	}
+	return exitOK
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	entries, err := os.ReadDir("../../api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := os.ReadFile("../../next.config.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mounted := map[string]bool{}
+	for _, route := range routes {
+		mounted[route.path] = true
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			if !mounted["/api/"+entry.Name()] {
+				t.Errorf("serve doesn't mount /api/%s", entry.Name())
+			}
+			if !regexp.MustCompile(`goApiRoutes = \[[^\]]*"` + regexp.QuoteMeta(entry.Name()) + `"`).Match(config) {
+				t.Errorf("next.config.js doesn't proxy /api/%s", entry.Name())
+			}
+		})
+	}
+	if len(routes) != len(entries) {
+		t.Errorf("%d routes for %d api directories", len(routes), len(entries))
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+// inputFlags collects repeated -input flags.
+type inputFlags []simulator.Var
+
+func (i *inputFlags) String() string {
+	return fmt.Sprintf("%v", *i)
+}
+
+// Set parses an input as type:value, e.g. decimal:1.5. A value without a
+// type is a string.
+func (i *inputFlags) Set(s string) error {
+	v := simulator.Var{Type: "string", Value: s}
+	if varType, value, ok := strings.Cut(s, ":"); ok && knownVarTypes[varType] {
+		v.Type, v.Value = varType, value
+	}
+	*i = append(*i, v)
+	return nil
+}
+
+var knownVarTypes = map[string]bool{
+	"string":  true,
+	"bytes32": true,
+	"bytes":   true,
+	"int":     true,
+	"float":   true,
+	"decimal": true,
+	"bool":    true,
+	"address": true,
+	"null":    true,
+}
+
+type taskResult struct {
+	Value          string                    `json:"value"`
+	Val64          string                    `json:"val64"`
+	Error          string                    `json:"error"`
+	SideEffectData string                    `json:"sideEffectData"`
+	TimedOut       bool                      `json:"timedOut"`
+	Attempts       []simulator.AttemptResult `json:"attempts,omitempty"`
+	// Contract is the ethcall or ethtx decoded against the ABI registry
+	Contract *simulator.ContractCall `json:"contract,omitempty"`
+	// Revert is why an ethcall reverted
+	Revert *contracts.Revert `json:"revert,omitempty"`
+}
+
+func taskCmd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("task", "<type>")
+	options := fs.String("options", "{}", "the task's options as a JSON object")
+	varsPath := fs.String("vars", "", "YAML or JSON file of vars, jobRun, jobSpec and chains")
+	compression := fs.Float64("backoff-compression", 0, "divide the delays between retries by this")
+	abis := abisFlag(fs)
+	var inputs inputFlags
+	fs.Var(&inputs, "input", "an input as type:value, e.g. decimal:1.5 (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !checkFormat(*format) {
+		return exitUsage
+	}
+
+	taskType := simulator.TaskType(fs.Arg(0))
+	opts := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(*options), &opts); err != nil {
+		return usageError(fmt.Errorf("options: %w", err))
+	}
+	registry, err := loadRegistry(*abis)
+	if err != nil {
+		return usageError(err)
+	}
+	if opts, err = registry.ResolveOptions(taskType.String(), opts); err != nil {
+		return usageError(fmt.Errorf("options: %w", err))
+	}
+
+	vf, err := loadVarFile(*varsPath)
+	if err != nil {
+		return usageError(err)
+	}
+	vars, err := vf.vars()
+	if err != nil {
+		return usageError(err)
+	}
+	chains, err := chain.NewChains(vf.Chains)
+	if err != nil {
+		return usageError(err)
+	}
+
+	taskInputs := make([]interface{}, 0, len(inputs))
+	for _, input := range inputs {
+		value, err := simulator.LoadVar(input)
+		if err != nil {
+			return usageError(err)
+		}
+		taskInputs = append(taskInputs, value)
+	}
+
+	result, err := simulator.RunTask(ctx, taskType, opts, vars, taskInputs, simulator.ExecuteOptions{
+		BackoffCompression: *compression,
+		Chains:             chains,
+	})
+	if err != nil {
+		return usageError(err)
+	}
+
+	out := taskResult{
+		Value:    fmt.Sprintf("%v", result.Value),
+		Attempts: simulator.EncodeAttempts(result.Attempts),
+		Contract: simulator.DecodeContractCall(registry, taskType, opts, vars, result),
+		Revert:   simulator.DecodeRevert(registry, taskType, result),
+	}
+	if out.Val64, err = simulator.ToBase64(result.Value); err != nil {
+		return usageError(err)
+	}
+	if result.Error != nil {
+		out.Error = result.Error.Error()
+		out.TimedOut = result.TimedOut()
+	}
+	if result.SideEffectData != nil {
+		out.SideEffectData = fmt.Sprintf("%v", result.SideEffectData)
+	}
+
+	if *format == "json" {
+		if err := writeJSON(os.Stdout, out); err != nil {
+			return usageError(err)
+		}
+	} else if out.Error != "" {
+		fmt.Printf("error: %s\n", out.Error)
+	} else {
+		fmt.Println(out.Value)
+		if out.SideEffectData != "" {
+			fmt.Printf("side effect data: %s\n", out.SideEffectData)
+		}
+	}
+	if out.Contract != nil && *format != "json" {
+		printContractCall(out.Contract)
+	}
+	if out.Revert != nil && *format != "json" {
+		printRevert(out.Revert)
+	}
+
+	if out.Error != "" {
+		return exitFailed
+	}
+	return exitOK
+}
+
+func printContractCall(call *simulator.ContractCall) {
+	parts := []struct {
+		label   string
+		decoded *contracts.Decoded
+	}{{"call", call.Call}, {"returned", call.Result}}
+
+	for _, part := range parts {
+		if part.decoded == nil {
+			continue
+		}
+		fmt.Printf("%s %s.%s\n", part.label, part.decoded.Contract, part.decoded.Signature)
+		for _, arg := range part.decoded.Args {
+			fmt.Printf("  %s %s: %v\n", arg.Type, arg.Name, arg.Value)
+		}
+	}
+	if call.Error != "" {
+		fmt.Printf("can't decode: %s\n", call.Error)
+	}
+}
+
+func printRevert(revert *contracts.Revert) {
+	switch revert.Kind {
+	case "error":
+		fmt.Printf("reverted with %q\n", revert.Reason)
+	case "panic":
+		fmt.Printf("panicked with %s: %s\n", revert.PanicCode, revert.PanicReason)
+	case "custom":
+		fmt.Printf("reverted with %s.%s\n", revert.Error.Contract, revert.Error.Signature)
+		for _, arg := range revert.Error.Args {
+			fmt.Printf("  %s %s: %v\n", arg.Type, arg.Name, arg.Value)
+		}
+	case "unknown":
+		if revert.Data == "" {
+			fmt.Println("reverted without a reason")
+		} else {
+			fmt.Printf("reverted with %s\n", revert.Data)
+		}
+	}
+}
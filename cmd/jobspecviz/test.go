@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pickleyd/jobspecviz/testsuite"
+)
+
+func testCmd(ctx context.Context, args []string) int {
+	fs, format := newFlagSet("test", "<suite or directory>...")
+	watchMode := fs.Bool("watch", false, "keep running, re-linting the specs and re-running the suites that change")
+	update := fs.Bool("update", false, "rewrite the tests' golden snapshots instead of comparing with them")
+	coverageDir := fs.String("coverage", "", "write a coverage report of the pipeline tests' specs to the directory, as JSON, HTML and DOT")
+	abis := abisFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !checkFormat(*format, "junit") {
+		return exitUsage
+	}
+
+	registry, err := loadRegistry(*abis)
+	if err != nil {
+		return usageError(err)
+	}
+
+	if *watchMode {
+		if *format != "text" {
+			return usageError(fmt.Errorf("-watch only supports the text format"))
+		}
+		if *update {
+			// Rewriting a snapshot would re-run the tests reading it, which
+			// would rewrite it again
+			return usageError(fmt.Errorf("-watch can't be used with -update"))
+		}
+		return watch(ctx, fs.Args(), *abis, registry)
+	}
+
+	files, err := testsuite.Find(fs.Args())
+	if err != nil {
+		return usageError(err)
+	}
+
+	report := &testsuite.Report{Results: []testsuite.Result{}}
+	coverage := testsuite.NewCoverage()
+	for _, file := range files {
+		suite, err := testsuite.LoadFile(file)
+		if err != nil {
+			report.Add(testsuite.LoadFailure(file, err))
+			continue
+		}
+		suite.UpdateSnapshots = *update
+		if suite.Registry == nil {
+			suite.Registry = registry
+		}
+		results := testsuite.Run(ctx, suite)
+		report.Add(results...)
+		coverage.Add(suite, results)
+	}
+
+	if *coverageDir != "" {
+		if err := writeCoverage(*coverageDir, coverage); err != nil {
+			return usageError(err)
+		}
+	}
+
+	switch *format {
+	case "json":
+		if err := writeJSON(os.Stdout, report); err != nil {
+			return usageError(err)
+		}
+	case "junit":
+		if err := report.WriteJUnit(os.Stdout); err != nil {
+			return usageError(err)
+		}
+	default:
+		report.WriteText(os.Stdout)
+		if *coverageDir != "" {
+			fmt.Println()
+			coverage.WriteText(os.Stdout)
+		}
+	}
+
+	if !report.OK() {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// writeCoverage writes coverage.json and coverage.html to the directory, and
+// an annotated graph of each spec, named after it.
+func writeCoverage(dir string, coverage *testsuite.Coverage) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	write := func(name string, w func(io.Writer) error) error {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := w(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	err := write("coverage.json", func(w io.Writer) error { return writeJSON(w, coverage) })
+	if err != nil {
+		return err
+	}
+	if err := write("coverage.html", coverage.WriteHTML); err != nil {
+		return err
+	}
+
+	used := map[string]bool{}
+	for _, spec := range coverage.Specs {
+		name := dotName(spec.Spec)
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s-%d", dotName(spec.Spec), i)
+		}
+		used[name] = true
+
+		if err := write(name+".dot", spec.WriteDOT); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotName turns a spec's key, a path possibly followed by "#test", into a
+// file name.
+func dotName(spec string) string {
+	path, test, _ := strings.Cut(spec, "#")
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if test != "" {
+		name += "-" + test
+	}
+	return strings.NewReplacer(" ", "_", "/", "_").Replace(name)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTestCmdLoadFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		suite string
+	}{
+		{name: "invalid YAML", suite: "tests: [\n"},
+		{name: "unknown field", suite: "tests:\n  - name: a\n    wnat: {value: 1}\n"},
+		{name: "missing spec file", suite: "specFile: missing.toml\ntests: []\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "broken.test.yaml"), []byte(test.suite), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "empty.test.yaml"), []byte("tests: []\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := testCmd(context.Background(), []string{"-format", "json", dir}); got != exitFailed {
+				t.Errorf("exit code %d, want %d", got, exitFailed)
+			}
+		})
+	}
+}
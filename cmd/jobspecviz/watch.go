@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/lint"
+	"github.com/pickleyd/jobspecviz/testsuite"
+)
+
+// settle is how long the watcher waits for a burst of events, e.g. an editor
+// writing a temporary file and renaming it, to finish before re-running.
+const settle = 200 * time.Millisecond
+
+// watcher keeps the latest lint findings and test results of the specs and
+// suites under the watched paths, so that a change only re-runs what it
+// affects.
+type watcher struct {
+	ctx      context.Context
+	findings map[string]lint.FileFindings
+	results  map[string][]testsuite.Result
+	// loadErrs are the suites that can't be loaded, by file
+	loadErrs map[string]error
+	// deps are the files each suite reads, by suite file: spec files, ABIs
+	// and golden snapshots
+	deps map[string][]string
+	// abis is the -abis path, and registry the registry loaded from it, for
+	// specs and suites without their own
+	abis     string
+	registry *contracts.Registry
+}
+
+// watch lints the specs and runs the suites under the paths, then re-lints a
+// spec and re-runs the tests reading it whenever it changes, re-runs the
+// tests reading any other file that changes, re-runs a whole suite whenever
+// it changes, and re-lints and re-runs everything when the -abis change,
+// until the context is cancelled.
+func watch(ctx context.Context, paths []string, abis string, registry *contracts.Registry) int {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return usageError(err)
+	}
+	defer fsw.Close()
+
+	w := &watcher{
+		ctx:      ctx,
+		findings: map[string]lint.FileFindings{},
+		results:  map[string][]testsuite.Result{},
+		loadErrs: map[string]error{},
+		deps:     map[string][]string{},
+		abis:     abis,
+		registry: registry,
+	}
+
+	changed := map[string]bool{}
+	for _, path := range paths {
+		if err := addWatches(fsw, path, changed); err != nil {
+			return usageError(err)
+		}
+	}
+	if abis != "" {
+		w.watchDep(fsw, abis)
+	}
+	w.update(fsw, changed)
+
+	timer := time.NewTimer(settle)
+	timer.Stop()
+	changed = map[string]bool{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case err := <-fsw.Errors:
+			fmt.Fprintf(os.Stderr, "jobspecviz: %v\n", err)
+		case event := <-fsw.Events:
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			path := filepath.Clean(event.Name)
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				if err := addWatches(fsw, path, changed); err != nil {
+					fmt.Fprintf(os.Stderr, "jobspecviz: %v\n", err)
+				}
+			} else if w.isWatched(path) {
+				changed[path] = true
+			}
+			timer.Reset(settle)
+		case <-timer.C:
+			if len(changed) == 0 {
+				continue
+			}
+			fmt.Printf("\n--- %s\n", time.Now().Format("15:04:05"))
+			w.update(fsw, changed)
+			changed = map[string]bool{}
+		}
+	}
+}
+
+// isWatched reports whether a change to the file needs a re-run: it is a
+// spec, a suite, a file a suite reads or one of the -abis.
+func (w *watcher) isWatched(path string) bool {
+	if filepath.Ext(path) == ".toml" || testsuite.IsSuiteFile(path) {
+		return true
+	}
+	if w.abis != "" && within(path, w.abis) {
+		return true
+	}
+	for _, deps := range w.deps {
+		for _, dep := range deps {
+			if within(path, dep) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// within reports whether the path is the file dep, or is in the directory
+// dep.
+func within(path, dep string) bool {
+	dep = filepath.Clean(dep)
+	return path == dep || filepath.Dir(path) == dep
+}
+
+// watchDep watches a file a suite reads through its directory, or a
+// directory of them itself, as it may be outside the watched paths.
+func (w *watcher) watchDep(fsw *fsnotify.Watcher, dep string) {
+	dir := dep
+	if info, err := os.Stat(dep); err != nil || !info.IsDir() {
+		dir = filepath.Dir(dep)
+	}
+	if err := fsw.Add(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "jobspecviz: %v\n", err)
+	}
+}
+
+// addWatches watches the directory, and those under it, marking the specs
+// and suites in them as changed. A file is watched through its directory.
+func addWatches(fsw *fsnotify.Watcher, path string, changed map[string]bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		changed[filepath.Clean(path)] = true
+		return fsw.Add(filepath.Dir(path))
+	}
+
+	return filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return fsw.Add(p)
+		}
+		if filepath.Ext(p) == ".toml" || testsuite.IsSuiteFile(p) {
+			changed[filepath.Clean(p)] = true
+		}
+		return nil
+	})
+}
+
+// update re-lints the changed specs and re-runs the changed suites, along
+// with the tests reading a changed file, then prints what was re-run and the
+// totals. A change to the -abis reloads them and re-runs everything.
+func (w *watcher) update(fsw *fsnotify.Watcher, changed map[string]bool) {
+	specs := []string{}
+	// suites are the suites to re-run, with the files they read that
+	// changed, or nil to re-run every test
+	suites := map[string][]string{}
+
+	for path := range changed {
+		if w.abis != "" && within(path, w.abis) {
+			w.reloadABIs(changed)
+			break
+		}
+	}
+
+	for path := range changed {
+		_, err := os.Stat(path)
+		exists := err == nil
+
+		if filepath.Ext(path) == ".toml" {
+			if exists {
+				specs = append(specs, path)
+			} else {
+				delete(w.findings, path)
+			}
+		}
+
+		for suite, deps := range w.deps {
+			for _, dep := range deps {
+				if paths, ok := suites[suite]; within(path, dep) && (!ok || paths != nil) {
+					suites[suite] = append(paths, path)
+					break
+				}
+			}
+		}
+
+		if testsuite.IsSuiteFile(path) {
+			if exists {
+				suites[path] = nil
+			} else {
+				delete(w.results, path)
+				delete(w.loadErrs, path)
+				delete(w.deps, path)
+			}
+		}
+	}
+
+	sort.Strings(specs)
+	linted := lint.LintFiles(specs, runtime.NumCPU(), w.registry)
+	for _, result := range linted {
+		w.findings[result.File] = result
+		for _, f := range result.Findings {
+			fmt.Printf("%s:%d: %s: %s [%s]\n", result.File, f.Line, f.Severity, f.Message, f.Rule)
+		}
+	}
+
+	files := make([]string, 0, len(suites))
+	for file := range suites {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	report := &testsuite.Report{Results: []testsuite.Result{}}
+	for _, file := range files {
+		results, err := w.runSuite(fsw, file, suites[file])
+		if err != nil {
+			fmt.Printf("ERROR %v\n", err)
+			continue
+		}
+		report.Add(results...)
+	}
+	if len(report.Results) > 0 {
+		report.WriteText(os.Stdout)
+	}
+
+	w.printTotals(len(linted), len(report.Results), len(files))
+}
+
+// runSuite loads the suite and re-runs the tests reading one of the changed
+// files, or every test if changed is nil or the suite hasn't run yet. It
+// remembers the results of all the tests and the files the suite reads,
+// which are watched even if they are outside the watched paths, and returns
+// the results of the tests it ran.
+func (w *watcher) runSuite(fsw *fsnotify.Watcher, file string, changed []string) ([]testsuite.Result, error) {
+	previous, ran := w.results[file]
+	delete(w.results, file)
+	delete(w.loadErrs, file)
+
+	suite, err := testsuite.LoadFile(file)
+	if err != nil {
+		// Keep watching the files it read before, so that fixing a missing
+		// spec re-runs it
+		w.loadErrs[file] = err
+		return nil, err
+	}
+
+	if suite.Registry == nil {
+		suite.Registry = w.registry
+	}
+
+	w.deps[file] = suite.Files()
+	for _, dep := range w.deps[file] {
+		w.watchDep(fsw, dep)
+	}
+
+	if !ran || changed == nil {
+		results := testsuite.Run(w.ctx, suite)
+		w.results[file] = results
+		return results, nil
+	}
+
+	kept := map[string]testsuite.Result{}
+	for _, result := range previous {
+		kept[result.Name] = result
+	}
+	all := make([]testsuite.Result, 0, len(suite.Tests))
+	results := []testsuite.Result{}
+	for _, test := range suite.Tests {
+		result, ok := kept[test.Name]
+		if !ok || readsChanged(suite, test, changed) {
+			result = testsuite.RunTest(w.ctx, suite, test)
+			results = append(results, result)
+		}
+		all = append(all, result)
+	}
+	w.results[file] = all
+	return results, nil
+}
+
+// readsChanged reports whether the test reads one of the changed files.
+func readsChanged(suite *testsuite.Suite, test testsuite.Test, changed []string) bool {
+	for _, dep := range suite.TestFiles(test) {
+		for _, path := range changed {
+			if within(path, dep) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reloadABIs reloads the -abis registry and marks every spec and suite seen
+// so far as changed, keeping the old registry if the new one doesn't load.
+func (w *watcher) reloadABIs(changed map[string]bool) {
+	registry, err := loadRegistry(w.abis)
+	if err != nil {
+		fmt.Printf("ERROR %v\n", err)
+		return
+	}
+	w.registry = registry
+
+	for file := range w.findings {
+		changed[file] = true
+	}
+	for file := range w.results {
+		changed[file] = true
+	}
+	for file := range w.loadErrs {
+		changed[file] = true
+	}
+}
+
+func (w *watcher) printTotals(linted, ran, suites int) {
+	findings := make([]lint.FileFindings, 0, len(w.findings))
+	for _, f := range w.findings {
+		findings = append(findings, f)
+	}
+	summary := lint.Summarize(findings)
+
+	passed, failed := 0, len(w.loadErrs)
+	for _, results := range w.results {
+		for _, result := range results {
+			if result.Passed {
+				passed++
+			} else {
+				failed++
+			}
+		}
+	}
+
+	fmt.Printf("re-linted %d specs and re-ran %d tests in %d suites\n", linted, ran, suites)
+	fmt.Printf("%d specs: %d errors, %d warnings, %d infos; %d suites: %d tests passed, %d failed\n",
+		summary.Files, summary.Errors, summary.Warnings, summary.Infos, len(w.results)+len(w.loadErrs), passed, failed)
+	fmt.Println("watching for changes, press Ctrl+C to stop")
+}
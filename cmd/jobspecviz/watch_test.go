@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pickleyd/jobspecviz/testsuite"
+)
+
+func TestIsWatched(t *testing.T) {
+	w := &watcher{
+		abis: "abis/",
+		deps: map[string][]string{
+			"suites/price.test.yaml": {"specs/price.toml", "golden/price.json", "shared/abis"},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "other/spec.toml", want: true},
+		{path: "other/price.test.yaml", want: true},
+		{path: "other/price.test.json", want: true},
+		{path: "abis/Token.json", want: true},
+		{path: "abis", want: true},
+		{path: "abis/nested/Token.json"},
+		{path: "golden/price.json", want: true},
+		{path: "golden/other.json"},
+		{path: "shared/abis/Vault.json", want: true},
+		{path: "other/notes.md"},
+		{path: "other/config.yaml"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if got := w.isWatched(filepath.FromSlash(test.path)); got != test.want {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadsChanged(t *testing.T) {
+	suite := &testsuite.Suite{
+		File:     filepath.FromSlash("suites/price.test.yaml"),
+		SpecFile: "price.toml",
+		ABIs:     "abis",
+	}
+
+	tests := []struct {
+		name    string
+		test    testsuite.Test
+		changed []string
+		want    bool
+	}{
+		{name: "suite spec", test: testsuite.Test{Name: "run"}, changed: []string{"suites/price.toml"}, want: true},
+		{name: "own spec", test: testsuite.Test{Name: "run", SpecFile: "other.toml"}, changed: []string{"suites/price.toml"}},
+		{name: "inline spec", test: testsuite.Test{Name: "run", Spec: "a [type=any]"}, changed: []string{"suites/price.toml"}},
+		{name: "task test", test: testsuite.Test{Name: "divide", Task: "divide"}, changed: []string{"suites/price.toml"}},
+		{name: "ABI", test: testsuite.Test{Name: "divide", Task: "divide"}, changed: []string{"suites/abis/Feed.json"}, want: true},
+		{name: "own snapshot", test: testsuite.Test{Name: "run", Snapshot: "run.golden"}, changed: []string{"suites/run.golden"}, want: true},
+		{name: "another snapshot", test: testsuite.Test{Name: "run", Snapshot: "run.golden"}, changed: []string{"suites/other.golden"}},
+		{name: "one of several", test: testsuite.Test{Name: "run", SpecFile: "other.toml"}, changed: []string{"suites/price.toml", "suites/other.toml"}, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := []string{}
+			for _, path := range test.changed {
+				changed = append(changed, filepath.FromSlash(path))
+			}
+			if got := readsChanged(suite, test.test, changed); got != test.want {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTestCmdWatchFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "update", args: []string{"-watch", "-update", "."}},
+		{name: "JSON", args: []string{"-watch", "-format", "json", "."}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := testCmd(context.Background(), test.args); got != exitUsage {
+				t.Errorf("exit code %d, want %d", got, exitUsage)
+			}
+		})
+	}
+}
@@ -0,0 +1,533 @@
+package contracts
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+)
+
+// Signature is a single method, event or error, or a bare list of arguments
+// as ethabidecode takes them, to encode values against and decode hex with.
+type Signature struct {
+	// Kind is function, event, error or arguments
+	Kind string
+	Name string
+	// Sig is the canonical signature, e.g. transfer(address,uint256)
+	Sig string
+	// ID is the selector of a method or error, or the topic of an event
+	ID        []byte
+	Inputs    abi.Arguments
+	Outputs   abi.Arguments
+	Anonymous bool
+}
+
+// ParseSignature parses a human-readable signature, e.g.
+// "transfer(address to, uint256 amount)",
+// "event Transfer(address indexed from, address indexed to, uint256 value)"
+// or "error Unauthorized(address caller)", or a list of arguments such as
+// "uint256 answer, uint256 updatedAt". A signature without a keyword is a
+// function.
+func ParseSignature(s string) (*Signature, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("the signature is empty")
+	}
+
+	if !isFragment(s) {
+		params, err := parseParams(s)
+		if err != nil {
+			return nil, err
+		}
+		args, err := arguments(params)
+		if err != nil {
+			return nil, err
+		}
+		return &Signature{Kind: "arguments", Inputs: args}, nil
+	}
+
+	f, err := parseFragment(s)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil || f.Type == "constructor" {
+		return nil, fmt.Errorf("%q has no selector to encode against", s)
+	}
+	parsed, err := parseSignatures([]string{s})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range parsed.Methods {
+		return &Signature{Kind: "function", Name: m.RawName, Sig: m.Sig, ID: m.ID, Inputs: m.Inputs, Outputs: m.Outputs}, nil
+	}
+	for _, e := range parsed.Events {
+		return &Signature{Kind: "event", Name: e.RawName, Sig: e.Sig, ID: e.ID.Bytes(), Inputs: e.Inputs, Anonymous: e.Anonymous}, nil
+	}
+	for _, e := range parsed.Errors {
+		return &Signature{Kind: "error", Name: e.Name, Sig: e.Sig, ID: e.ID[:4], Inputs: e.Inputs}, nil
+	}
+	return nil, fmt.Errorf("%q has no selector to encode against", s)
+}
+
+// ParseEvent parses an event signature, in the form ethabidecodelog takes it
+// or with the event keyword.
+func ParseEvent(s string) (*Signature, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "event ") {
+		s = "event " + s
+	}
+	return ParseSignature(s)
+}
+
+// isFragment tells a method, event or error signature from a list of
+// arguments: it starts with a keyword or a name followed by its arguments.
+func isFragment(s string) bool {
+	for _, kind := range []string{"function", "event", "error", "constructor"} {
+		if strings.HasPrefix(s, kind+" ") || strings.HasPrefix(s, kind+"(") {
+			return true
+		}
+	}
+	open := strings.Index(s, "(")
+	if open < 0 {
+		return false
+	}
+	name := strings.TrimSpace(s[:open])
+	return isIdentifier(name) && name != "tuple"
+}
+
+func arguments(params []param) (abi.Arguments, error) {
+	args := make(abi.Arguments, 0, len(params))
+	for _, p := range params {
+		t, err := p.abiType()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, abi.Argument{Name: p.Name, Type: t, Indexed: p.Indexed})
+	}
+	return args, nil
+}
+
+// Encoded is values encoded against a signature, in hex.
+type Encoded struct {
+	Kind      string `json:"kind"`
+	Signature string `json:"signature,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+	// Calldata is the selector followed by the arguments, as ethabiencode
+	// returns them, for a method or error
+	Calldata string `json:"calldata,omitempty"`
+	// Data is the arguments without a selector, as ethabidecode takes them,
+	// or an event's non-indexed arguments, as ethabidecodelog takes them
+	Data string `json:"data"`
+	// Topics are an event's topic followed by its indexed arguments
+	Topics []string `json:"topics,omitempty"`
+}
+
+// Encode encodes the values, one for each of the signature's arguments. The
+// values are what the var-helper converts vars to, e.g. *big.Int for an
+// int, and are converted to the argument types the same way the tasks do.
+func (s *Signature) Encode(values []interface{}) (*Encoded, error) {
+	if len(values) != len(s.Inputs) {
+		return nil, fmt.Errorf("%d values for %d arguments", len(values), len(s.Inputs))
+	}
+
+	converted := make([]interface{}, len(values))
+	for i, arg := range s.Inputs {
+		v, err := abiValue(values[i], arg.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", argName(arg, i), err)
+		}
+		converted[i] = v
+	}
+
+	encoded := &Encoded{Kind: s.Kind, Signature: s.Sig}
+	if s.Kind != "event" {
+		data, err := s.Inputs.Pack(converted...)
+		if err != nil {
+			return nil, err
+		}
+		encoded.Data = hexutil.Encode(data)
+		if s.ID != nil {
+			encoded.Selector = hexutil.Encode(s.ID)
+			encoded.Calldata = hexutil.Encode(append(append([]byte{}, s.ID...), data...))
+		}
+		return encoded, nil
+	}
+
+	encoded.Selector = hexutil.Encode(s.ID)
+	encoded.Topics = []string{}
+	if !s.Anonymous {
+		encoded.Topics = append(encoded.Topics, encoded.Selector)
+	}
+	nonIndexed := []interface{}{}
+	for i, arg := range s.Inputs {
+		if !arg.Indexed {
+			nonIndexed = append(nonIndexed, converted[i])
+			continue
+		}
+		topic, err := topicOf(arg.Type, converted[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", argName(arg, i), err)
+		}
+		encoded.Topics = append(encoded.Topics, topic.Hex())
+	}
+	data, err := s.Inputs.NonIndexed().Pack(nonIndexed...)
+	if err != nil {
+		return nil, err
+	}
+	encoded.Data = hexutil.Encode(data)
+	return encoded, nil
+}
+
+// topicOf is the topic of an indexed argument: the value itself, padded to
+// 32 bytes, or the hash of a string or bytes. Arrays and tuples are hashed
+// in their in-place encoding, as Solidity does.
+func topicOf(t abi.Type, value interface{}) (common.Hash, error) {
+	switch t.T {
+	case abi.StringTy:
+		return crypto.Keccak256Hash([]byte(value.(string))), nil
+	case abi.BytesTy:
+		return crypto.Keccak256Hash(value.([]byte)), nil
+	case abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		encoded, err := inPlace(t, reflect.ValueOf(value))
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return crypto.Keccak256Hash(encoded), nil
+	}
+	word, err := abi.Arguments{{Type: t}}.Pack(value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(word), nil
+}
+
+// inPlace encodes an indexed array or tuple for hashing into its topic: the
+// elements or fields one after the other, strings and bytes padded to 32
+// bytes, without offsets or lengths.
+func inPlace(t abi.Type, v reflect.Value) ([]byte, error) {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy:
+		b := []byte(v.String())
+		if t.T == abi.BytesTy {
+			b = v.Bytes()
+		}
+		return common.RightPadBytes(b, (len(b)+31)/32*32), nil
+	case abi.SliceTy, abi.ArrayTy:
+		encoded := []byte{}
+		for i := 0; i < v.Len(); i++ {
+			elem, err := inPlace(*t.Elem, v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, elem...)
+		}
+		return encoded, nil
+	case abi.TupleTy:
+		encoded := []byte{}
+		for i, elem := range t.TupleElems {
+			field, err := inPlace(*elem, v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, field...)
+		}
+		return encoded, nil
+	}
+	return abi.Arguments{{Type: t}}.Pack(v.Interface())
+}
+
+// Decode decodes hex against the signature: calldata or the return data of
+// a method, revert data of an error, data of arguments, or the topics and
+// data of an event log. Method data is taken as calldata if it starts with
+// the method's selector.
+func (s *Signature) Decode(data []byte, topics []common.Hash) (*Decoded, error) {
+	decoded := &Decoded{Kind: s.Kind, Name: s.Name, Signature: s.Sig}
+	if s.ID != nil {
+		decoded.Selector = hexutil.Encode(s.ID)
+	}
+
+	var err error
+	switch s.Kind {
+	case "arguments":
+		decoded.Args, err = decodeArgs(s.Inputs, data)
+	case "function":
+		if len(data) >= 4 && bytes.Equal(data[:4], s.ID) {
+			decoded.Args, err = decodeArgs(s.Inputs, data[4:])
+		} else if len(s.Outputs) > 0 {
+			decoded.Args, err = decodeArgs(s.Outputs, data)
+		} else {
+			err = fmt.Errorf("the data doesn't start with the selector %s", decoded.Selector)
+		}
+	case "error":
+		if len(data) < 4 || !bytes.Equal(data[:4], s.ID) {
+			return nil, fmt.Errorf("the data doesn't start with the selector %s", decoded.Selector)
+		}
+		decoded.Args, err = decodeArgs(s.Inputs, data[4:])
+	case "event":
+		decoded.Args, err = s.decodeLog(data, topics)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// DecodeHex is Decode for hex data and topics. Data can be left out for an
+// event without non-indexed arguments.
+func (s *Signature) DecodeHex(data string, topics []string) (*Decoded, error) {
+	b := []byte{}
+	if data != "" {
+		var err error
+		if b, err = hexutil.Decode(data); err != nil {
+			return nil, fmt.Errorf("data: %w", err)
+		}
+	}
+
+	hashes := make([]common.Hash, len(topics))
+	for i, topic := range topics {
+		h, err := hexutil.Decode(topic)
+		if err != nil {
+			return nil, fmt.Errorf("topic %d: %w", i, err)
+		}
+		if len(h) != common.HashLength {
+			return nil, fmt.Errorf("topic %d is %d bytes rather than 32", i, len(h))
+		}
+		hashes[i] = common.BytesToHash(h)
+	}
+	return s.Decode(b, hashes)
+}
+
+func (s *Signature) decodeLog(data []byte, topics []common.Hash) ([]Arg, error) {
+	if !s.Anonymous {
+		if len(topics) == 0 || !bytes.Equal(topics[0].Bytes(), s.ID) {
+			return nil, fmt.Errorf("the first topic isn't the event's, %s", hexutil.Encode(s.ID))
+		}
+		topics = topics[1:]
+	}
+
+	nonIndexed, err := decodeArgs(s.Inputs.NonIndexed(), data)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]Arg, 0, len(s.Inputs))
+	for i, arg := range s.Inputs {
+		if !arg.Indexed {
+			args, nonIndexed = append(args, nonIndexed[0]), nonIndexed[1:]
+			args[len(args)-1].Name = argName(arg, i)
+			continue
+		}
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("no topic for the indexed %s", argName(arg, i))
+		}
+		topic := topics[0]
+		topics = topics[1:]
+
+		// Strings, bytes, arrays and tuples are hashed into their topic
+		var value interface{} = topic.Hex()
+		switch arg.Type.T {
+		case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		default:
+			values, err := abi.Arguments{{Type: arg.Type}}.UnpackValues(topic.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", argName(arg, i), err)
+			}
+			value = display(reflect.ValueOf(values[0]), arg.Type)
+		}
+		args = append(args, Arg{Name: argName(arg, i), Type: arg.Type.String(), Value: value})
+	}
+	if len(topics) > 0 {
+		return nil, fmt.Errorf("%d more topics than indexed arguments", len(topics))
+	}
+	return args, nil
+}
+
+func argName(arg abi.Argument, i int) string {
+	if arg.Name == "" {
+		return fmt.Sprintf("arg%d", i)
+	}
+	return arg.Name
+}
+
+// abiValue converts a value, as the var-helper converts it, to the Go type
+// go-ethereum packs as the ABI type. Hex strings stand for addresses and
+// bytes, and integers can also be decimals without a fraction, floats or
+// strings.
+func abiValue(value interface{}, t abi.Type) (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("no value for %s", t)
+	}
+	target := t.GetType()
+
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		n, err := toBigInt(value)
+		if err != nil {
+			return nil, err
+		}
+		if t.T == abi.UintTy && n.Sign() < 0 {
+			return nil, fmt.Errorf("%s is negative, which a %s can't be", n, t)
+		}
+		if bits := n.BitLen(); t.T == abi.UintTy && bits > t.Size || t.T == abi.IntTy && bits > t.Size-1 && !isMinInt(n, t.Size) {
+			return nil, fmt.Errorf("%s overflows %s", n, t)
+		}
+		if target.Kind() == reflect.Ptr {
+			return n, nil
+		}
+		if t.T == abi.UintTy {
+			return reflect.ValueOf(n.Uint64()).Convert(target).Interface(), nil
+		}
+		return reflect.ValueOf(n.Int64()).Convert(target).Interface(), nil
+
+	case abi.BoolTy:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if v == "true" || v == "false" {
+				return v == "true", nil
+			}
+		}
+
+	case abi.StringTy:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+
+	case abi.AddressTy:
+		switch v := value.(type) {
+		case common.Address:
+			return v, nil
+		case string:
+			if common.IsHexAddress(v) {
+				return common.HexToAddress(v), nil
+			}
+		}
+		if b, ok := toByteSlice(value); ok && len(b) == common.AddressLength {
+			return common.BytesToAddress(b), nil
+		}
+
+	case abi.BytesTy:
+		if b, ok := toByteSlice(value); ok {
+			return b, nil
+		}
+
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b, ok := toByteSlice(value)
+		if !ok {
+			break
+		}
+		if len(b) > target.Len() {
+			return nil, fmt.Errorf("%d bytes don't fit in %s", len(b), t)
+		}
+		fixed := reflect.New(target).Elem()
+		reflect.Copy(fixed, reflect.ValueOf(b))
+		return fixed.Interface(), nil
+
+	case abi.SliceTy, abi.ArrayTy:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			break
+		}
+		if t.T == abi.ArrayTy && v.Len() != t.Size {
+			return nil, fmt.Errorf("%d values for %s", v.Len(), t)
+		}
+		s := reflect.New(target).Elem()
+		if t.T == abi.SliceTy {
+			s = reflect.MakeSlice(target, v.Len(), v.Len())
+		}
+		for i := 0; i < v.Len(); i++ {
+			elem, err := abiValue(v.Index(i).Interface(), *t.Elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			s.Index(i).Set(reflect.ValueOf(elem))
+		}
+		return s.Interface(), nil
+
+	case abi.TupleTy:
+		fields := make([]interface{}, len(t.TupleElems))
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for i, name := range t.TupleRawNames {
+				fields[i] = v[name]
+			}
+		case []interface{}:
+			if len(v) != len(fields) {
+				return nil, fmt.Errorf("%d values for %s", len(v), t)
+			}
+			copy(fields, v)
+		default:
+			return nil, fmt.Errorf("%T can't be a %s, give an object or an array", value, t)
+		}
+
+		tuple := reflect.New(target).Elem()
+		for i, elem := range t.TupleElems {
+			field, err := abiValue(fields[i], *elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", t.TupleRawNames[i], err)
+			}
+			tuple.Field(i).Set(reflect.ValueOf(field))
+		}
+		return tuple.Interface(), nil
+	}
+
+	return nil, fmt.Errorf("%T %v can't be a %s", value, value, t)
+}
+
+func isMinInt(n *big.Int, size int) bool {
+	return n.Cmp(new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), uint(size-1)))) == 0
+}
+
+func toBigInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, nil
+	case decimal.Decimal:
+		if v.Equal(v.Truncate(0)) {
+			return v.BigInt(), nil
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			break
+		}
+		if f := big.NewFloat(v); f.IsInt() {
+			n, _ := f.Int(nil)
+			return n, nil
+		}
+	case string:
+		if n, ok := new(big.Int).SetString(v, 0); ok {
+			return n, nil
+		}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		n, _ := new(big.Int).SetString(fmt.Sprint(v), 10)
+		return n, nil
+	}
+	return nil, fmt.Errorf("%T %v is not an integer", value, value)
+}
+
+func toByteSlice(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return v, true
+	case hexutil.Bytes:
+		return v, true
+	case [32]byte:
+		return v[:], true
+	case common.Hash:
+		return v.Bytes(), true
+	case string:
+		b, err := hexutil.Decode(v)
+		return b, err == nil
+	}
+	return nil, false
+}
@@ -0,0 +1,179 @@
+package contracts
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	holder    = "0x5f4ec3df9cbd43714fe2740f5e3616155c5b8419"
+	recipient = "0x000000000000000000000000000000000000dead"
+)
+
+func TestSignatureRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		signature     string
+		values        []interface{}
+		wantKind      string
+		wantSelector  string
+		wantCalldata  string
+		wantTopics    int
+		wantArgValues []interface{}
+	}{
+		{
+			name:         "function",
+			signature:    "transfer(address to, uint256 amount)",
+			values:       []interface{}{recipient, big.NewInt(1000)},
+			wantKind:     "function",
+			wantSelector: "0xa9059cbb",
+			wantCalldata: "0xa9059cbb" +
+				"000000000000000000000000000000000000000000000000000000000000dead" +
+				"00000000000000000000000000000000000000000000000000000000000003e8",
+			wantArgValues: []interface{}{recipient, "1000"},
+		},
+		{
+			name:          "arguments",
+			signature:     "int256 answer, bool ok, string note",
+			values:        []interface{}{big.NewInt(-5), true, "hi"},
+			wantKind:      "arguments",
+			wantArgValues: []interface{}{"-5", true, "hi"},
+		},
+		{
+			name:          "decimal and float integers",
+			signature:     "uint256 a, uint8 b",
+			values:        []interface{}{decimal.RequireFromString("12"), float64(7)},
+			wantKind:      "arguments",
+			wantArgValues: []interface{}{"12", "7"},
+		},
+		{
+			name:          "error",
+			signature:     "error Unauthorized(address caller)",
+			values:        []interface{}{holder},
+			wantKind:      "error",
+			wantSelector:  hexutil.Encode(crypto.Keccak256([]byte("Unauthorized(address)"))[:4]),
+			wantArgValues: []interface{}{holder},
+		},
+		{
+			name:          "event",
+			signature:     "event Transfer(address indexed from, address indexed to, uint256 value)",
+			values:        []interface{}{holder, recipient, big.NewInt(42)},
+			wantKind:      "event",
+			wantSelector:  "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+			wantTopics:    3,
+			wantArgValues: []interface{}{holder, recipient, "42"},
+		},
+		{
+			name:          "anonymous event",
+			signature:     "event Ping(uint256 indexed id, bytes payload) anonymous",
+			values:        []interface{}{big.NewInt(3), []byte{1, 2}},
+			wantKind:      "event",
+			wantTopics:    1,
+			wantArgValues: []interface{}{"3", "0x0102"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := ParseSignature(test.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sig.Kind != test.wantKind {
+				t.Errorf("kind %q, want %q", sig.Kind, test.wantKind)
+			}
+
+			encoded, err := sig.Encode(test.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.wantSelector != "" && encoded.Selector != test.wantSelector {
+				t.Errorf("selector %s, want %s", encoded.Selector, test.wantSelector)
+			}
+			if test.wantCalldata != "" && encoded.Calldata != test.wantCalldata {
+				t.Errorf("calldata %s, want %s", encoded.Calldata, test.wantCalldata)
+			}
+			if len(encoded.Topics) != test.wantTopics {
+				t.Errorf("%d topics, want %d", len(encoded.Topics), test.wantTopics)
+			}
+
+			data := encoded.Data
+			if encoded.Calldata != "" {
+				data = encoded.Calldata
+			}
+			decoded, err := sig.DecodeHex(data, encoded.Topics)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]interface{}, len(decoded.Args))
+			for i, arg := range decoded.Args {
+				got[i] = arg.Value
+				if s, ok := arg.Value.(string); ok {
+					got[i] = strings.ToLower(s)
+				}
+			}
+			if !reflect.DeepEqual(got, test.wantArgValues) {
+				t.Errorf("decoded %#v, want %#v", got, test.wantArgValues)
+			}
+		})
+	}
+}
+
+func TestSignatureEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		values    []interface{}
+	}{
+		{name: "too few values", signature: "f(uint256 a, uint256 b)", values: []interface{}{big.NewInt(1)}},
+		{name: "negative uint", signature: "f(uint256 a)", values: []interface{}{big.NewInt(-1)}},
+		{name: "uint8 overflow", signature: "f(uint8 a)", values: []interface{}{big.NewInt(256)}},
+		{name: "fractional int", signature: "f(int256 a)", values: []interface{}{decimal.RequireFromString("1.5")}},
+		{name: "bad address", signature: "f(address a)", values: []interface{}{"0x1234"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := ParseSignature(test.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := sig.Encode(test.values); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestSignatureDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		data      string
+		topics    []string
+	}{
+		{name: "not hex", signature: "uint256 a", data: "0xzz"},
+		{name: "short data", signature: "uint256 a", data: "0x01"},
+		{name: "error without its selector", signature: "error E(uint256 a)", data: "0x" + strings.Repeat("00", 36)},
+		{name: "function without selector or outputs", signature: "f(uint256 a)", data: "0x" + strings.Repeat("00", 32)},
+		{name: "short topic", signature: "event E(uint256 indexed a)", topics: []string{"0x01"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sig, err := ParseSignature(test.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := sig.DecodeHex(test.data, test.topics); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+// Package contracts is a registry of contract ABIs, so that specs can refer
+// to a contract's methods and events as Contract.method rather than pasting
+// their signatures into every ethabiencode, ethabidecode and ethabidecodelog
+// task, and so that calldata, ethcall results and revert data can be decoded
+// for display.
+//
+// A contract's ABI is either a JSON ABI, bare or in a Truffle or Hardhat
+// artifact, or human-readable signatures such as
+// "function balanceOf(address owner) view returns (uint256)", as a JSON array
+// of strings or a text file with one signature per line.
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Source is a contract's ABI as uploaded, in any of the formats of the
+// package doc.
+type Source struct {
+	Name string          `json:"name" yaml:"name"`
+	ABI  json.RawMessage `json:"abi" yaml:"abi"`
+}
+
+// Contract is a named, parsed ABI.
+type Contract struct {
+	Name string
+	ABI  abi.ABI
+}
+
+// Registry holds contracts by name. A nil registry holds none.
+type Registry struct {
+	contracts map[string]*Contract
+}
+
+func NewRegistry() *Registry {
+	return &Registry{contracts: map[string]*Contract{}}
+}
+
+// Load parses the sources into a registry.
+func Load(sources []Source) (*Registry, error) {
+	r := NewRegistry()
+	for _, source := range sources {
+		if err := r.Add(source.Name, source.ABI); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// LoadPath loads the .json and .abi files at the path, which is either a
+// file or a directory of them. Each contract is named after its file, e.g.
+// Aggregator.json holds Aggregator.
+func LoadPath(path string) (*Registry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".json", ".abi":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+	}
+
+	r := NewRegistry()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if err := r.Add(name, data); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return r, nil
+}
+
+// Add parses the ABI and registers it under the name, replacing any contract
+// of that name.
+func (r *Registry) Add(name string, data []byte) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("%q is not a valid contract name", name)
+	}
+
+	parsed, err := parseABI(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	r.contracts[name] = &Contract{Name: name, ABI: parsed}
+	return nil
+}
+
+// Contract returns the named contract, or nil.
+func (r *Registry) Contract(name string) *Contract {
+	if r == nil {
+		return nil
+	}
+	return r.contracts[name]
+}
+
+// Contracts returns every contract, by name.
+func (r *Registry) Contracts() []*Contract {
+	if r == nil {
+		return nil
+	}
+	contracts := make([]*Contract, 0, len(r.contracts))
+	for _, c := range r.contracts {
+		contracts = append(contracts, c)
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].Name < contracts[j].Name
+	})
+	return contracts
+}
+
+// parseABI parses a JSON ABI, an artifact with one in its "abi" field, or
+// human-readable signatures.
+func parseABI(data []byte) (abi.ABI, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if err := json.Unmarshal(trimmed, &artifact); err != nil {
+			return abi.ABI{}, err
+		}
+		if len(artifact.ABI) == 0 {
+			return abi.ABI{}, fmt.Errorf("the artifact has no abi field")
+		}
+		return parseABI(artifact.ABI)
+
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var entries []json.RawMessage
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return abi.ABI{}, err
+		}
+		signatures := []string{}
+		for _, entry := range entries {
+			var signature string
+			if json.Unmarshal(entry, &signature) == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+		if len(signatures) == 0 {
+			return abi.JSON(bytes.NewReader(trimmed))
+		}
+		if len(signatures) != len(entries) {
+			return abi.ABI{}, fmt.Errorf("the ABI mixes JSON entries and signatures")
+		}
+		return parseSignatures(signatures)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "//") {
+			lines = append(lines, line)
+		}
+	}
+	return parseSignatures(lines)
+}
+
+// parseSignatures builds an ABI from human-readable signatures.
+func parseSignatures(signatures []string) (abi.ABI, error) {
+	fragments := []fragment{}
+	for _, signature := range signatures {
+		f, err := parseFragment(signature)
+		if err != nil {
+			return abi.ABI{}, err
+		}
+		if f != nil {
+			fragments = append(fragments, *f)
+		}
+	}
+
+	data, err := json.Marshal(fragments)
+	if err != nil {
+		return abi.ABI{}, err
+	}
+	return abi.JSON(bytes.NewReader(data))
+}
@@ -0,0 +1,181 @@
+package contracts
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Decoded is calldata, a method's return data, or a custom error decoded
+// against the registry, for display.
+type Decoded struct {
+	Contract string `json:"contract,omitempty"`
+	// Kind is function, error, or event or arguments when decoded against a
+	// Signature
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	Selector  string `json:"selector"`
+	Args      []Arg  `json:"args"`
+}
+
+// Arg is a decoded argument or return value. Integers are decimal strings
+// and addresses and bytes are hex, so that the value survives JSON intact.
+type Arg struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// DecodeCalldata decodes calldata, as given to ethcall and ethtx, by the
+// method its selector belongs to.
+func (r *Registry) DecodeCalldata(data []byte) (*Decoded, error) {
+	c, method, err := r.methodBySelector(data)
+	if err != nil {
+		return nil, err
+	}
+	args, err := decodeArgs(method.Inputs, data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s: %w", c.Name, method.Sig, err)
+	}
+	return &Decoded{
+		Contract:  c.Name,
+		Kind:      "function",
+		Name:      method.RawName,
+		Signature: method.Sig,
+		Selector:  hexutil.Encode(method.ID),
+		Args:      args,
+	}, nil
+}
+
+// DecodeResult decodes what an ethcall with the calldata returned, by the
+// return values of the method the calldata calls.
+func (r *Registry) DecodeResult(calldata, result []byte) (*Decoded, error) {
+	c, method, err := r.methodBySelector(calldata)
+	if err != nil {
+		return nil, err
+	}
+	args, err := decodeArgs(method.Outputs, result)
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s: %w", c.Name, method.Sig, err)
+	}
+	return &Decoded{
+		Contract:  c.Name,
+		Kind:      "function",
+		Name:      method.RawName,
+		Signature: method.Sig,
+		Selector:  hexutil.Encode(method.ID),
+		Args:      args,
+	}, nil
+}
+
+// DecodeError decodes revert data by the custom error its selector belongs
+// to.
+func (r *Registry) DecodeError(data []byte) (*Decoded, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%s is too short for a selector", hexutil.Encode(data))
+	}
+	for _, c := range r.Contracts() {
+		for _, name := range sortedNames(c.ABI.Errors) {
+			e := c.ABI.Errors[name]
+			if !bytes.Equal(e.ID[:4], data[:4]) {
+				continue
+			}
+			args, err := decodeArgs(e.Inputs, data[4:])
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", c.Name, e.Sig, err)
+			}
+			return &Decoded{
+				Contract:  c.Name,
+				Kind:      "error",
+				Name:      e.Name,
+				Signature: e.Sig,
+				Selector:  hexutil.Encode(e.ID[:4]),
+				Args:      args,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("no error in the ABI registry has selector %s", hexutil.Encode(data[:4]))
+}
+
+// methodBySelector finds the method the calldata calls. Contracts are
+// searched by name, so a selector shared by several contracts, such as an
+// ERC20 method, is decoded by the first.
+func (r *Registry) methodBySelector(data []byte) (*Contract, abi.Method, error) {
+	if len(data) < 4 {
+		return nil, abi.Method{}, fmt.Errorf("%s is too short for a selector", hexutil.Encode(data))
+	}
+	for _, c := range r.Contracts() {
+		for _, name := range sortedNames(c.ABI.Methods) {
+			if method := c.ABI.Methods[name]; bytes.Equal(method.ID, data[:4]) {
+				return c, method, nil
+			}
+		}
+	}
+	return nil, abi.Method{}, fmt.Errorf("no method in the ABI registry has selector %s", hexutil.Encode(data[:4]))
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func decodeArgs(args abi.Arguments, data []byte) ([]Arg, error) {
+	values, err := args.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]Arg, 0, len(args))
+	for i, arg := range args {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		decoded = append(decoded, Arg{
+			Name:  name,
+			Type:  arg.Type.String(),
+			Value: display(reflect.ValueOf(values[i]), arg.Type),
+		})
+	}
+	return decoded, nil
+}
+
+// display converts a value unpacked by go-ethereum into one that reads well
+// as JSON.
+func display(v reflect.Value, t abi.Type) interface{} {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		return fmt.Sprint(v.Interface())
+	case abi.AddressTy:
+		return v.Interface().(common.Address).Hex()
+	case abi.BytesTy:
+		return hexutil.Encode(v.Bytes())
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return hexutil.Encode(b)
+	case abi.SliceTy, abi.ArrayTy:
+		elems := make([]interface{}, v.Len())
+		for i := range elems {
+			elems[i] = display(v.Index(i), *t.Elem)
+		}
+		return elems
+	case abi.TupleTy:
+		fields := make(map[string]interface{}, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			fields[t.TupleRawNames[i]] = display(v.Field(i), *elem)
+		}
+		return fields
+	}
+	return v.Interface()
+}
@@ -0,0 +1,277 @@
+package contracts
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// reference is an abi attribute referring to a registered contract, e.g.
+// Aggregator.latestRoundData. Overloads are told apart by their argument
+// types, e.g. Token.transfer(address,uint256).
+var reference = regexp.MustCompile(`^\s*([A-Za-z_$][A-Za-z0-9_$]*)\.([A-Za-z_$][A-Za-z0-9_$]*)(\([^()]*\))?\s*$`)
+
+// IsReference reports whether the abi attribute refers to a registered
+// contract rather than spelling out the signature.
+func IsReference(attr string) bool {
+	return reference.MatchString(attr)
+}
+
+// Resolve turns a Contract.member reference into the abi attribute of a task
+// of the given type: the method and its arguments for ethabiencode, the
+// method's return values for ethabidecode and the event for
+// ethabidecodelog.
+func (r *Registry) Resolve(ref, taskType string) (string, error) {
+	m := reference.FindStringSubmatch(ref)
+	if m == nil {
+		return "", fmt.Errorf("%q is not a Contract.member reference", ref)
+	}
+	c := r.Contract(m[1])
+	if c == nil {
+		return "", fmt.Errorf("%s isn't in the ABI registry", m[1])
+	}
+
+	switch taskType {
+	case TaskETHABIEncode, TaskETHABIDecode:
+		method, err := c.method(m[2], m[3])
+		if err != nil {
+			return "", err
+		}
+		if taskType == TaskETHABIDecode {
+			if len(method.Outputs) == 0 {
+				return "", fmt.Errorf("%s.%s returns nothing to decode", c.Name, method.RawName)
+			}
+			return formatArgs(method.Outputs, false), nil
+		}
+		return method.RawName + "(" + formatArgs(method.Inputs, false) + ")", nil
+	case TaskETHABIDecodeLog:
+		event, err := c.event(m[2], m[3])
+		if err != nil {
+			return "", err
+		}
+		return event.RawName + "(" + formatArgs(event.Inputs, true) + ")", nil
+	}
+	return "", fmt.Errorf("%s has no abi attribute", taskType)
+}
+
+func (c *Contract) method(name, types string) (abi.Method, error) {
+	matches := []abi.Method{}
+	for _, method := range c.ABI.Methods {
+		if method.RawName == name && (types == "" || method.Sig == name+strings.ReplaceAll(types, " ", "")) {
+			matches = append(matches, method)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return abi.Method{}, fmt.Errorf("%s has no method %s%s", c.Name, name, types)
+	case 1:
+		return matches[0], nil
+	}
+	return abi.Method{}, fmt.Errorf("%s.%s is overloaded, pick one by its argument types, e.g. %s.%s", c.Name, name, c.Name, matches[0].Sig)
+}
+
+func (c *Contract) event(name, types string) (abi.Event, error) {
+	matches := []abi.Event{}
+	for _, event := range c.ABI.Events {
+		if event.RawName == name && (types == "" || event.Sig == name+strings.ReplaceAll(types, " ", "")) {
+			matches = append(matches, event)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return abi.Event{}, fmt.Errorf("%s has no event %s%s", c.Name, name, types)
+	case 1:
+		return matches[0], nil
+	}
+	return abi.Event{}, fmt.Errorf("%s.%s is overloaded, pick one by its argument types, e.g. %s.%s", c.Name, name, c.Name, matches[0].Sig)
+}
+
+// abiAttribute returns the task's abi attribute and its type, if it has one.
+func abiAttribute(task pipeline.Task) (*string, string) {
+	switch t := task.(type) {
+	case *pipeline.ETHABIEncodeTask:
+		return &t.ABI, TaskETHABIEncode
+	case *pipeline.ETHABIDecodeTask:
+		return &t.ABI, TaskETHABIDecode
+	case *pipeline.ETHABIDecodeLogTask:
+		return &t.ABI, TaskETHABIDecodeLog
+	}
+	return nil, ""
+}
+
+// ResolvePipeline replaces the references in the pipeline's abi attributes
+// with the signatures they refer to. References that can't be resolved are
+// left as they are and returned as errors, by task.
+func (r *Registry) ResolvePipeline(p *pipeline.Pipeline) []error {
+	errs := []error{}
+	for _, task := range p.Tasks {
+		attr, taskType := abiAttribute(task)
+		if attr == nil || !IsReference(*attr) {
+			continue
+		}
+		resolved, err := r.Resolve(*attr, taskType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", task.DotID(), err))
+			continue
+		}
+		*attr = resolved
+	}
+	return errs
+}
+
+// ResolveOptions resolves a reference in the abi option of a single task, as
+// sent by the frontend. The options are copied rather than changed.
+func (r *Registry) ResolveOptions(taskType string, options map[string]interface{}) (map[string]interface{}, error) {
+	attr, ok := options["abi"].(string)
+	if !ok || !IsReference(attr) {
+		return options, nil
+	}
+
+	resolved, err := r.Resolve(attr, taskType)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		copied[k] = v
+	}
+	copied["abi"] = resolved
+	return copied, nil
+}
+
+// Expand rewrites the references in the abi attributes of the pipeline's
+// DOT source with the signatures they refer to, so that the spec can be
+// given to a node, which has no registry. The pipeline is the one parsed
+// from the source, before its references are resolved.
+func (r *Registry) Expand(source string, p *pipeline.Pipeline) (string, []error) {
+	errs := []error{}
+	for _, task := range p.Tasks {
+		attr, taskType := abiAttribute(task)
+		if attr == nil || !IsReference(*attr) {
+			continue
+		}
+		resolved, err := r.Resolve(*attr, taskType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", task.DotID(), err))
+			continue
+		}
+
+		start, end, ok := attributeList(source, task.DotID())
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: can't find the task's attributes", task.DotID()))
+			continue
+		}
+		abiAttr := regexp.MustCompile(`(\babi\s*=\s*)"` + regexp.QuoteMeta(*attr) + `"`)
+		expanded := abiAttr.ReplaceAllLiteralString(source[start:end], "abi=\""+resolved+"\"")
+		source = source[:start] + expanded + source[end:]
+	}
+	return source, errs
+}
+
+// attributeList finds the bracketed attributes of the task in the DOT
+// source, skipping brackets within quoted values.
+func attributeList(source, dotID string) (int, int, bool) {
+	declaration := regexp.MustCompile(`(^|[^\w."])"?` + regexp.QuoteMeta(dotID) + `"?\s*\[`)
+	loc := declaration.FindStringIndex(source)
+	if loc == nil {
+		return 0, 0, false
+	}
+
+	start := loc[1] - 1
+	quoted := false
+	for i := start + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ']':
+			if !quoted {
+				return start, i + 1, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Member is a method, event or error of a contract, with the reference to
+// it and the abi attribute each task takes for it.
+type Member struct {
+	// Kind is function, event or error
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	// Selector is the 4 byte selector of a method or error, or the topic of
+	// an event
+	Selector  string `json:"selector"`
+	Reference string `json:"reference,omitempty"`
+	// Attributes are the abi attributes the member resolves to, by task type
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Members lists the contract's methods, events and errors, each by name.
+func (c *Contract) Members() []Member {
+	members := []Member{}
+
+	// Overloads are referred to by their argument types
+	methods, events := map[string]int{}, map[string]int{}
+	for _, m := range c.ABI.Methods {
+		methods[m.RawName]++
+	}
+	for _, e := range c.ABI.Events {
+		events[e.RawName]++
+	}
+
+	for _, name := range sortedNames(c.ABI.Methods) {
+		m := c.ABI.Methods[name]
+		ref := c.Name + "." + m.RawName
+		if methods[m.RawName] > 1 {
+			ref = c.Name + "." + m.Sig
+		}
+		members = append(members, Member{
+			Kind:      "function",
+			Name:      m.RawName,
+			Signature: m.Sig,
+			Selector:  hexutil.Encode(m.ID),
+			Reference: ref,
+			Attributes: map[string]string{
+				TaskETHABIEncode: m.RawName + "(" + formatArgs(m.Inputs, false) + ")",
+			},
+		})
+		if len(m.Outputs) > 0 {
+			members[len(members)-1].Attributes[TaskETHABIDecode] = formatArgs(m.Outputs, false)
+		}
+	}
+	for _, name := range sortedNames(c.ABI.Events) {
+		e := c.ABI.Events[name]
+		ref := c.Name + "." + e.RawName
+		if events[e.RawName] > 1 {
+			ref = c.Name + "." + e.Sig
+		}
+		members = append(members, Member{
+			Kind:      "event",
+			Name:      e.RawName,
+			Signature: e.Sig,
+			Selector:  e.ID.Hex(),
+			Reference: ref,
+			Attributes: map[string]string{
+				TaskETHABIDecodeLog: e.RawName + "(" + formatArgs(e.Inputs, true) + ")",
+			},
+		})
+	}
+	for _, name := range sortedNames(c.ABI.Errors) {
+		e := c.ABI.Errors[name]
+		members = append(members, Member{
+			Kind:      "error",
+			Name:      e.Name,
+			Signature: e.Sig,
+			Selector:  hexutil.Encode(e.ID[:4]),
+		})
+	}
+	return members
+}
@@ -0,0 +1,219 @@
+package contracts
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+const tokenABI = `[
+	{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"ok","type":"bool"}],"stateMutability":"nonpayable"},
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"}],"outputs":[],"stateMutability":"nonpayable"},
+	{"type":"function","name":"burn","inputs":[{"name":"amount","type":"uint256"}],"outputs":[],"stateMutability":"nonpayable"},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}],"anonymous":false}
+]`
+
+func tokenRegistry(t *testing.T) *Registry {
+	t.Helper()
+	registry, err := Load([]Source{{Name: "Token", ABI: json.RawMessage(tokenABI)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func TestIsReference(t *testing.T) {
+	tests := []struct {
+		attr string
+		want bool
+	}{
+		{attr: "Token.balanceOf", want: true},
+		{attr: " Token.transfer(address,uint256) ", want: true},
+		{attr: "Token.transfer()", want: true},
+		{attr: "balanceOf(address owner)"},
+		{attr: "uint256 balance"},
+		{attr: "$(abi)"},
+		{attr: "Token.balanceOf.x"},
+		{attr: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.attr, func(t *testing.T) {
+			if got := IsReference(test.attr); got != test.want {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	registry := tokenRegistry(t)
+
+	tests := []struct {
+		name     string
+		ref      string
+		taskType string
+		want     string
+		wantErr  bool
+	}{
+		{name: "encode", ref: "Token.balanceOf", taskType: TaskETHABIEncode, want: "balanceOf(address owner)"},
+		{name: "decode", ref: "Token.balanceOf", taskType: TaskETHABIDecode, want: "uint256 arg0"},
+		{name: "decode log", ref: "Token.Transfer", taskType: TaskETHABIDecodeLog, want: "Transfer(address indexed from, address indexed to, uint256 value)"},
+		{name: "overload by types", ref: "Token.transfer(address, uint256)", taskType: TaskETHABIEncode, want: "transfer(address to, uint256 amount)"},
+		{name: "other overload", ref: "Token.transfer(address)", taskType: TaskETHABIEncode, want: "transfer(address to)"},
+		{name: "ambiguous overload", ref: "Token.transfer", taskType: TaskETHABIEncode, wantErr: true},
+		{name: "nothing to decode", ref: "Token.burn", taskType: TaskETHABIDecode, wantErr: true},
+		{name: "unknown method", ref: "Token.mint", taskType: TaskETHABIEncode, wantErr: true},
+		{name: "unknown event", ref: "Token.Approval", taskType: TaskETHABIDecodeLog, wantErr: true},
+		{name: "unknown contract", ref: "Vault.balanceOf", taskType: TaskETHABIEncode, wantErr: true},
+		{name: "not a reference", ref: "balanceOf(address owner)", taskType: TaskETHABIEncode, wantErr: true},
+		{name: "task without an abi", ref: "Token.balanceOf", taskType: "ethcall", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := registry.Resolve(test.ref, test.taskType)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("%q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	registry := tokenRegistry(t)
+
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:    "reference",
+			options: map[string]interface{}{"abi": "Token.balanceOf", "data": "{}"},
+			want:    map[string]interface{}{"abi": "balanceOf(address owner)", "data": "{}"},
+		},
+		{
+			name:    "signature",
+			options: map[string]interface{}{"abi": "balanceOf(address owner)"},
+			want:    map[string]interface{}{"abi": "balanceOf(address owner)"},
+		},
+		{
+			name:    "no abi",
+			options: map[string]interface{}{"data": "{}"},
+			want:    map[string]interface{}{"data": "{}"},
+		},
+		{
+			name:    "unresolvable",
+			options: map[string]interface{}{"abi": "Token.mint"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			abi := test.options["abi"]
+			got, err := registry.ResolveOptions(TaskETHABIEncode, test.options)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+			if test.options["abi"] != abi {
+				t.Errorf("changed the options' abi to %v", test.options["abi"])
+			}
+		})
+	}
+}
+
+func TestResolvePipeline(t *testing.T) {
+	registry := tokenRegistry(t)
+	base := func(id string) pipeline.BaseTask { return pipeline.NewBaseTask(0, id, nil, nil, 0) }
+
+	tests := []struct {
+		name     string
+		task     pipeline.Task
+		wantABI  string
+		wantErrs int
+	}{
+		{name: "encode", task: &pipeline.ETHABIEncodeTask{BaseTask: base("encode"), ABI: "Token.balanceOf"}, wantABI: "balanceOf(address owner)"},
+		{name: "decode", task: &pipeline.ETHABIDecodeTask{BaseTask: base("decode"), ABI: "Token.balanceOf"}, wantABI: "uint256 arg0"},
+		{name: "decode log", task: &pipeline.ETHABIDecodeLogTask{BaseTask: base("log"), ABI: "Token.Transfer"}, wantABI: "Transfer(address indexed from, address indexed to, uint256 value)"},
+		{name: "signature", task: &pipeline.ETHABIEncodeTask{BaseTask: base("encode"), ABI: "burn(uint256 amount)"}, wantABI: "burn(uint256 amount)"},
+		{name: "unresolvable", task: &pipeline.ETHABIEncodeTask{BaseTask: base("encode"), ABI: "Token.mint"}, wantABI: "Token.mint", wantErrs: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := registry.ResolvePipeline(&pipeline.Pipeline{Tasks: []pipeline.Task{test.task}})
+			if len(errs) != test.wantErrs {
+				t.Errorf("errors %v, want %d", errs, test.wantErrs)
+			}
+			if attr, _ := abiAttribute(test.task); *attr != test.wantABI {
+				t.Errorf("abi %q, want %q", *attr, test.wantABI)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	registry := tokenRegistry(t)
+	base := func(id string) pipeline.BaseTask { return pipeline.NewBaseTask(0, id, nil, nil, 0) }
+
+	tests := []struct {
+		name     string
+		source   string
+		tasks    []pipeline.Task
+		want     string
+		wantErrs int
+	}{
+		{
+			name:   "reference",
+			source: `encode [type=ethabiencode abi="Token.balanceOf" data="{\"owner\": \"$(owner)\"}"]`,
+			tasks:  []pipeline.Task{&pipeline.ETHABIEncodeTask{BaseTask: base("encode"), ABI: "Token.balanceOf"}},
+			want:   `encode [type=ethabiencode abi="balanceOf(address owner)" data="{\"owner\": \"$(owner)\"}"]`,
+		},
+		{
+			name: "only the task's own attribute",
+			source: `encode2 [type=ethabiencode abi="Token.balanceOf"]
+encode [type=ethabiencode abi="Token.balanceOf" note="]"]`,
+			tasks: []pipeline.Task{&pipeline.ETHABIEncodeTask{BaseTask: base("encode"), ABI: "Token.balanceOf"}},
+			want: `encode2 [type=ethabiencode abi="Token.balanceOf"]
+encode [type=ethabiencode abi="balanceOf(address owner)" note="]"]`,
+		},
+		{
+			name:     "unresolvable",
+			source:   `encode [type=ethabiencode abi="Token.mint"]`,
+			tasks:    []pipeline.Task{&pipeline.ETHABIEncodeTask{BaseTask: base("encode"), ABI: "Token.mint"}},
+			want:     `encode [type=ethabiencode abi="Token.mint"]`,
+			wantErrs: 1,
+		},
+		{
+			name:     "task missing from the source",
+			source:   `other [type=any]`,
+			tasks:    []pipeline.Task{&pipeline.ETHABIEncodeTask{BaseTask: base("encode"), ABI: "Token.balanceOf"}},
+			want:     `other [type=any]`,
+			wantErrs: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, errs := registry.Expand(test.source, &pipeline.Pipeline{Tasks: test.tasks})
+			if len(errs) != test.wantErrs {
+				t.Errorf("errors %v, want %d", errs, test.wantErrs)
+			}
+			if got != test.want {
+				t.Errorf("%s\nwant %s", got, test.want)
+			}
+		})
+	}
+}
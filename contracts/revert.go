@@ -0,0 +1,94 @@
+package contracts
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	// errorSelector is the selector of Error(string), which require and
+	// revert with a message revert with
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	// panicSelector is the selector of Panic(uint256), which failed asserts,
+	// overflows and the like revert with
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// panicReasons are the codes Solidity panics with.
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assert failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "conversion to an invalid enum value",
+	0x22: "incorrectly encoded storage byte array",
+	0x31: "pop on an empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to a zero-initialized internal function",
+}
+
+// Revert is why a call reverted.
+type Revert struct {
+	// Kind is error for Error(string), panic for Panic(uint256), custom for
+	// a custom error in the ABI registry, or unknown
+	Kind string `json:"kind"`
+	// Reason is the message of Error(string)
+	Reason string `json:"reason,omitempty"`
+	// PanicCode is the code of Panic(uint256), e.g. 0x11, and PanicReason
+	// what it means
+	PanicCode   string `json:"panicCode,omitempty"`
+	PanicReason string `json:"panicReason,omitempty"`
+	// Error is the custom error with its arguments
+	Error *Decoded `json:"error,omitempty"`
+	// Data is the revert data, if any
+	Data string `json:"data,omitempty"`
+}
+
+// DecodeRevert decodes revert data as Error(string), Panic(uint256) or a
+// custom error of the registry. Data that is none of them, or no data at
+// all, is an unknown revert.
+func (r *Registry) DecodeRevert(data []byte) *Revert {
+	revert := &Revert{Kind: "unknown"}
+	if len(data) > 0 {
+		revert.Data = hexutil.Encode(data)
+	}
+	if len(data) < 4 {
+		return revert
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if values, err := stringArgs.UnpackValues(data[4:]); err == nil {
+			revert.Kind, revert.Reason = "error", values[0].(string)
+		}
+	case bytes.Equal(data[:4], panicSelector):
+		if values, err := uintArgs.UnpackValues(data[4:]); err == nil {
+			code := values[0].(*big.Int)
+			revert.Kind, revert.PanicCode = "panic", fmt.Sprintf("0x%02x", code)
+			revert.PanicReason = "unknown panic code"
+			if reason, ok := panicReasons[code.Uint64()]; ok && code.IsUint64() {
+				revert.PanicReason = reason
+			}
+		}
+	default:
+		if decoded, err := r.DecodeError(data); err == nil {
+			revert.Kind, revert.Error = "custom", decoded
+		}
+	}
+	return revert
+}
+
+var stringArgs, uintArgs = singleArgument("string"), singleArgument("uint256")
+
+func singleArgument(t string) abi.Arguments {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return abi.Arguments{{Type: typ}}
+}
@@ -0,0 +1,115 @@
+package contracts
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const vaultABI = `[{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}]`
+
+func TestDecodeRevert(t *testing.T) {
+	registry, err := Load([]Source{{Name: "Vault", ABI: json.RawMessage(vaultABI)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(signature string, values ...interface{}) []byte {
+		sig, err := ParseSignature(signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := sig.Encode(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hexutil.MustDecode(encoded.Calldata)
+	}
+	custom := encode("error InsufficientBalance(uint256 available, uint256 required)", big.NewInt(1), big.NewInt(2))
+
+	tests := []struct {
+		name     string
+		registry *Registry
+		data     []byte
+		want     Revert
+		// wantArgs are the custom error's argument values
+		wantArgs []interface{}
+	}{
+		{
+			name: "no data",
+			want: Revert{Kind: "unknown"},
+		},
+		{
+			name: "shorter than a selector",
+			data: []byte{0x08, 0xc3},
+			want: Revert{Kind: "unknown", Data: "0x08c3"},
+		},
+		{
+			name: "Error(string)",
+			data: encode("error Error(string reason)", "not enough LINK"),
+			want: Revert{Kind: "error", Reason: "not enough LINK"},
+		},
+		{
+			name: "Panic(uint256)",
+			data: encode("error Panic(uint256 code)", big.NewInt(0x11)),
+			want: Revert{Kind: "panic", PanicCode: "0x11", PanicReason: "arithmetic overflow or underflow"},
+		},
+		{
+			name: "Panic with an unknown code",
+			data: encode("error Panic(uint256 code)", big.NewInt(0x99)),
+			want: Revert{Kind: "panic", PanicCode: "0x99", PanicReason: "unknown panic code"},
+		},
+		{
+			name: "Error(string) with truncated data",
+			data: encode("error Error(string reason)", "not enough LINK")[:40],
+			want: Revert{Kind: "unknown"},
+		},
+		{
+			name:     "custom error",
+			registry: registry,
+			data:     custom,
+			want:     Revert{Kind: "custom"},
+			wantArgs: []interface{}{"1", "2"},
+		},
+		{
+			name: "custom error without the registry",
+			data: custom,
+			want: Revert{Kind: "unknown"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.registry.DecodeRevert(test.data)
+
+			if len(test.data) > 0 {
+				test.want.Data = hexutil.Encode(test.data)
+			}
+			decoded := got.Error
+			got.Error = nil
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("%+v, want %+v", *got, test.want)
+			}
+
+			if test.wantArgs == nil {
+				if decoded != nil {
+					t.Errorf("decoded a custom error %+v", decoded)
+				}
+				return
+			}
+			if decoded == nil || decoded.Name != "InsufficientBalance" {
+				t.Fatalf("custom error %+v, want InsufficientBalance", decoded)
+			}
+			args := make([]interface{}, len(decoded.Args))
+			for i, arg := range decoded.Args {
+				args[i] = arg.Value
+			}
+			if !reflect.DeepEqual(args, test.wantArgs) {
+				t.Errorf("args %v, want %v", args, test.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package contracts
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// The task types with an abi attribute, and the form it takes for each.
+const (
+	// TaskETHABIEncode's abi is a method, e.g. "transfer(address to, uint256 amount)"
+	TaskETHABIEncode = "ethabiencode"
+	// TaskETHABIDecode's abi is the arguments, e.g. "uint256 answer, uint256 updatedAt"
+	TaskETHABIDecode = "ethabidecode"
+	// TaskETHABIDecodeLog's abi is an event, e.g. "Transfer(address indexed from, address indexed to, uint256 value)"
+	TaskETHABIDecodeLog = "ethabidecodelog"
+)
+
+// fragment is an entry of a JSON ABI.
+type fragment struct {
+	Type            string  `json:"type"`
+	Name            string  `json:"name,omitempty"`
+	Inputs          []param `json:"inputs"`
+	Outputs         []param `json:"outputs,omitempty"`
+	StateMutability string  `json:"stateMutability,omitempty"`
+	Anonymous       bool    `json:"anonymous,omitempty"`
+}
+
+// param is an argument of a JSON ABI entry.
+type param struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Indexed    bool    `json:"indexed,omitempty"`
+	Components []param `json:"components,omitempty"`
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func isIdentifier(s string) bool {
+	return identifier.MatchString(s)
+}
+
+// parseFragment parses a human-readable signature such as
+// "function latestRoundData() view returns (uint80 roundId, int256 answer)",
+// "event Transfer(address indexed from, address indexed to, uint256 value)"
+// or "error Unauthorized(address caller)". A signature without a keyword is a
+// function. Fallback and receive functions have nothing to register, so they
+// return nil.
+func parseFragment(signature string) (*fragment, error) {
+	s := strings.TrimSuffix(strings.TrimSpace(signature), ";")
+
+	f := &fragment{Type: "function"}
+	for _, kind := range []string{"function", "event", "error", "constructor", "fallback", "receive"} {
+		if s == kind || strings.HasPrefix(s, kind+" ") || strings.HasPrefix(s, kind+"(") {
+			f.Type = kind
+			s = strings.TrimSpace(s[len(kind):])
+			break
+		}
+	}
+	if f.Type == "fallback" || f.Type == "receive" {
+		return nil, nil
+	}
+
+	open := strings.Index(s, "(")
+	if open < 0 {
+		return nil, fmt.Errorf("%q is not a signature", signature)
+	}
+	end, err := closingParen(s, open)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", signature, err)
+	}
+
+	f.Name = strings.TrimSpace(s[:open])
+	if f.Type == "constructor" {
+		if f.Name != "" {
+			return nil, fmt.Errorf("%q: a constructor has no name", signature)
+		}
+	} else if !isIdentifier(f.Name) {
+		return nil, fmt.Errorf("%q: %q is not a valid name", signature, f.Name)
+	}
+
+	if f.Inputs, err = parseParams(s[open+1 : end]); err != nil {
+		return nil, fmt.Errorf("%q: %w", signature, err)
+	}
+	for _, p := range f.Inputs {
+		if p.Indexed && f.Type != "event" {
+			return nil, fmt.Errorf("%q: only event arguments can be indexed", signature)
+		}
+	}
+
+	rest := strings.TrimSpace(s[end+1:])
+	if i := strings.Index(rest, "returns"); i >= 0 {
+		returns := strings.TrimSpace(rest[i+len("returns"):])
+		rest = rest[:i]
+		if f.Type != "function" || !strings.HasPrefix(returns, "(") {
+			return nil, fmt.Errorf("%q: bad returns clause", signature)
+		}
+		end, err := closingParen(returns, 0)
+		if err != nil || strings.TrimSpace(returns[end+1:]) != "" {
+			return nil, fmt.Errorf("%q: bad returns clause", signature)
+		}
+		if f.Outputs, err = parseParams(returns[1:end]); err != nil {
+			return nil, fmt.Errorf("%q: %w", signature, err)
+		}
+	}
+
+	for _, modifier := range strings.Fields(rest) {
+		switch modifier {
+		case "view", "pure", "payable", "nonpayable":
+			f.StateMutability = modifier
+		case "anonymous":
+			f.Anonymous = true
+		case "external", "public", "virtual", "override":
+		default:
+			return nil, fmt.Errorf("%q: unknown modifier %q", signature, modifier)
+		}
+	}
+	if f.Type == "function" && f.StateMutability == "" {
+		f.StateMutability = "nonpayable"
+	}
+
+	return f, nil
+}
+
+// closingParen returns the index of the parenthesis closing the one at open.
+func closingParen(s string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unbalanced parentheses")
+}
+
+// parseParams parses a comma separated list of arguments, each a type
+// followed by optional modifiers and a name, e.g. "address indexed from".
+// Tuples are written as "(uint256 a, address b)[] name" or with a tuple
+// prefix.
+func parseParams(s string) ([]param, error) {
+	params := []param{}
+	depth, start := 0, 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch s[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		part := strings.TrimSpace(s[start:i])
+		start = i + 1
+		if part == "" {
+			if i < len(s) || len(params) > 0 {
+				return nil, fmt.Errorf("empty argument")
+			}
+			continue
+		}
+
+		p, err := parseParam(part)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+func parseParam(s string) (param, error) {
+	p := param{}
+	rest := strings.TrimPrefix(s, "tuple")
+
+	if strings.HasPrefix(rest, "(") {
+		end, err := closingParen(rest, 0)
+		if err != nil {
+			return p, fmt.Errorf("%q: %w", s, err)
+		}
+		if p.Components, err = parseParams(rest[1:end]); err != nil {
+			return p, err
+		}
+		rest = rest[end+1:]
+		dims := len(rest) - len(strings.TrimLeft(rest, "[]0123456789"))
+		p.Type, rest = "tuple"+rest[:dims], rest[dims:]
+	} else {
+		fields := strings.Fields(s)
+		p.Type, rest = normalizeType(fields[0]), strings.TrimPrefix(strings.TrimSpace(s), fields[0])
+	}
+
+	for _, field := range strings.Fields(rest) {
+		switch {
+		case field == "indexed":
+			p.Indexed = true
+		case field == "memory" || field == "calldata" || field == "storage" || field == "payable":
+		case p.Name == "" && isIdentifier(field):
+			p.Name = field
+		default:
+			return p, fmt.Errorf("%q: unexpected %q", s, field)
+		}
+	}
+
+	if _, err := p.abiType(); err != nil {
+		return p, fmt.Errorf("%q: %w", s, err)
+	}
+	return p, nil
+}
+
+// normalizeType expands the aliases Solidity allows, e.g. uint[] is
+// uint256[].
+func normalizeType(t string) string {
+	base, dims := t, ""
+	if i := strings.Index(t, "["); i >= 0 {
+		base, dims = t[:i], t[i:]
+	}
+	switch base {
+	case "uint", "int":
+		base += "256"
+	case "byte":
+		base = "bytes1"
+	}
+	return base + dims
+}
+
+func (p param) abiType() (abi.Type, error) {
+	t, err := abi.NewType(p.Type, "", p.marshaling().Components)
+	if err != nil {
+		return t, err
+	}
+	// go-ethereum takes any size, the EVM doesn't
+	for elem := &t; elem != nil; elem = elem.Elem {
+		switch elem.T {
+		case abi.IntTy, abi.UintTy:
+			if elem.Size < 8 || elem.Size > 256 || elem.Size%8 != 0 {
+				return t, fmt.Errorf("%s is not a valid integer size", p.Type)
+			}
+		case abi.FixedBytesTy:
+			if elem.Size < 1 || elem.Size > 32 {
+				return t, fmt.Errorf("%s is not a valid bytes size", p.Type)
+			}
+		}
+	}
+	return t, nil
+}
+
+func (p param) marshaling() abi.ArgumentMarshaling {
+	m := abi.ArgumentMarshaling{Name: p.Name, Type: p.Type, Indexed: p.Indexed}
+	for _, c := range p.Components {
+		m.Components = append(m.Components, c.marshaling())
+	}
+	return m
+}
+
+// formatArgs writes the arguments in the form abi attributes take, naming
+// unnamed ones arg0, arg1 and so on, as the tasks need a name for each.
+func formatArgs(args abi.Arguments, withIndexed bool) string {
+	parts := make([]string, 0, len(args))
+	for i, arg := range args {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		part := arg.Type.String()
+		if withIndexed && arg.Indexed {
+			part += " indexed"
+		}
+		parts = append(parts, part+" "+name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ValidateAttribute checks the abi attribute of a task of the given type, in
+// the form the task takes it. Attributes that refer to vars can't be checked
+// until the pipeline runs and are left alone.
+func ValidateAttribute(taskType, attr string) error {
+	attr = strings.TrimSpace(attr)
+	if strings.Contains(attr, "$(") {
+		return nil
+	}
+	if attr == "" {
+		return fmt.Errorf("the abi is empty")
+	}
+
+	var params []param
+	var err error
+	switch taskType {
+	case TaskETHABIEncode, TaskETHABIDecodeLog:
+		var f *fragment
+		kind := "function "
+		if taskType == TaskETHABIDecodeLog {
+			kind = "event "
+		}
+		if f, err = parseFragment(kind + attr); err == nil {
+			if !strings.HasSuffix(attr, ")") {
+				err = fmt.Errorf("%q should only be the name and arguments", attr)
+			}
+			params = f.Inputs
+		}
+	case TaskETHABIDecode:
+		params, err = parseParams(attr)
+		for _, p := range params {
+			if err == nil && p.Indexed {
+				err = fmt.Errorf("%q: only ethabidecodelog arguments can be indexed", attr)
+			}
+		}
+	default:
+		return fmt.Errorf("%s has no abi attribute", taskType)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, p := range params {
+		if p.Name == "" {
+			return fmt.Errorf("the %s argument has no name, which the task needs", p.Type)
+		}
+	}
+	return nil
+}
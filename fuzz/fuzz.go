@@ -0,0 +1,328 @@
+// Package fuzz runs single tasks with generated options, inputs and vars to
+// find the ones that crash the API: panics, hangs, results that can't be
+// serialised and errors of an unexpected kind. Every value is generated as a
+// typed var and loaded the way the var-helper loads it, so that a finding can
+// be replayed as a test.
+package fuzz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/simulator"
+	"github.com/pickleyd/jobspecviz/testsuite"
+)
+
+// Kinds of findings
+const (
+	KindPanic = "panic"
+	// KindHang is a task that ran past the timeout
+	KindHang = "hang"
+	// KindMarshal is a result that can't be serialised, which the API
+	// handlers treat as fatal
+	KindMarshal = "marshal"
+	// KindUnexpectedError is an error that isn't one of the pipeline's own
+	// error classes, e.g. one leaking from a library
+	KindUnexpectedError = "unexpected-error"
+)
+
+// expectedErrors are the errors tasks return for bad options or inputs.
+var expectedErrors = []error{
+	pipeline.ErrWrongInputCardinality,
+	pipeline.ErrBadInput,
+	pipeline.ErrInputTaskErrored,
+	pipeline.ErrParameterEmpty,
+	pipeline.ErrIndexOutOfRange,
+	pipeline.ErrTooManyErrors,
+	pipeline.ErrKeypathNotFound,
+	pipeline.ErrKeypathTooDeep,
+	pipeline.ErrVarsRoot,
+}
+
+// NetworkTasks reach out to the network, so fuzzing them sends requests to
+// whatever URLs and nodes the generated options point at.
+var NetworkTasks = []simulator.TaskType{
+	simulator.TaskTypeHTTP,
+	simulator.TaskTypeBridge,
+	simulator.TaskTypeETHCall,
+	simulator.TaskTypeETHTx,
+}
+
+// Case is the options, inputs and vars of one run of a task.
+type Case struct {
+	Options map[string]string        `json:"options"`
+	Inputs  []simulator.Var          `json:"inputs"`
+	Vars    map[string]simulator.Var `json:"vars"`
+}
+
+// Test turns the case into a test of the task, without any assertions.
+func (c Case) Test(name string, taskType simulator.TaskType) testsuite.Test {
+	options := make(map[string]interface{}, len(c.Options))
+	for k, v := range c.Options {
+		options[k] = v
+	}
+	return testsuite.Test{
+		Name:    name,
+		Task:    taskType.String(),
+		Options: options,
+		Inputs:  c.Inputs,
+		Vars:    c.Vars,
+	}
+}
+
+// Config sets up fuzzing of a task type.
+type Config struct {
+	TaskType simulator.TaskType
+	// Options are given these values rather than generated ones, e.g. so
+	// that ethabiencode is given a valid abi
+	Options    map[string]string
+	Iterations int
+	// Seed makes the cases reproducible, with 0 picking a random one
+	Seed int64
+	// Timeout is how long a task may run before it is reported as hung
+	Timeout time.Duration
+	// Expected are substrings of errors that aren't findings, on top of the
+	// pipeline's own error classes
+	Expected []string
+	// MaxShrinks caps the runs spent shrinking each finding
+	MaxShrinks int
+}
+
+// Finding is a kind of failure, with the smallest case found that causes it.
+type Finding struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	// Hits is the number of cases that failed this way
+	Hits int  `json:"hits"`
+	Case Case `json:"case"`
+}
+
+// Report is the outcome of fuzzing a task type.
+type Report struct {
+	TaskType   simulator.TaskType `json:"taskType"`
+	Seed       int64              `json:"seed"`
+	Iterations int                `json:"iterations"`
+	// Timeout is how long a task could run before it was reported as hung
+	Timeout time.Duration `json:"timeout"`
+	// Invalid cases have vars the var-helper can't convert, e.g. NaN
+	Invalid int `json:"invalid"`
+	// Rejected cases have options the task can't be created with
+	Rejected int       `json:"rejected"`
+	Findings []Finding `json:"findings"`
+}
+
+// Reproducers turns the findings into tests of the task that fail for as
+// long as the task fails the same way. Every test also fails if the value
+// can't be serialised, which covers marshal findings. An unexpected error is
+// expected to go away; if the fix is for the task to fail with one of the
+// pipeline's errors instead, the reproducer should expect an error.
+func (r *Report) Reproducers() []testsuite.Test {
+	tests := make([]testsuite.Test, 0, len(r.Findings))
+	for i, finding := range r.Findings {
+		test := finding.Case.Test(fmt.Sprintf("%s %s %d", r.TaskType, finding.Kind, i+1), r.TaskType)
+		switch finding.Kind {
+		case KindPanic:
+			test.ExpectNoPanic = true
+		case KindHang:
+			test.ExpectNoTimeout = true
+			test.Options["timeout"] = r.Timeout.String()
+		case KindUnexpectedError:
+			test.ExpectNoError = true
+		}
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+// outcome is how a run of a case went. An empty kind means it went fine, or
+// failed in an expected way.
+type outcome struct {
+	kind     string
+	message  string
+	invalid  bool
+	rejected bool
+}
+
+// signature identifies a failure regardless of the values in its message, so
+// that cases failing the same way are counted as one finding.
+func (o outcome) signature() string {
+	return o.kind + ": " + numbers.ReplaceAllString(o.message, "N")
+}
+
+var numbers = regexp.MustCompile(`0x[0-9a-fA-F]*|-?[0-9]+(\.[0-9]+)?`)
+
+// Fuzz runs the task type with generated cases and shrinks each failure to
+// the smallest case that fails the same way.
+func Fuzz(ctx context.Context, cfg Config) (*Report, error) {
+	options, err := optionNames(cfg.TaskType)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.MaxShrinks <= 0 {
+		cfg.MaxShrinks = 200
+	}
+
+	report := &Report{
+		TaskType: cfg.TaskType,
+		Seed:     cfg.Seed,
+		Timeout:  cfg.Timeout,
+		Findings: []Finding{},
+	}
+
+	g := &generator{rng: rand.New(rand.NewSource(cfg.Seed))}
+	findings := map[string]*Finding{}
+	order := []string{}
+
+	for i := 0; i < cfg.Iterations && ctx.Err() == nil; i++ {
+		c := g.newCase(options, cfg.Options)
+		o := run(ctx, cfg, c)
+		report.Iterations++
+
+		switch {
+		case o.invalid:
+			report.Invalid++
+			continue
+		case o.rejected:
+			report.Rejected++
+		}
+		if o.kind == "" {
+			continue
+		}
+
+		sig := o.signature()
+		if finding, ok := findings[sig]; ok {
+			finding.Hits++
+			continue
+		}
+
+		shrunk, o := shrink(ctx, cfg, c, o)
+		findings[sig] = &Finding{Kind: o.kind, Message: o.message, Hits: 1, Case: shrunk}
+		order = append(order, sig)
+	}
+
+	for _, sig := range order {
+		report.Findings = append(report.Findings, *findings[sig])
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Hits > report.Findings[j].Hits
+	})
+	return report, nil
+}
+
+// optionNames lists the options of the task type, from the fields of the
+// task, leaving out the attributes every task has.
+func optionNames(taskType simulator.TaskType) ([]string, error) {
+	task, err := simulator.NewTask(taskType, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	base := map[string]bool{"Index": true, "Timeout": true, "FailEarly": true, "Retries": true, "MinBackoff": true, "MaxBackoff": true}
+	names := []string{}
+	for field := range fields {
+		if !base[field] {
+			names = append(names, strings.ToLower(field[:1])+field[1:])
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// run runs the case as api/task would, including serialising the result.
+func run(ctx context.Context, cfg Config, c Case) (o outcome) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			o = outcome{kind: KindPanic, message: fmt.Sprint(rec)}
+		}
+	}()
+
+	vars, err := simulator.LoadVars(c.Vars, nil, nil)
+	if err != nil {
+		return outcome{invalid: true}
+	}
+	inputs := make([]interface{}, 0, len(c.Inputs))
+	for _, in := range c.Inputs {
+		input, err := simulator.LoadVar(in)
+		if err != nil {
+			return outcome{invalid: true}
+		}
+		inputs = append(inputs, input)
+	}
+
+	options := make(map[string]interface{}, len(c.Options))
+	for k, v := range c.Options {
+		options[k] = v
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	result, err := simulator.RunTask(runCtx, cfg.TaskType, options, vars, inputs, simulator.ExecuteOptions{})
+	if err != nil {
+		return outcome{rejected: true}
+	}
+
+	if result.Error != nil {
+		return classify(ctx, cfg, result)
+	}
+
+	if _, err := simulator.ToBase64(result.Value); err != nil {
+		return outcome{kind: KindMarshal, message: err.Error()}
+	}
+	if result.SideEffectData != nil {
+		if _, err := simulator.ToBase64(result.SideEffectData); err != nil {
+			return outcome{kind: KindMarshal, message: "side effect data: " + err.Error()}
+		}
+	}
+	return outcome{}
+}
+
+func classify(ctx context.Context, cfg Config, result *simulator.TaskResult) outcome {
+	message := result.Error.Error()
+
+	switch {
+	case result.Panicked():
+		return outcome{kind: KindPanic, message: strings.TrimPrefix(message, simulator.ErrTaskPanicked.Error()+": ")}
+	case result.TimedOut(), result.Cancelled() && ctx.Err() == nil:
+		return outcome{kind: KindHang, message: fmt.Sprintf("ran for more than %v", cfg.Timeout)}
+	case result.Cancelled():
+		// Fuzzing was stopped
+		return outcome{}
+	}
+
+	for _, expected := range expectedErrors {
+		if errors.Is(result.Error, expected) {
+			return outcome{}
+		}
+	}
+	for _, expected := range cfg.Expected {
+		if strings.Contains(message, expected) {
+			return outcome{}
+		}
+	}
+	return outcome{kind: KindUnexpectedError, message: message}
+}
@@ -0,0 +1,56 @@
+package fuzz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+func TestReproducers(t *testing.T) {
+	tests := []struct {
+		kind           string
+		wantNoError    bool
+		wantNoPanic    bool
+		wantNoTimeout  bool
+		wantTimeoutOpt string
+	}{
+		{kind: KindPanic, wantNoPanic: true},
+		{kind: KindHang, wantNoTimeout: true, wantTimeoutOpt: "2s"},
+		{kind: KindUnexpectedError, wantNoError: true},
+		{kind: KindMarshal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.kind, func(t *testing.T) {
+			report := &Report{
+				TaskType: simulator.TaskTypeDivide,
+				Timeout:  2 * time.Second,
+				Findings: []Finding{{
+					Kind: test.kind,
+					Case: Case{
+						Options: map[string]string{"divisor": "0"},
+						Inputs:  []simulator.Var{{Value: "1", Type: "int"}},
+					},
+				}},
+			}
+
+			tests := report.Reproducers()
+			if len(tests) != 1 {
+				t.Fatalf("%d reproducers, want 1", len(tests))
+			}
+			got := tests[0]
+
+			if got.Task != "divide" || got.Options["divisor"] != "0" || len(got.Inputs) != 1 {
+				t.Errorf("reproducer %+v doesn't replay the case", got)
+			}
+			if got.ExpectNoError != test.wantNoError || got.ExpectNoPanic != test.wantNoPanic || got.ExpectNoTimeout != test.wantNoTimeout {
+				t.Errorf("expects no error %v, panic %v, timeout %v, want %v, %v, %v",
+					got.ExpectNoError, got.ExpectNoPanic, got.ExpectNoTimeout, test.wantNoError, test.wantNoPanic, test.wantNoTimeout)
+			}
+			if timeout, _ := got.Options["timeout"].(string); timeout != test.wantTimeoutOpt {
+				t.Errorf("timeout option %q, want %q", timeout, test.wantTimeoutOpt)
+			}
+		})
+	}
+}
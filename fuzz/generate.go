@@ -0,0 +1,237 @@
+package fuzz
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+// Edge cases of each kind of value. Strings that aren't valid for a type,
+// such as malformed hex, are passed as strings, as that is how they reach a
+// task from an http response.
+var (
+	edgeInts = []string{
+		"0", "1", "-1",
+		bigPow(63, -1), bigPow(63, 0), bigPow(64, -1), bigPow(64, 0),
+		bigPow(255, -1), bigPow(255, 0), bigPow(256, -1), bigPow(256, 0),
+		"-" + bigPow(255, 0), "-" + bigPow(255, 1), "-" + bigPow(256, 0),
+	}
+	edgeDecimals = []string{
+		"0", "0.1", "-0.1", "1e-18", "1e-100", "1e100", "123456789012345678901234567890.123456789012345678901234567890",
+		"-1e77", "0.000000000000000000000000000000000000000000000000000000000000000000000000000000001",
+	}
+	edgeFloats = []string{
+		"0", "-0", "1.5", "1e308", "-1e308", "5e-324", "9007199254740993", "NaN", "Inf", "-Inf",
+	}
+	edgeStrings = []string{
+		"", " ", "0", "-1", "1.5", "true", "null", "[]", "{}", `{"a":1}`, "$(x)", "a,b,c",
+		"0x", "0x0", "0x123", "0xZZ", "0xzz00", "0x" + strings.Repeat("ff", 33),
+		"not a number", "ünïcödé", "\x00", strings.Repeat("a", 10000),
+	}
+	edgeAddresses = []string{
+		"0x0000000000000000000000000000000000000000",
+		"0xffffffffffffffffffffffffffffffffffffffff",
+		"0xDeaDbeefdEAdbeefdEadbEEFdeadbeEFdEaDbeeF",
+	}
+	edgeBytes = []string{"0x", "0x00", "0xff", "0x" + strings.Repeat("00", 32), "0x" + strings.Repeat("ab", 100)}
+)
+
+// bigPow returns 2^exp plus delta as a string.
+func bigPow(exp uint, delta int64) string {
+	n := new(big.Int).Lsh(big.NewInt(1), exp)
+	return n.Add(n, big.NewInt(delta)).String()
+}
+
+type generator struct {
+	rng *rand.Rand
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.rng.Intn(len(values))]
+}
+
+// scalar generates a single typed value, mostly edge cases but sometimes a
+// random one.
+func (g *generator) scalar() simulator.Var {
+	switch g.rng.Intn(8) {
+	case 0:
+		if g.rng.Intn(3) == 0 {
+			return simulator.Var{Value: g.randomInt(), Type: "int"}
+		}
+		return simulator.Var{Value: g.pick(edgeInts), Type: "int"}
+	case 1:
+		return simulator.Var{Value: g.pick(edgeDecimals), Type: "decimal"}
+	case 2:
+		return simulator.Var{Value: g.pick(edgeFloats), Type: "float"}
+	case 3:
+		return simulator.Var{Value: g.pick([]string{"true", "false"}), Type: "bool"}
+	case 4:
+		return simulator.Var{Value: g.pick(edgeAddresses), Type: "address"}
+	case 5:
+		return simulator.Var{Value: g.pick(edgeBytes), Type: "bytes", FromType: "hex"}
+	case 6:
+		return simulator.Var{Type: "null"}
+	}
+	return stringVar(g.pick(edgeStrings))
+}
+
+// jsonVar is a var of a decoded JSON document. A null document can't be
+// kept, so it is a null var.
+func jsonVar(value interface{}) simulator.Var {
+	if value == nil {
+		return simulator.Var{Type: "null"}
+	}
+	return simulator.Var{Keep: value}
+}
+
+// stringVar is a string var. An empty string is kept as is, as the
+// var-helper only converts vars with a value.
+func stringVar(s string) simulator.Var {
+	if s == "" {
+		return simulator.Var{Keep: s}
+	}
+	return simulator.Var{Value: s, Type: "string"}
+}
+
+func (g *generator) randomInt() string {
+	digits := 1 + g.rng.Intn(80)
+	var b strings.Builder
+	if g.rng.Intn(2) == 0 {
+		b.WriteString("-")
+	}
+	b.WriteByte(byte('1' + g.rng.Intn(9)))
+	for i := 1; i < digits; i++ {
+		b.WriteByte(byte('0' + g.rng.Intn(10)))
+	}
+	return b.String()
+}
+
+// value generates a scalar, an array or a JSON document.
+func (g *generator) value() simulator.Var {
+	switch g.rng.Intn(6) {
+	case 0:
+		return g.array()
+	case 1:
+		return jsonVar(g.json(g.rng.Intn(4)))
+	case 2:
+		if g.rng.Intn(4) == 0 {
+			return simulator.Var{Keep: g.deep(50 + g.rng.Intn(500))}
+		}
+	}
+	return g.scalar()
+}
+
+// array generates an array of values of one type, or an empty one.
+func (g *generator) array() simulator.Var {
+	n := g.rng.Intn(6)
+	if n == 0 {
+		return simulator.Var{Keep: []interface{}{}}
+	}
+
+	first := g.scalar()
+	for first.Type == "null" || first.Keep != nil {
+		first = g.scalar()
+	}
+	values := []string{first.Value}
+	for i := 1; i < n; i++ {
+		v := g.scalar()
+		for v.Type != first.Type {
+			v = g.scalar()
+		}
+		values = append(values, v.Value)
+	}
+	return simulator.Var{Values: values, Type: first.Type, FromType: first.FromType}
+}
+
+// json generates a JSON document of the given depth, as decoded by
+// encoding/json.
+func (g *generator) json(depth int) interface{} {
+	if depth <= 0 {
+		switch g.rng.Intn(5) {
+		case 0:
+			return float64(g.rng.Intn(2000) - 1000)
+		case 1:
+			return g.pick(edgeStrings[:18])
+		case 2:
+			return g.rng.Intn(2) == 0
+		case 3:
+			return nil
+		}
+		return 1e300
+	}
+
+	n := g.rng.Intn(4)
+	if g.rng.Intn(2) == 0 {
+		array := []interface{}{}
+		for i := 0; i < n; i++ {
+			array = append(array, g.json(depth-1))
+		}
+		return array
+	}
+	object := map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		object[g.pick([]string{"a", "b", "data", "result", "0", ""})] = g.json(depth - 1)
+	}
+	return object
+}
+
+// deep generates JSON nested to the given depth.
+func (g *generator) deep(depth int) interface{} {
+	var value interface{} = float64(1)
+	for i := 0; i < depth; i++ {
+		if g.rng.Intn(2) == 0 {
+			value = []interface{}{value}
+		} else {
+			value = map[string]interface{}{"data": value}
+		}
+	}
+	return value
+}
+
+// option generates an option's value: a literal, nothing, or a reference to
+// a generated var.
+func (g *generator) option(vars map[string]simulator.Var) string {
+	switch g.rng.Intn(4) {
+	case 0:
+		return ""
+	case 1:
+		return g.scalar().Value
+	}
+
+	name := fmt.Sprintf("v%d", len(vars))
+	vars[name] = g.value()
+	if g.rng.Intn(4) == 0 {
+		// A path into the var, which may not exist
+		return fmt.Sprintf("$(%s.%s)", name, g.pick([]string{"data", "0", "a", "result"}))
+	}
+	return fmt.Sprintf("$(%s)", name)
+}
+
+// newCase generates a case for a task with the given options. Fixed options
+// are used as is.
+func (g *generator) newCase(options []string, fixed map[string]string) Case {
+	c := Case{
+		Options: map[string]string{},
+		Inputs:  []simulator.Var{},
+		Vars:    map[string]simulator.Var{},
+	}
+
+	for _, option := range options {
+		if value, ok := fixed[option]; ok {
+			c.Options[option] = value
+		} else if g.rng.Intn(5) > 0 {
+			c.Options[option] = g.option(c.Vars)
+		}
+	}
+	for option, value := range fixed {
+		c.Options[option] = value
+	}
+
+	for i := g.rng.Intn(4); i > 0; i-- {
+		c.Inputs = append(c.Inputs, g.value())
+	}
+	return c
+}
@@ -0,0 +1,283 @@
+package fuzz
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+// shrink looks for a smaller case that fails the same way, by repeatedly
+// trying simpler versions of the case and keeping the first that still
+// fails, until none do or MaxShrinks runs are spent. Hangs are costly to
+// reproduce, so they get a tenth of the runs.
+func shrink(ctx context.Context, cfg Config, c Case, o outcome) (Case, outcome) {
+	budget := cfg.MaxShrinks
+	if o.kind == KindHang {
+		budget /= 10
+	}
+	sig := o.signature()
+
+	for budget > 0 && ctx.Err() == nil {
+		shrunk := false
+		for _, candidate := range candidates(c, cfg.Options) {
+			if budget <= 0 || ctx.Err() != nil {
+				break
+			}
+			budget--
+
+			if co := run(ctx, cfg, candidate); co.kind != "" && co.signature() == sig {
+				c, o = candidate, co
+				shrunk = true
+				break
+			}
+		}
+		if !shrunk {
+			break
+		}
+	}
+	return c, o
+}
+
+// candidates are the simpler versions of the case, most aggressive first:
+// without an input, option or var, and then with each value simplified.
+// Fixed options are kept.
+func candidates(c Case, fixed map[string]string) []Case {
+	cases := []Case{}
+
+	for i := range c.Inputs {
+		next := c.clone()
+		next.Inputs = append(next.Inputs[:i], next.Inputs[i+1:]...)
+		cases = append(cases, next)
+	}
+	for _, k := range sortedKeys(c.Options) {
+		if _, ok := fixed[k]; ok {
+			continue
+		}
+		next := c.clone()
+		delete(next.Options, k)
+		cases = append(cases, next)
+	}
+	for _, k := range sortedVarKeys(c.Vars) {
+		next := c.clone()
+		delete(next.Vars, k)
+		cases = append(cases, next)
+	}
+
+	for i, in := range c.Inputs {
+		for _, v := range simplerVars(in) {
+			next := c.clone()
+			next.Inputs[i] = v
+			cases = append(cases, next)
+		}
+	}
+	for _, k := range sortedVarKeys(c.Vars) {
+		for _, v := range simplerVars(c.Vars[k]) {
+			next := c.clone()
+			next.Vars[k] = v
+			cases = append(cases, next)
+		}
+	}
+	for _, k := range sortedKeys(c.Options) {
+		if _, ok := fixed[k]; ok {
+			continue
+		}
+		for _, s := range simplerStrings(c.Options[k]) {
+			next := c.clone()
+			next.Options[k] = s
+			cases = append(cases, next)
+		}
+	}
+
+	return cases
+}
+
+func (c Case) clone() Case {
+	next := Case{
+		Options: make(map[string]string, len(c.Options)),
+		Inputs:  append([]simulator.Var{}, c.Inputs...),
+		Vars:    make(map[string]simulator.Var, len(c.Vars)),
+	}
+	for k, v := range c.Options {
+		next.Options[k] = v
+	}
+	for k, v := range c.Vars {
+		next.Vars[k] = v
+	}
+	return next
+}
+
+// simplerVars are versions of the var with fewer or shorter values, or less
+// nested JSON.
+func simplerVars(v simulator.Var) []simulator.Var {
+	vars := []simulator.Var{}
+
+	switch {
+	case v.Keep != nil:
+		for _, keep := range simplerJSON(v.Keep) {
+			vars = append(vars, jsonVar(keep))
+		}
+	case len(v.Values) > 0:
+		half := v
+		half.Values = v.Values[:len(v.Values)/2]
+		if len(half.Values) > 0 {
+			vars = append(vars, half)
+		}
+		for i := range v.Values {
+			fewer := v
+			fewer.Values = append(append([]string{}, v.Values[:i]...), v.Values[i+1:]...)
+			if len(fewer.Values) > 0 {
+				vars = append(vars, fewer)
+			}
+		}
+		for i, value := range v.Values {
+			for _, s := range simplerStrings(value) {
+				simpler := v
+				simpler.Values = append([]string{}, v.Values...)
+				simpler.Values[i] = s
+				vars = append(vars, simpler)
+			}
+		}
+	default:
+		for _, s := range simplerStrings(v.Value) {
+			simpler := v
+			simpler.Value = s
+			if s == "" {
+				simpler = stringVar(s)
+			}
+			vars = append(vars, simpler)
+		}
+	}
+
+	return vars
+}
+
+// simplerStrings are shorter versions of the string, or simple numbers.
+func simplerStrings(s string) []string {
+	strs := []string{}
+	if s == "" {
+		return strs
+	}
+	for _, simple := range []string{"", "0", "1"} {
+		if len(simple) < len(s) {
+			strs = append(strs, simple)
+		}
+	}
+	if len(s) > 1 {
+		strs = append(strs, s[:len(s)/2], s[len(s)/2:], s[:len(s)-1])
+	}
+	return strs
+}
+
+// simplerJSON are versions of the JSON with its children in place of it,
+// fewer elements or keys, or empty.
+func simplerJSON(value interface{}) []interface{} {
+	values := []interface{}{}
+
+	// Deeply nested JSON is shrunk by half its depth at a time, as a level at
+	// a time would use up the runs
+	if depth := chainDepth(value); depth > 4 {
+		values = append(values, descend(value, depth/2))
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		values = append(values, v...)
+		if len(v) > 0 {
+			values = append(values, []interface{}{})
+		}
+		if len(v) > 1 {
+			values = append(values, append([]interface{}{}, v[:len(v)/2]...))
+		}
+		for i := range v {
+			values = append(values, append(append([]interface{}{}, v[:i]...), v[i+1:]...))
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			values = append(values, v[k])
+		}
+		if len(v) > 0 {
+			values = append(values, map[string]interface{}{})
+		}
+		for _, k := range keys {
+			fewer := make(map[string]interface{}, len(v)-1)
+			for k2, v2 := range v {
+				if k2 != k {
+					fewer[k2] = v2
+				}
+			}
+			values = append(values, fewer)
+		}
+	case string:
+		for _, s := range simplerStrings(v) {
+			values = append(values, s)
+		}
+	case float64:
+		if v != 0 {
+			values = append(values, float64(0))
+		}
+	}
+
+	return values
+}
+
+// chainDepth is the number of arrays and objects with a single child nested
+// in each other, starting at the value.
+func chainDepth(value interface{}) int {
+	depth := 0
+	for {
+		child, ok := onlyChild(value)
+		if !ok {
+			return depth
+		}
+		value = child
+		depth++
+	}
+}
+
+func descend(value interface{}, levels int) interface{} {
+	for i := 0; i < levels; i++ {
+		value, _ = onlyChild(value)
+	}
+	return value
+}
+
+func onlyChild(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		if len(v) == 1 {
+			return v[0], true
+		}
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for _, child := range v {
+				return child, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedVarKeys(m map[string]simulator.Var) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
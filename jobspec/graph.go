@@ -0,0 +1,37 @@
+package jobspec
+
+import "github.com/pickleyd/chainlink/core/services/pipeline"
+
+type TaskDependency struct {
+	Id              string `json:"id"`
+	PropagateResult bool   `json:"propagateResult"`
+}
+
+type Task struct {
+	Id     string           `json:"id"`
+	Inputs []TaskDependency `json:"inputs"`
+}
+
+// Graph lists the tasks of the pipeline, in execution order, with the tasks
+// each depends on.
+func Graph(p *pipeline.Pipeline) []Task {
+	tasks := []Task{}
+	if p == nil {
+		return tasks
+	}
+
+	for _, element := range p.Tasks {
+		task := Task{
+			Id:     element.DotID(),
+			Inputs: []TaskDependency{},
+		}
+		for _, input := range element.Inputs() {
+			task.Inputs = append(task.Inputs, TaskDependency{
+				Id:              input.InputTask.DotID(),
+				PropagateResult: input.PropagateResult,
+			})
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
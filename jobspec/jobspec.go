@@ -0,0 +1,137 @@
+package jobspec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// Spec is a job spec as read from a file. It is either a TOML job spec, with
+// the pipeline in its observationSource, or a bare pipeline in DOT.
+type Spec struct {
+	File string
+	Type string
+	Name string
+	// Fields are the job level fields of a TOML job spec, nil for a bare
+	// pipeline
+	Fields map[string]interface{}
+	// Source is the pipeline in DOT
+	Source string
+	// Line is the line of the file that the pipeline starts on, counting
+	// from 1
+	Line int
+	// Pipeline is nil if the pipeline failed to parse, with ParseErr saying
+	// why
+	Pipeline *pipeline.Pipeline
+	ParseErr error
+
+	tree *toml.Tree
+	data string
+}
+
+// jobSpecField is a line that only a TOML job spec would have, used to pick
+// which error to report for input that is neither valid TOML nor valid DOT.
+// type is left out as multi-line DOT attributes can start with it.
+var jobSpecField = regexp.MustCompile(`(?m)^\s*(schemaVersion|observationSource)\s*=`)
+
+// Load reads a spec. It is a TOML job spec if it is valid TOML with a string
+// type or observationSource, and a bare pipeline otherwise. It only fails if
+// it looks like a TOML job spec but is neither valid TOML nor a valid
+// pipeline; a pipeline that fails to parse is reported through ParseErr
+// instead, so that tools can still report on the rest of the spec.
+func Load(data []byte) (*Spec, error) {
+	spec := &Spec{
+		Source: string(data),
+		Line:   1,
+		data:   string(data),
+	}
+
+	tree, tomlErr := toml.LoadBytes(data)
+	if tomlErr == nil && isJobSpec(tree) {
+		spec.tree = tree
+		spec.Fields = tree.ToMap()
+		spec.Type, _ = spec.Fields["type"].(string)
+		spec.Name, _ = spec.Fields["name"].(string)
+		spec.Source, _ = spec.Fields["observationSource"].(string)
+		spec.Line = spec.sourceLine()
+	}
+
+	spec.Pipeline, spec.ParseErr = pipeline.Parse(spec.Source)
+	if spec.ParseErr != nil && tomlErr != nil && jobSpecField.Match(data) {
+		return nil, tomlErr
+	}
+	return spec, nil
+}
+
+// isJobSpec reports whether the TOML has the top level fields of a job spec.
+func isJobSpec(tree *toml.Tree) bool {
+	for _, key := range []string{"type", "observationSource"} {
+		if _, ok := tree.Get(key).(string); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFile reads a spec from a file, or from stdin if the path is "-".
+func LoadFile(path string) (*Spec, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	spec.File = path
+	return spec, nil
+}
+
+// Text is the spec as it was read, e.g. for rewriting parts of it.
+func (s *Spec) Text() string {
+	return s.data
+}
+
+// sourceLine finds the line of the file that the observationSource starts
+// on. Multi-line TOML strings are kept verbatim apart from escapes, so the
+// source can usually be found as is.
+func (s *Spec) sourceLine() int {
+	if s.Source != "" {
+		if i := strings.Index(s.data, s.Source); i >= 0 {
+			return strings.Count(s.data[:i], "\n") + 1
+		}
+	}
+	return s.FieldLine("observationSource")
+}
+
+// FieldLine is the line of the file a job level field is on, or 0 if the
+// spec doesn't have it.
+func (s *Spec) FieldLine(key string) int {
+	if s.tree == nil || !s.tree.Has(key) {
+		return 0
+	}
+	return s.tree.GetPosition(key).Line
+}
+
+// TaskLine is the line of the file the task with the given dot ID is
+// declared on, or the first line of the pipeline if it can't be found.
+func (s *Spec) TaskLine(dotID string) int {
+	declaration := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(dotID) + `\s*\[`)
+	loc := declaration.FindStringIndex(s.Source)
+	if loc == nil {
+		return s.Line
+	}
+	return s.Line + strings.Count(s.Source[:loc[0]], "\n")
+}
@@ -0,0 +1,96 @@
+package jobspec
+
+import (
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantTOML   bool
+		wantType   string
+		wantSource string
+		wantLine   int
+		wantErr    bool
+	}{
+		{
+			name: "TOML job spec",
+			data: `type = "directrequest"
+schemaVersion = 1
+name = "example"
+observationSource = """
+fetch [type=http method=GET url="https://example.com"]
+"""
+`,
+			wantTOML:   true,
+			wantType:   "directrequest",
+			wantSource: "fetch [type=http method=GET url=\"https://example.com\"]\n",
+			wantLine:   5,
+		},
+		{
+			name:       "TOML with only an observationSource",
+			data:       "observationSource = \"a [type=any]\"\n",
+			wantTOML:   true,
+			wantSource: "a [type=any]",
+			wantLine:   1,
+		},
+		{
+			name:       "DOT",
+			data:       "fetch [type=http method=GET url=\"https://example.com\"]\n",
+			wantSource: "fetch [type=http method=GET url=\"https://example.com\"]\n",
+			wantLine:   1,
+		},
+		{
+			name: "DOT with a multi-line attribute list",
+			data: `fetch [type="http"
+       method=GET
+       url="https://example.com"]
+`,
+			wantSource: "fetch [type=\"http\"\n       method=GET\n       url=\"https://example.com\"]\n",
+			wantLine:   1,
+		},
+		{
+			name: "DOT with an attribute list starting on its own line",
+			data: `ds [
+  type=bridge
+  name="price"
+]
+parse [type=jsonparse path="data,result"]
+ds -> parse
+`,
+			wantSource: "ds [\n  type=bridge\n  name=\"price\"\n]\nparse [type=jsonparse path=\"data,result\"]\nds -> parse\n",
+			wantLine:   1,
+		},
+		{
+			name:    "invalid TOML job spec",
+			data:    "type = \"directrequest\"\nschemaVersion = 1\nobservationSource = \"\"\"\na [type=any]\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec, err := Load([]byte(test.data))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if isTOML := spec.Fields != nil; isTOML != test.wantTOML {
+				t.Errorf("read as TOML: %v, want %v", isTOML, test.wantTOML)
+			}
+			if spec.Type != test.wantType {
+				t.Errorf("type %q, want %q", spec.Type, test.wantType)
+			}
+			if spec.Source != test.wantSource {
+				t.Errorf("source %q, want %q", spec.Source, test.wantSource)
+			}
+			if spec.Line != test.wantLine {
+				t.Errorf("line %d, want %d", spec.Line, test.wantLine)
+			}
+		})
+	}
+}
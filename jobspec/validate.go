@@ -0,0 +1,86 @@
+package jobspec
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// requiredFields are the job level fields each job type needs, beyond type
+// and schemaVersion.
+var requiredFields = map[string][]string{
+	"cron":              {"schedule", "observationSource"},
+	"directrequest":     {"contractAddress", "observationSource"},
+	"fluxmonitor":       {"contractAddress", "observationSource"},
+	"keeper":            {"contractAddress", "fromAddress"},
+	"offchainreporting": {"contractAddress"},
+	"webhook":           {"observationSource"},
+}
+
+var addressFields = []string{"contractAddress", "fromAddress"}
+
+// FieldError is a problem with a job level field.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Validate checks the job level fields of a TOML job spec. It checks what can
+// be checked without a node, such as the job type's required fields, rather
+// than everything a node validates when the job is created. A bare pipeline
+// has nothing to validate.
+func (s *Spec) Validate() []FieldError {
+	if s.Fields == nil {
+		return nil
+	}
+
+	errs := []FieldError{}
+
+	if s.Type == "" {
+		errs = append(errs, FieldError{Field: "type", Message: "is required"})
+	} else if _, known := requiredFields[s.Type]; !known {
+		types := make([]string, 0, len(requiredFields))
+		for t := range requiredFields {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		errs = append(errs, FieldError{Field: "type", Message: fmt.Sprintf("unknown job type %q, expected one of %v", s.Type, types)})
+	}
+
+	switch version := s.Fields["schemaVersion"].(type) {
+	case nil:
+		errs = append(errs, FieldError{Field: "schemaVersion", Message: "is required"})
+	case int64:
+		if version != 1 {
+			errs = append(errs, FieldError{Field: "schemaVersion", Message: fmt.Sprintf("must be 1, got %d", version)})
+		}
+	default:
+		errs = append(errs, FieldError{Field: "schemaVersion", Message: "must be a number"})
+	}
+
+	for _, field := range requiredFields[s.Type] {
+		if value, ok := s.Fields[field]; !ok || value == "" {
+			errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf("is required for %s jobs", s.Type)})
+		}
+	}
+
+	for _, field := range addressFields {
+		value, ok := s.Fields[field]
+		if !ok {
+			continue
+		}
+		if address, isString := value.(string); !isString || !common.IsHexAddress(address) {
+			errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf("%v is not an address", value)})
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,185 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/jobspec"
+)
+
+// Find returns the spec files among the paths, walking directories for
+// .toml files.
+func Find(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		if path == "-" {
+			files = append(files, path)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(p) == ".toml" {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// LintFiles lints the files with the given number of workers. The results are
+// in the same order as the files. A file that can't be read or isn't valid
+// TOML gets a parse finding rather than stopping the batch. References to
+// contracts in abi attributes are resolved against the registry, which may
+// be nil.
+func LintFiles(files []string, workers int, registry *contracts.Registry) []FileFindings {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]FileFindings, len(files))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = lintFile(files[i], registry)
+			}
+		}()
+	}
+
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func lintFile(path string, registry *contracts.Registry) FileFindings {
+	spec, err := jobspec.LoadFile(path)
+	if err != nil {
+		return FileFindings{
+			File: path,
+			Findings: []Finding{{
+				Rule:     parseRule.ID,
+				Severity: SeverityError,
+				Message:  err.Error(),
+				Line:     1,
+			}},
+		}
+	}
+	if spec.Pipeline != nil {
+		registry.ResolvePipeline(spec.Pipeline)
+	}
+	return FileFindings{File: path, Findings: Lint(spec)}
+}
+
+// LocatedFinding is a finding along with the file it is in.
+type LocatedFinding struct {
+	File string `json:"file"`
+	Finding
+}
+
+// RuleGroup collects the findings of one rule across files.
+type RuleGroup struct {
+	Rule        string           `json:"rule"`
+	Description string           `json:"description"`
+	Severity    Severity         `json:"severity"`
+	Findings    []LocatedFinding `json:"findings"`
+}
+
+// Summary counts the findings of a batch.
+type Summary struct {
+	Files         int `json:"files"`
+	FilesWithErrs int `json:"filesWithErrors"`
+	Errors        int `json:"errors"`
+	Warnings      int `json:"warnings"`
+	Infos         int `json:"infos"`
+}
+
+// ByRule groups the findings by rule, in the order the rules run, followed by
+// findings of unknown rules by ID. Rules without findings are left out.
+func ByRule(files []FileFindings) []RuleGroup {
+	groups := map[string]*RuleGroup{}
+	for _, file := range files {
+		for _, finding := range file.Findings {
+			group, ok := groups[finding.Rule]
+			if !ok {
+				group = &RuleGroup{Rule: finding.Rule}
+				if rule := RuleByID(finding.Rule); rule != nil {
+					group.Description = rule.Description
+					group.Severity = rule.Severity
+				}
+				groups[finding.Rule] = group
+			}
+			group.Findings = append(group.Findings, LocatedFinding{File: file.File, Finding: finding})
+		}
+	}
+
+	order := map[string]int{}
+	for i, rule := range Rules {
+		order[rule.ID] = i
+	}
+
+	result := make([]RuleGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	rank := func(rule string) int {
+		if i, ok := order[rule]; ok {
+			return i
+		}
+		return len(Rules)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ri, rj := rank(result[i].Rule), rank(result[j].Rule)
+		if ri != rj {
+			return ri < rj
+		}
+		return result[i].Rule < result[j].Rule
+	})
+	return result
+}
+
+func Summarize(files []FileFindings) Summary {
+	summary := Summary{Files: len(files)}
+	for _, file := range files {
+		if HasErrors(file.Findings) {
+			summary.FilesWithErrs++
+		}
+		for _, finding := range file.Findings {
+			switch finding.Severity {
+			case SeverityError:
+				summary.Errors++
+			case SeverityWarning:
+				summary.Warnings++
+			default:
+				summary.Infos++
+			}
+		}
+	}
+	return summary
+}
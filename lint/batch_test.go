@@ -0,0 +1,168 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.toml", "b.txt", "nested/c.toml"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "directory",
+			paths: []string{dir},
+			want:  []string{filepath.Join(dir, "a.toml"), filepath.Join(dir, "nested/c.toml")},
+		},
+		{
+			name:  "file of any extension",
+			paths: []string{filepath.Join(dir, "b.txt")},
+			want:  []string{filepath.Join(dir, "b.txt")},
+		},
+		{
+			name:  "stdin",
+			paths: []string{"-"},
+			want:  []string{"-"},
+		},
+		{
+			name:    "missing",
+			paths:   []string{filepath.Join(dir, "missing.toml")},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Find(test.paths)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLintFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{}
+	for _, name := range []string{"a.toml", "b.toml", "c.toml", "d.toml", "e.toml"} {
+		files = append(files, filepath.Join(dir, name))
+	}
+
+	tests := []struct {
+		name    string
+		workers int
+	}{
+		{name: "no workers", workers: 0},
+		{name: "one worker", workers: 1},
+		{name: "more workers than files", workers: 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := LintFiles(files, test.workers, nil)
+			if len(results) != len(files) {
+				t.Fatalf("%d results, want %d", len(results), len(files))
+			}
+			for i, result := range results {
+				if result.File != files[i] {
+					t.Errorf("result %d for %s, want %s", i, result.File, files[i])
+				}
+				if len(result.Findings) != 1 || result.Findings[0].Rule != parseRule.ID || result.Findings[0].Line != 1 {
+					t.Errorf("%s findings %+v, want a parse finding on line 1", result.File, result.Findings)
+				}
+			}
+		})
+	}
+}
+
+var batchFindings = []FileFindings{
+	{File: "a.toml", Findings: []Finding{
+		{Rule: "no-timeout", Severity: SeverityWarning},
+		{Rule: "parse", Severity: SeverityError},
+	}},
+	{File: "b.toml"},
+	{File: "c.toml", Findings: []Finding{
+		{Rule: "no-timeout", Severity: SeverityWarning},
+		{Rule: "custom", Severity: SeverityInfo},
+	}},
+}
+
+func TestByRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []FileFindings
+		wantRules []string
+		wantFiles [][]string
+	}{
+		{name: "no findings", files: []FileFindings{{File: "a.toml"}}, wantRules: []string{}, wantFiles: [][]string{}},
+		{
+			name:      "in rule order",
+			files:     batchFindings,
+			wantRules: []string{"parse", "no-timeout", "custom"},
+			wantFiles: [][]string{{"a.toml"}, {"a.toml", "c.toml"}, {"c.toml"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, files := []string{}, [][]string{}
+			for _, group := range ByRule(test.files) {
+				rules = append(rules, group.Rule)
+				groupFiles := []string{}
+				for _, finding := range group.Findings {
+					groupFiles = append(groupFiles, finding.File)
+				}
+				files = append(files, groupFiles)
+			}
+			if !reflect.DeepEqual(rules, test.wantRules) {
+				t.Errorf("rules %v, want %v", rules, test.wantRules)
+			}
+			if !reflect.DeepEqual(files, test.wantFiles) {
+				t.Errorf("files %v, want %v", files, test.wantFiles)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []FileFindings
+		want  Summary
+	}{
+		{name: "nothing", want: Summary{}},
+		{name: "clean file", files: []FileFindings{{File: "a.toml"}}, want: Summary{Files: 1}},
+		{
+			name:  "findings",
+			files: batchFindings,
+			want:  Summary{Files: 3, FilesWithErrs: 1, Errors: 1, Warnings: 2, Infos: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Summarize(test.files); got != test.want {
+				t.Errorf("%+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package lint
+
+import (
+	"sort"
+
+	"github.com/pickleyd/jobspecviz/jobspec"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Finding is a problem a rule found in a spec.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Task is the dot ID of the task the finding is about, if any
+	Task string `json:"task,omitempty"`
+	// Line is the line of the spec's file the finding points at
+	Line int `json:"line"`
+}
+
+// Rule checks a spec for one kind of problem. Severity is the default for the
+// rule's findings.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    Severity
+	// NeedsPipeline rules only run on specs whose pipeline parsed
+	NeedsPipeline bool
+	Check         func(spec *jobspec.Spec) []Finding
+}
+
+// Rules are all the lint rules, in the order they run.
+var Rules = []Rule{
+	parseRule,
+	jobSpecRule,
+	undefinedVarRule,
+	allowedFaultsRule,
+	divideByZeroRule,
+	noTimeoutRule,
+	multipleResultsRule,
+	abiRule,
+}
+
+// RuleByID returns the rule with the given ID, or nil.
+func RuleByID(id string) *Rule {
+	for i := range Rules {
+		if Rules[i].ID == id {
+			return &Rules[i]
+		}
+	}
+	return nil
+}
+
+// Lint runs every rule against the spec.
+func Lint(spec *jobspec.Spec) []Finding {
+	return Run(spec, Rules)
+}
+
+// Run runs the given rules against the spec. The findings are sorted by line.
+func Run(spec *jobspec.Spec, rules []Rule) []Finding {
+	findings := []Finding{}
+
+	for _, rule := range rules {
+		if rule.NeedsPipeline && spec.Pipeline == nil {
+			continue
+		}
+
+		for _, finding := range rule.Check(spec) {
+			finding.Rule = rule.ID
+			if finding.Severity == "" {
+				finding.Severity = rule.Severity
+			}
+			if finding.Line == 0 {
+				if finding.Task != "" {
+					finding.Line = spec.TaskLine(finding.Task)
+				} else {
+					finding.Line = spec.Line
+				}
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// HasErrors reports whether any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
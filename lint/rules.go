@@ -0,0 +1,266 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/jobspec"
+	"github.com/pickleyd/jobspecviz/simulator"
+	"github.com/shopspring/decimal"
+)
+
+var parseRule = Rule{
+	ID:          "parse",
+	Description: "The pipeline must parse",
+	Severity:    SeverityError,
+	Check: func(spec *jobspec.Spec) []Finding {
+		if spec.ParseErr == nil {
+			return nil
+		}
+		return []Finding{{Message: spec.ParseErr.Error()}}
+	},
+}
+
+var jobSpecRule = Rule{
+	ID:          "job-spec",
+	Description: "The job level fields must be valid for the job type",
+	Severity:    SeverityError,
+	Check: func(spec *jobspec.Spec) []Finding {
+		findings := []Finding{}
+		for _, err := range spec.Validate() {
+			line := spec.FieldLine(err.Field)
+			if line == 0 {
+				line = 1
+			}
+			findings = append(findings, Finding{Message: err.Error(), Line: line})
+		}
+		return findings
+	},
+}
+
+var varReference = regexp.MustCompile(`\$\(\s*([^)\s]+)\s*\)`)
+
+// globalVars are always set by the node.
+var globalVars = map[string]bool{
+	"jobRun":  true,
+	"jobSpec": true,
+}
+
+var undefinedVarRule = Rule{
+	ID:            "undefined-var",
+	Description:   "Vars must refer to jobRun, jobSpec or a task that runs earlier",
+	Severity:      SeverityError,
+	NeedsPipeline: true,
+	Check: func(spec *jobspec.Spec) []Finding {
+		tasks := map[string]bool{}
+		for _, task := range spec.Pipeline.Tasks {
+			tasks[task.DotID()] = true
+		}
+
+		findings := []Finding{}
+		for _, task := range spec.Pipeline.Tasks {
+			upstream := map[string]bool{}
+			simulator.MarkUpstream(task, upstream)
+
+			for _, ref := range references(task) {
+				root := strings.Split(ref, ".")[0]
+				switch {
+				case globalVars[root] || upstream[root]:
+				case tasks[root]:
+					findings = append(findings, Finding{
+						Task:    task.DotID(),
+						Message: fmt.Sprintf("$(%s) refers to %s, which doesn't run before %s", ref, root, task.DotID()),
+					})
+				default:
+					findings = append(findings, Finding{
+						Task:     task.DotID(),
+						Severity: SeverityWarning,
+						Message:  fmt.Sprintf("$(%s) refers to %s, which is not a task, jobRun or jobSpec", ref, root),
+					})
+				}
+			}
+		}
+		return findings
+	},
+}
+
+var allowedFaultsRule = Rule{
+	ID:            "allowed-faults",
+	Description:   "allowedFaults should be less than the number of values aggregated",
+	Severity:      SeverityWarning,
+	NeedsPipeline: true,
+	Check: func(spec *jobspec.Spec) []Finding {
+		findings := []Finding{}
+		for _, task := range spec.Pipeline.Tasks {
+			// Count as the task would, with a stand-in for each var and
+			// input, as only the number of values matters
+			vars := map[string]interface{}{}
+			for _, ref := range references(task) {
+				_ = simulator.SetKeypath(vars, ref, 0)
+			}
+			inputs := []pipeline.Result{}
+			for _, dep := range task.Inputs() {
+				if dep.PropagateResult {
+					inputs = append(inputs, pipeline.Result{Value: 0})
+				}
+			}
+			count := simulator.CountFaults(task, vars, inputs)
+			if count == nil {
+				continue
+			}
+
+			if count.Allowed >= count.Values {
+				findings = append(findings, Finding{
+					Task:    task.DotID(),
+					Message: fmt.Sprintf("allowedFaults is %d but there are only %d values, so every value may fail", count.Allowed, count.Values),
+				})
+			}
+		}
+		return findings
+	},
+}
+
+var divideByZeroRule = Rule{
+	ID:            "divide-by-zero",
+	Description:   "divide must not have a literal divisor of zero",
+	Severity:      SeverityError,
+	NeedsPipeline: true,
+	Check: func(spec *jobspec.Spec) []Finding {
+		findings := []Finding{}
+		for _, task := range spec.Pipeline.Tasks {
+			divide, ok := task.(*pipeline.DivideTask)
+			if !ok {
+				continue
+			}
+			if divisor, err := decimal.NewFromString(strings.TrimSpace(divide.Divisor)); err == nil && divisor.IsZero() {
+				findings = append(findings, Finding{Task: task.DotID(), Message: "the divisor is zero"})
+			}
+		}
+		return findings
+	},
+}
+
+var noTimeoutRule = Rule{
+	ID:            "no-timeout",
+	Description:   "http and bridge tasks should set a timeout",
+	Severity:      SeverityInfo,
+	NeedsPipeline: true,
+	Check: func(spec *jobspec.Spec) []Finding {
+		findings := []Finding{}
+		for _, task := range spec.Pipeline.Tasks {
+			switch task.(type) {
+			case *pipeline.HTTPTask, *pipeline.BridgeTask:
+			default:
+				continue
+			}
+			if _, set := task.TaskTimeout(); !set {
+				findings = append(findings, Finding{
+					Task:    task.DotID(),
+					Message: fmt.Sprintf("%s has no timeout, so it waits as long as the node's default", task.DotID()),
+				})
+			}
+		}
+		return findings
+	},
+}
+
+var multipleResultsRule = Rule{
+	ID:            "multiple-results",
+	Description:   "A pipeline with more than one final task returns an array of results",
+	Severity:      SeverityInfo,
+	NeedsPipeline: true,
+	Check: func(spec *jobspec.Spec) []Finding {
+		final := []string{}
+		for _, task := range spec.Pipeline.Tasks {
+			if len(task.Outputs()) == 0 {
+				final = append(final, task.DotID())
+			}
+		}
+		if len(final) < 2 {
+			return nil
+		}
+		return []Finding{{
+			Message: fmt.Sprintf("the pipeline has %d final tasks (%s), so its result is an array", len(final), strings.Join(final, ", ")),
+		}}
+	},
+}
+
+var abiRule = Rule{
+	ID:            "abi",
+	Description:   "abi attributes must be valid signatures, or refer to a contract in the ABI registry",
+	Severity:      SeverityError,
+	NeedsPipeline: true,
+	Check: func(spec *jobspec.Spec) []Finding {
+		findings := []Finding{}
+		for _, task := range spec.Pipeline.Tasks {
+			var attr, taskType string
+			switch t := task.(type) {
+			case *pipeline.ETHABIEncodeTask:
+				attr, taskType = t.ABI, contracts.TaskETHABIEncode
+			case *pipeline.ETHABIDecodeTask:
+				attr, taskType = t.ABI, contracts.TaskETHABIDecode
+			case *pipeline.ETHABIDecodeLogTask:
+				attr, taskType = t.ABI, contracts.TaskETHABIDecodeLog
+			default:
+				continue
+			}
+
+			// References are resolved before linting, so one that is left
+			// isn't in the registry
+			if contracts.IsReference(attr) {
+				findings = append(findings, Finding{
+					Task:    task.DotID(),
+					Message: fmt.Sprintf("abi %s doesn't refer to a method or event in the ABI registry", strings.TrimSpace(attr)),
+				})
+			} else if err := contracts.ValidateAttribute(taskType, attr); err != nil {
+				findings = append(findings, Finding{Task: task.DotID(), Message: err.Error()})
+			}
+		}
+		return findings
+	},
+}
+
+// references returns the keypaths of the vars the task's attributes refer to.
+func references(task pipeline.Task) []string {
+	jsonData, err := json.Marshal(task)
+	if err != nil {
+		return nil
+	}
+
+	attrs := map[string]interface{}{}
+	if err := json.Unmarshal(jsonData, &attrs); err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case string:
+			for _, ref := range varReference.FindAllStringSubmatch(val, -1) {
+				seen[ref[1]] = true
+			}
+		case map[string]interface{}:
+			for _, nested := range val {
+				walk(nested)
+			}
+		case []interface{}:
+			for _, nested := range val {
+				walk(nested)
+			}
+		}
+	}
+	walk(attrs)
+
+	refs := make([]string, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
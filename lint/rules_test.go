@@ -0,0 +1,64 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/pickleyd/jobspecviz/jobspec"
+)
+
+func TestAllowedFaultsRule(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       string
+		wantFindings int
+	}{
+		{
+			name: "fewer faults than inputs",
+			source: `a [type=any]
+b [type=any]
+c [type=any]
+median [type=median allowedFaults=1]
+a -> median
+b -> median
+c -> median`,
+		},
+		{
+			name: "as many faults as inputs",
+			source: `a [type=any]
+b [type=any]
+median [type=median allowedFaults=2]
+a -> median
+b -> median`,
+			wantFindings: 1,
+		},
+		{
+			name:         "as many faults as listed values",
+			source:       `median [type=median values=<[1, 2]> allowedFaults=2]`,
+			wantFindings: 1,
+		},
+		{
+			name:   "listed values with vars",
+			source: `median [type=median values=<[$(a), $(b), $(c)]> allowedFaults="2"]`,
+		},
+		{
+			name:   "no allowedFaults",
+			source: `median [type=median values=<[1]>]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec, err := jobspec.Load([]byte(test.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if spec.ParseErr != nil {
+				t.Fatal(spec.ParseErr)
+			}
+			findings := allowedFaultsRule.Check(spec)
+			if len(findings) != test.wantFindings {
+				t.Errorf("findings %+v, want %d", findings, test.wantFindings)
+			}
+		})
+	}
+}
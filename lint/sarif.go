@@ -0,0 +1,146 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+// FileFindings are the findings in one spec file.
+type FileFindings struct {
+	File     string    `json:"file"`
+	Findings []Finding `json:"findings"`
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex *int            `json:"ruleIndex,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLevel maps a severity to a SARIF level, which calls info a note.
+func sarifLevel(severity Severity) string {
+	if severity == SeverityInfo {
+		return "note"
+	}
+	return string(severity)
+}
+
+// WriteSARIF writes the findings as a SARIF 2.1.0 log, with each finding
+// located at its line in the spec file.
+func WriteSARIF(w io.Writer, files []FileFindings) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:  "jobspecviz",
+				Rules: []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	ruleIndex := map[string]int{}
+	for i, rule := range Rules {
+		ruleIndex[rule.ID] = i
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Severity)},
+		})
+	}
+
+	for _, file := range files {
+		uri := filepath.ToSlash(file.File)
+		if file.File == "-" {
+			uri = "stdin"
+		}
+
+		for _, finding := range file.Findings {
+			line := finding.Line
+			if line < 1 {
+				line = 1
+			}
+
+			result := sarifResult{
+				RuleID:  finding.Rule,
+				Level:   sarifLevel(finding.Severity),
+				Message: sarifMessage{Text: finding.Message},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: uri},
+						Region:           sarifRegion{StartLine: line},
+					},
+				}},
+			}
+			// Findings of rules outside Rules, e.g. custom ones, have no
+			// index to point at
+			if idx, ok := ruleIndex[finding.Rule]; ok {
+				result.RuleIndex = &idx
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,92 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []FileFindings
+		wantURIs  []string
+		wantLines []int
+		wantLevel []string
+	}{
+		{
+			name:  "no findings",
+			files: []FileFindings{{File: "price.toml"}},
+		},
+		{
+			name: "findings",
+			files: []FileFindings{
+				{File: "specs/price.toml", Findings: []Finding{
+					{Rule: "no-timeout", Severity: SeverityWarning, Message: "no timeout", Line: 7},
+					{Rule: "parse", Severity: SeverityError, Message: "bad spec"},
+				}},
+				{File: "-", Findings: []Finding{
+					{Rule: "divide-by-zero", Severity: SeverityInfo, Message: "divisor may be 0", Line: 2},
+				}},
+			},
+			wantURIs:  []string{"specs/price.toml", "specs/price.toml", "stdin"},
+			wantLines: []int{7, 1, 2},
+			wantLevel: []string{"warning", "error", "note"},
+		},
+		{
+			name: "finding of a rule outside Rules",
+			files: []FileFindings{
+				{File: "price.toml", Findings: []Finding{
+					{Rule: "custom", Severity: SeverityWarning, Message: "custom check", Line: 3},
+				}},
+			},
+			wantURIs:  []string{"price.toml"},
+			wantLines: []int{3},
+			wantLevel: []string{"warning"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteSARIF(&buf, test.files); err != nil {
+				t.Fatal(err)
+			}
+
+			var got sarifLog
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if got.Version != "2.1.0" || len(got.Runs) != 1 {
+				t.Fatalf("version %q with %d runs, want 2.1.0 with 1", got.Version, len(got.Runs))
+			}
+			run := got.Runs[0]
+			if len(run.Tool.Driver.Rules) != len(Rules) {
+				t.Errorf("%d rules, want %d", len(run.Tool.Driver.Rules), len(Rules))
+			}
+			if len(run.Results) != len(test.wantURIs) {
+				t.Fatalf("%d results, want %d", len(run.Results), len(test.wantURIs))
+			}
+
+			for i, result := range run.Results {
+				location := result.Locations[0].PhysicalLocation
+				if location.ArtifactLocation.URI != test.wantURIs[i] {
+					t.Errorf("result %d uri %q, want %q", i, location.ArtifactLocation.URI, test.wantURIs[i])
+				}
+				if location.Region.StartLine != test.wantLines[i] {
+					t.Errorf("result %d line %d, want %d", i, location.Region.StartLine, test.wantLines[i])
+				}
+				if result.Level != test.wantLevel[i] {
+					t.Errorf("result %d level %q, want %q", i, result.Level, test.wantLevel[i])
+				}
+				if result.RuleIndex == nil {
+					if RuleByID(result.RuleID) != nil {
+						t.Errorf("result %d has no rule index, want one for %s", i, result.RuleID)
+					}
+				} else if rule := run.Tool.Driver.Rules[*result.RuleIndex]; rule.ID != result.RuleID {
+					t.Errorf("result %d rule index points at %s, want %s", i, rule.ID, result.RuleID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		wantStatus int
+		wantNext   bool
+	}{
+		{name: "preflight", method: http.MethodOptions, wantStatus: http.StatusNoContent},
+		{name: "post", method: http.MethodPost, wantStatus: http.StatusTeapot, wantNext: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			rec := httptest.NewRecorder()
+			CORS("http://localhost:3000", next).ServeHTTP(rec, httptest.NewRequest(test.method, "/api/run", nil))
+
+			if rec.Code != test.wantStatus {
+				t.Errorf("status %d, want %d", rec.Code, test.wantStatus)
+			}
+			if called != test.wantNext {
+				t.Errorf("called the handler: %v, want %v", called, test.wantNext)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+				t.Errorf("allowed origin %q, want %q", got, "http://localhost:3000")
+			}
+		})
+	}
+}
+
+func TestLogging(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name:    "implicit status",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+			want:    "POST /api/run 200 ",
+		},
+		{
+			name: "error status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "bad", http.StatusBadRequest)
+			},
+			want: "POST /api/run 400 ",
+		},
+		{
+			name: "streaming",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				flusher, ok := w.(http.Flusher)
+				if !ok {
+					http.Error(w, "not a flusher", http.StatusInternalServerError)
+					return
+				}
+				w.Write([]byte("event"))
+				flusher.Flush()
+			},
+			want: "POST /api/run 200 ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			rec := httptest.NewRecorder()
+			Logging(log.New(&logs, "", 0), test.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/run", nil))
+
+			if !strings.HasPrefix(logs.String(), test.want) {
+				t.Errorf("logged %q, want %q", logs.String(), test.want)
+			}
+		})
+	}
+}
@@ -3,4 +3,15 @@ const nextConfig = {
   reactStrictMode: true,
 }
 
+// The Go API routes, served by `vercel dev` or, if GO_API_URL is set, by
+// `jobspecviz serve`
+const goApiRoutes = ["abi-codec", "abis", "analyze", "debug", "event-log", "graph", "run", "stream", "sweep", "task", "var-helper"]
+
+if (process.env.GO_API_URL) {
+  nextConfig.rewrites = async () => goApiRoutes.map((route) => ({
+    source: `/api/${route}`,
+    destination: `${process.env.GO_API_URL}/api/${route}`,
+  }))
+}
+
 module.exports = nextConfig
@@ -0,0 +1,145 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/chain"
+)
+
+// runOnChain runs an ethcall or ethtx against the mock chain its evmChainID
+// picks, the way a node would against a real one. It returns false for
+// other tasks and without chains, in which case the task runs as usual.
+func runOnChain(task pipeline.Task, vars map[string]interface{}, chains chain.Chains) (pipeline.Result, pipeline.RunInfo, bool) {
+	if len(chains) == 0 {
+		return pipeline.Result{}, pipeline.RunInfo{}, false
+	}
+
+	switch t := task.(type) {
+	case *pipeline.ETHCallTask:
+		result, runInfo := ethCall(t, vars, chains)
+		return result, runInfo, true
+	case *pipeline.ETHTxTask:
+		result, runInfo := ethTx(t, vars, chains)
+		return result, runInfo, true
+	}
+	return pipeline.Result{}, pipeline.RunInfo{}, false
+}
+
+func ethCall(t *pipeline.ETHCallTask, vars map[string]interface{}, chains chain.Chains) (pipeline.Result, pipeline.RunInfo) {
+	c, to, data, err := resolveCall(chains, t.EVMChainID, "contract", t.Contract, t.Data, vars)
+	if err != nil {
+		return pipeline.Result{Error: err}, pipeline.RunInfo{}
+	}
+
+	result, err := c.Call(to, data)
+	if err != nil {
+		// Like a node, the call is retried unless it can never succeed
+		runInfo := pipeline.RunInfo{IsRetryable: !errors.Is(err, chain.ErrUnknownCall)}
+		return pipeline.Result{Error: fmt.Errorf("while calling contract at address %s on chain %s: %w", to.Hex(), c.ID, err)}, runInfo
+	}
+	return pipeline.Result{Value: result}, pipeline.RunInfo{}
+}
+
+func ethTx(t *pipeline.ETHTxTask, vars map[string]interface{}, chains chain.Chains) (pipeline.Result, pipeline.RunInfo) {
+	c, to, data, err := resolveCall(chains, t.EVMChainID, "to", t.To, t.Data, vars)
+	if err != nil {
+		return pipeline.Result{Error: err}, pipeline.RunInfo{}
+	}
+
+	var from common.Address
+	if t.From != "" {
+		if from, err = resolveAddress("from", t.From, vars); err != nil {
+			return pipeline.Result{Error: err}, pipeline.RunInfo{}
+		}
+	}
+
+	tx, err := c.SendTransaction(from, to, data)
+	if err != nil {
+		return pipeline.Result{Error: err}, pipeline.RunInfo{}
+	}
+	if tx.Err != nil && resolveBool(t.FailOnRevert, vars) {
+		return pipeline.Result{Error: fmt.Errorf("transaction %s reverted on chain %s: %w", tx.Hash.Hex(), c.ID, tx.Err)}, pipeline.RunInfo{}
+	}
+	// The node queues the transaction and moves on without a value
+	return pipeline.Result{Value: nil}, pipeline.RunInfo{}
+}
+
+// resolveCall resolves the chain, the address called and the data of an
+// ethcall or ethtx against the vars.
+func resolveCall(chains chain.Chains, evmChainID, addressName, address, dataAttr string, vars map[string]interface{}) (*chain.Chain, common.Address, []byte, error) {
+	id, err := resolveAttr(evmChainID, vars)
+	if err != nil {
+		return nil, common.Address{}, nil, fmt.Errorf("evmChainID: %w", err)
+	}
+	c, err := chains.Chain(chainID(id))
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+
+	to, err := resolveAddress(addressName, address, vars)
+	if err != nil {
+		return nil, common.Address{}, nil, err
+	}
+
+	value, err := resolveAttr(dataAttr, vars)
+	if err != nil {
+		return nil, common.Address{}, nil, fmt.Errorf("data: %w", err)
+	}
+	data, ok := bytesValue(value)
+	if !ok {
+		return nil, common.Address{}, nil, fmt.Errorf("data %q is not hex or bytes", dataAttr)
+	}
+	return c, to, data, nil
+}
+
+func resolveAddress(name, attr string, vars map[string]interface{}) (common.Address, error) {
+	value, err := resolveAttr(attr, vars)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%s: %w", name, err)
+	}
+	switch v := value.(type) {
+	case common.Address:
+		return v, nil
+	case string:
+		if common.IsHexAddress(v) {
+			return common.HexToAddress(v), nil
+		}
+	case []byte:
+		if len(v) == common.AddressLength {
+			return common.BytesToAddress(v), nil
+		}
+	}
+	return common.Address{}, fmt.Errorf("%s %v is not an address", name, value)
+}
+
+func resolveBool(attr string, vars map[string]interface{}) bool {
+	value, err := resolveAttr(attr, vars)
+	if err != nil {
+		return false
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+func chainID(value interface{}) string {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
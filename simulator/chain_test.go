@@ -0,0 +1,64 @@
+package simulator
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pickleyd/jobspecviz/chain"
+)
+
+func TestResolveCall(t *testing.T) {
+	const feed = "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"
+	chains, err := chain.NewChains([]chain.Config{{ChainID: "1"}, {ChainID: "137"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]interface{}{
+		"chainID":  big.NewInt(137),
+		"feed":     common.HexToAddress(feed),
+		"calldata": []byte{0x50, 0xd2, 0x5b, 0xcd},
+	}
+
+	tests := []struct {
+		name       string
+		evmChainID string
+		address    string
+		data       string
+		wantChain  string
+		wantErr    bool
+	}{
+		{name: "literals", evmChainID: "1", address: feed, data: "0x50d25bcd", wantChain: "1"},
+		{name: "vars", evmChainID: "$(chainID)", address: "$(feed)", data: "$(calldata)", wantChain: "137"},
+		{name: "vars with spaces", evmChainID: " $( chainID ) ", address: feed, data: "0x50d25bcd", wantChain: "137"},
+		{name: "no evmChainID with two chains", address: feed, data: "0x50d25bcd", wantErr: true},
+		{name: "unknown chain", evmChainID: "5", address: feed, data: "0x50d25bcd", wantErr: true},
+		{name: "missing var", evmChainID: "$(missing)", address: feed, data: "0x50d25bcd", wantErr: true},
+		{name: "not an address", evmChainID: "1", address: "0x12", data: "0x50d25bcd", wantErr: true},
+		{name: "no address", evmChainID: "1", data: "0x50d25bcd", wantErr: true},
+		{name: "data not hex", evmChainID: "1", address: feed, data: "latestAnswer()", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, to, data, err := resolveCall(chains, test.evmChainID, "contract", test.address, test.data, vars)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if c.ID.String() != test.wantChain {
+				t.Errorf("chain %s, want %s", c.ID, test.wantChain)
+			}
+			if to != common.HexToAddress(feed) {
+				t.Errorf("address %s, want %s", to.Hex(), feed)
+			}
+			if !bytes.Equal(data, []byte{0x50, 0xd2, 0x5b, 0xcd}) {
+				t.Errorf("data %x, want 50d25bcd", data)
+			}
+		})
+	}
+}
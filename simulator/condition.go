@@ -0,0 +1,98 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/shopspring/decimal"
+)
+
+// Comparison operators supported in conditions. Longer operators come first
+// so that ">=" is not mistaken for ">".
+var conditionOperators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// EvalCondition evaluates a simple comparison such as `$(parse) > 1000` or
+// `$(fetch.status) == "ok"` against the vars. Operands are either var
+// references in the same $(...) syntax as task attributes, or literals.
+// Numeric comparison is used when both sides are numbers, otherwise only
+// == and != are allowed and the operands are compared as strings.
+//
+// A condition that references a var which has not been set yet evaluates to
+// false rather than erroring, as that is the normal state early in a run.
+func EvalCondition(condition string, vars map[string]interface{}) (bool, error) {
+	left, op, right, err := splitCondition(condition)
+	if err != nil {
+		return false, err
+	}
+
+	leftVal, leftOk, err := resolveOperand(left, vars)
+	if err != nil || !leftOk {
+		return false, err
+	}
+	rightVal, rightOk, err := resolveOperand(right, vars)
+	if err != nil || !rightOk {
+		return false, err
+	}
+
+	leftDec, leftErr := decimal.NewFromString(leftVal)
+	rightDec, rightErr := decimal.NewFromString(rightVal)
+	if leftErr == nil && rightErr == nil {
+		cmp := leftDec.Cmp(rightDec)
+		switch op {
+		case ">=":
+			return cmp >= 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case "==":
+			return cmp == 0, nil
+		case "!=":
+			return cmp != 0, nil
+		case ">":
+			return cmp > 0, nil
+		case "<":
+			return cmp < 0, nil
+		}
+	}
+
+	switch op {
+	case "==":
+		return leftVal == rightVal, nil
+	case "!=":
+		return leftVal != rightVal, nil
+	}
+	return false, fmt.Errorf("condition %q: %s can only compare numbers", condition, op)
+}
+
+func splitCondition(condition string) (string, string, string, error) {
+	for _, op := range conditionOperators {
+		if idx := strings.Index(condition, op); idx >= 0 {
+			left := strings.TrimSpace(condition[:idx])
+			right := strings.TrimSpace(condition[idx+len(op):])
+			if left == "" || right == "" {
+				break
+			}
+			return left, op, right, nil
+		}
+	}
+	return "", "", "", fmt.Errorf("condition %q must be of the form <left> <operator> <right>", condition)
+}
+
+// resolveOperand returns the string form of the operand, and false if it
+// references a var that is not set.
+func resolveOperand(operand string, vars map[string]interface{}) (string, bool, error) {
+	if strings.HasPrefix(operand, "$(") && strings.HasSuffix(operand, ")") {
+		keypath := strings.TrimSpace(operand[2 : len(operand)-1])
+
+		val, err := pipeline.NewVarsFrom(vars).Get(keypath)
+		if err != nil {
+			return "", false, nil
+		}
+		if _, isErr := val.(error); isErr {
+			return "", false, nil
+		}
+		return fmt.Sprintf("%v", val), true, nil
+	}
+
+	return strings.Trim(operand, `"'`), true, nil
+}
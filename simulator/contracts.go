@@ -0,0 +1,110 @@
+package simulator
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/contracts"
+)
+
+// ContractCall is an ethcall or ethtx decoded against the ABI registry, for
+// display: the method called and its arguments, and what an ethcall
+// returned.
+type ContractCall struct {
+	Call   *contracts.Decoded `json:"call,omitempty"`
+	Result *contracts.Decoded `json:"result,omitempty"`
+	// Error is why the call couldn't be decoded, e.g. a selector that isn't
+	// in the registry
+	Error string `json:"error,omitempty"`
+}
+
+// revertData is the hex revert data a node puts in the error of an ethcall:
+// a selector followed by whole words.
+var revertData = regexp.MustCompile(`0x[0-9a-fA-F]{8}(?:[0-9a-fA-F]{64})*\b`)
+
+// DecodeContractCall decodes the data of an ethcall or ethtx task, after
+// resolving it against the vars, and what an ethcall returned. It returns
+// nil for other tasks, without a registry or without data.
+func DecodeContractCall(registry *contracts.Registry, taskType TaskType, options map[string]interface{}, vars map[string]interface{}, result *TaskResult) *ContractCall {
+	if taskType != TaskTypeETHCall && taskType != TaskTypeETHTx || len(registry.Contracts()) == 0 {
+		return nil
+	}
+	attr, _ := options["data"].(string)
+	value, err := resolveAttr(attr, vars)
+	if err != nil {
+		return nil
+	}
+	data, ok := bytesValue(value)
+	if !ok {
+		return nil
+	}
+
+	decoded := &ContractCall{}
+	if decoded.Call, err = registry.DecodeCalldata(data); err != nil {
+		decoded.Error = err.Error()
+		return decoded
+	}
+	if taskType != TaskTypeETHCall {
+		return decoded
+	}
+
+	if result.Error != nil {
+		return decoded
+	}
+	if value, ok := bytesValue(result.Value); ok {
+		if decoded.Result, err = registry.DecodeResult(data, value); err != nil {
+			decoded.Error = err.Error()
+		}
+	}
+	return decoded
+}
+
+// DecodeRevert decodes why an ethcall reverted from its error, which holds
+// the revert data as hex or, as geth words it, the reason after "execution
+// reverted: ". Custom errors are decoded against the registry, which may be
+// nil. It returns nil for other tasks and for errors that aren't reverts.
+func DecodeRevert(registry *contracts.Registry, taskType TaskType, result *TaskResult) *contracts.Revert {
+	if taskType != TaskTypeETHCall || result.Error == nil {
+		return nil
+	}
+
+	var chainRevert *chain.RevertError
+	if errors.As(result.Error, &chainRevert) {
+		return registry.DecodeRevert(chainRevert.Data)
+	}
+
+	// Other errors, e.g. a mock chain's unknown call, can hold hex too
+	msg := result.Error.Error()
+	if !strings.Contains(msg, "revert") {
+		return nil
+	}
+	if matches := revertData.FindAllString(msg, -1); len(matches) > 0 {
+		data, err := hexutil.Decode(matches[len(matches)-1])
+		if err == nil {
+			return registry.DecodeRevert(data)
+		}
+	}
+
+	revert := registry.DecodeRevert(nil)
+	if _, reason, ok := strings.Cut(msg, "execution reverted: "); ok && reason != "" {
+		revert.Kind, revert.Reason = "error", reason
+	}
+	return revert
+}
+
+// bytesValue converts a resolved data attribute, hex or bytes, to bytes.
+func bytesValue(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return v, true
+	case hexutil.Bytes:
+		return v, true
+	case string:
+		b, err := hexutil.Decode(v)
+		return b, err == nil
+	}
+	return nil, false
+}
@@ -0,0 +1,140 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/contracts"
+)
+
+func TestDecodeRevert(t *testing.T) {
+	// Panic(0x12), as a division by zero reverts
+	panicData := "0x4e487b71" + "0000000000000000000000000000000000000000000000000000000000000012"
+
+	tests := []struct {
+		name       string
+		taskType   TaskType
+		err        error
+		wantNil    bool
+		wantKind   string
+		wantReason string
+	}{
+		{
+			name:     "mock chain revert",
+			taskType: TaskTypeETHCall,
+			err:      fmt.Errorf("call: %w", &chain.RevertError{Data: []byte{0x4e, 0x48, 0x7b, 0x71}}),
+			wantKind: "unknown",
+		},
+		{
+			name:     "revert data in the message",
+			taskType: TaskTypeETHCall,
+			err:      errors.New("execution reverted: " + panicData),
+			wantKind: "panic",
+		},
+		{
+			name:       "geth reason",
+			taskType:   TaskTypeETHCall,
+			err:        errors.New("execution reverted: not enough LINK"),
+			wantKind:   "error",
+			wantReason: "not enough LINK",
+		},
+		{
+			name:     "revert without data",
+			taskType: TaskTypeETHCall,
+			err:      errors.New("execution reverted"),
+			wantKind: "unknown",
+		},
+		{
+			name:     "not a revert",
+			taskType: TaskTypeETHCall,
+			err:      errors.New("connection refused"),
+			wantNil:  true,
+		},
+		{
+			name:     "no error",
+			taskType: TaskTypeETHCall,
+			wantNil:  true,
+		},
+		{
+			name:     "other task",
+			taskType: TaskTypeETHTx,
+			err:      errors.New("execution reverted: " + panicData),
+			wantNil:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			revert := DecodeRevert(nil, test.taskType, &TaskResult{Error: test.err})
+			if test.wantNil {
+				if revert != nil {
+					t.Errorf("%+v, want nil", revert)
+				}
+				return
+			}
+			if revert == nil {
+				t.Fatal("nil")
+			}
+			if revert.Kind != test.wantKind || revert.Reason != test.wantReason {
+				t.Errorf("%s %q, want %s %q", revert.Kind, revert.Reason, test.wantKind, test.wantReason)
+			}
+		})
+	}
+}
+
+func TestDecodeContractCall(t *testing.T) {
+	registry := contracts.NewRegistry()
+	if err := registry.Add("Aggregator", []byte(aggregatorABI)); err != nil {
+		t.Fatal(err)
+	}
+	// latestAnswer()
+	const calldata = "0x50d25bcd"
+
+	tests := []struct {
+		name     string
+		taskType TaskType
+		data     interface{}
+		vars     map[string]interface{}
+		wantNil  bool
+		wantCall string
+	}{
+		{name: "hex", taskType: TaskTypeETHCall, data: calldata, wantCall: "latestAnswer"},
+		{name: "hex with spaces", taskType: TaskTypeETHTx, data: " " + calldata + " ", wantCall: "latestAnswer"},
+		{
+			name:     "var holding bytes",
+			taskType: TaskTypeETHCall,
+			data:     "$(encode)",
+			vars:     map[string]interface{}{"encode": []byte{0x50, 0xd2, 0x5b, 0xcd}},
+			wantCall: "latestAnswer",
+		},
+		{name: "missing var", taskType: TaskTypeETHCall, data: "$(encode)", wantNil: true},
+		{name: "no data", taskType: TaskTypeETHCall, wantNil: true},
+		{name: "not hex", taskType: TaskTypeETHCall, data: "latestAnswer", wantNil: true},
+		{name: "other task", taskType: TaskTypeHTTP, data: calldata, wantNil: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := map[string]interface{}{}
+			if test.data != nil {
+				options["data"] = test.data
+			}
+
+			decoded := DecodeContractCall(registry, test.taskType, options, test.vars, &TaskResult{})
+			if test.wantNil {
+				if decoded != nil {
+					t.Errorf("%+v, want nil", decoded)
+				}
+				return
+			}
+			if decoded == nil || decoded.Call == nil {
+				t.Fatalf("%+v, want a decoded call", decoded)
+			}
+			if decoded.Call.Name != test.wantCall {
+				t.Errorf("call to %s, want %s", decoded.Call.Name, test.wantCall)
+			}
+		})
+	}
+}
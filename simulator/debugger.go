@@ -0,0 +1,61 @@
+package simulator
+
+import (
+	"context"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// Breakpoint pauses a run before a task executes. A breakpoint with only a
+// Task matches that task, one with only a Condition matches before any task
+// once the condition holds, and one with both matches the task only when the
+// condition holds.
+type Breakpoint struct {
+	Task      string `json:"task"`
+	Condition string `json:"condition"`
+}
+
+func (b Breakpoint) Hit(task pipeline.Task, vars map[string]interface{}) (bool, error) {
+	if b.Task != "" && b.Task != task.DotID() {
+		return false, nil
+	}
+	if b.Condition == "" {
+		return b.Task != "", nil
+	}
+	return EvalCondition(b.Condition, vars)
+}
+
+// Continue steps through the run until it is done or a breakpoint is hit
+// before the next task, which the run is then paused at. Breakpoints are
+// checked before the first step too, except at the task the run is already
+// paused at, so that continuing from a breakpoint makes progress. It returns
+// the breakpoint that was hit, if any.
+func (r *Run) Continue(ctx context.Context, breakpoints []Breakpoint) (*Breakpoint, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		next := r.Next()
+		if next == nil {
+			r.PausedAt = ""
+			return nil, nil
+		}
+
+		if next.DotID() != r.PausedAt {
+			for _, b := range breakpoints {
+				hit, err := b.Hit(next, r.Vars)
+				if err != nil {
+					return nil, err
+				}
+				if hit {
+					r.PausedAt = next.DotID()
+					return &b, nil
+				}
+			}
+		}
+
+		r.PausedAt = ""
+		r.Step(ctx)
+	}
+}
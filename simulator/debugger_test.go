@@ -0,0 +1,192 @@
+package simulator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// mustParse parses a spec for a test, failing it on errors.
+func mustParse(t *testing.T, spec string) *pipeline.Pipeline {
+	t.Helper()
+	p, err := Parse(spec)
+	if err != nil {
+		t.Fatalf("parsing the spec: %v", err)
+	}
+	return p
+}
+
+// doneTasks are the dot IDs of the tasks with a result, in pipeline order.
+func doneTasks(r *Run) []string {
+	done := []string{}
+	for _, task := range r.Pipeline.Tasks {
+		if _, ok := r.Results[task.DotID()]; ok {
+			done = append(done, task.DotID())
+		}
+	}
+	return done
+}
+
+const chainSpec = `
+a [type=memo value=1]
+b [type=memo value=2]
+c [type=memo value=3]
+a -> b -> c
+`
+
+func TestContinue(t *testing.T) {
+	tests := []struct {
+		name        string
+		ran         []string
+		pausedAt    string
+		breakpoints []Breakpoint
+		wantHit     string
+		wantDone    []string
+		wantPaused  string
+	}{
+		{
+			name:     "no breakpoints",
+			wantDone: []string{"a", "b", "c"},
+		},
+		{
+			name:        "breakpoint on the first task",
+			breakpoints: []Breakpoint{{Task: "a"}},
+			wantHit:     "a",
+			wantDone:    []string{},
+			wantPaused:  "a",
+		},
+		{
+			name:        "resuming from the first task",
+			pausedAt:    "a",
+			breakpoints: []Breakpoint{{Task: "a"}},
+			wantDone:    []string{"a", "b", "c"},
+		},
+		{
+			name:        "breakpoint downstream",
+			breakpoints: []Breakpoint{{Task: "c"}},
+			wantHit:     "c",
+			wantDone:    []string{"a", "b"},
+			wantPaused:  "c",
+		},
+		{
+			name:        "resuming to the next breakpoint",
+			ran:         []string{"a"},
+			pausedAt:    "b",
+			breakpoints: []Breakpoint{{Task: "b"}, {Task: "c"}},
+			wantHit:     "c",
+			wantDone:    []string{"a", "b"},
+			wantPaused:  "c",
+		},
+		{
+			name:        "condition",
+			breakpoints: []Breakpoint{{Condition: "$(b) == 2"}},
+			wantHit:     "c",
+			wantDone:    []string{"a", "b"},
+			wantPaused:  "c",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run := NewRun(mustParse(t, chainSpec), nil)
+			run.Mocks = map[string]interface{}{"a": 1, "b": 2, "c": 3}
+			for _, id := range test.ran {
+				run.RunTask(context.Background(), run.Pipeline.ByDotID(id), nil)
+			}
+			run.PausedAt = test.pausedAt
+
+			hit, err := run.Continue(context.Background(), test.breakpoints)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var hitTask string
+			if hit != nil {
+				hitTask = hit.Task
+				if hitTask == "" {
+					hitTask = run.Next().DotID()
+				}
+			}
+			if hitTask != test.wantHit {
+				t.Errorf("hit %q, want %q", hitTask, test.wantHit)
+			}
+			if got := doneTasks(run); !reflect.DeepEqual(got, test.wantDone) {
+				t.Errorf("ran %v, want %v", got, test.wantDone)
+			}
+			if run.PausedAt != test.wantPaused {
+				t.Errorf("paused at %q, want %q", run.PausedAt, test.wantPaused)
+			}
+		})
+	}
+}
+
+func TestSetVar(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    map[string]interface{}
+		keypath string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:    "flat",
+			vars:    map[string]interface{}{},
+			keypath: "a",
+			want:    map[string]interface{}{"a": 5},
+		},
+		{
+			name:    "nested",
+			vars:    map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}},
+			keypath: "a.b",
+			want:    map[string]interface{}{"a": map[string]interface{}{"b": 5, "c": 2}},
+		},
+		{
+			name:    "missing maps are created",
+			vars:    map[string]interface{}{},
+			keypath: "jobRun.requestBody.price",
+			want: map[string]interface{}{
+				"jobRun": map[string]interface{}{"requestBody": map[string]interface{}{"price": 5}},
+			},
+		},
+		{
+			name:    "through a value that isn't a map",
+			vars:    map[string]interface{}{"a": "x"},
+			keypath: "a.b",
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			vars:    map[string]interface{}{},
+			keypath: "a..b",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run := NewRun(nil, test.vars)
+			err := run.SetVar(test.keypath, 5)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(run.Vars, test.want) {
+				t.Errorf("vars %v, want %v", run.Vars, test.want)
+			}
+		})
+	}
+}
+
+func TestSetVarTaskResult(t *testing.T) {
+	run := NewRun(nil, map[string]interface{}{"fetch": map[string]interface{}{"price": 1}})
+	run.Results["fetch"] = &TaskRun{Result: pipeline.Result{Value: run.Vars["fetch"]}}
+
+	if err := run.SetVar("fetch.price", 2); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"price": 2}
+	if got := run.Results["fetch"].Result.Value; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetch's result is %v, want %v", got, want)
+	}
+}
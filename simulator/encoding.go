@@ -0,0 +1,75 @@
+package simulator
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// ToBase64 marshals the input using Chainlink's custom marshalling logic and
+// base64 encodes the result, matching the "64" fields exchanged with the
+// frontend.
+func ToBase64(input interface{}) (string, error) {
+	jData, err := MarshalAsJsonSerializable(input)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(jData), nil
+}
+
+// FromBase64 reverses ToBase64. An empty string decodes to nil.
+func FromBase64(s string) (interface{}, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	dec, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	js := pipeline.JSONSerializable{}
+	if err := js.UnmarshalJSON(dec); err != nil {
+		return nil, err
+	}
+	return js.Val, nil
+}
+
+// VarsFromBase64 decodes a vars object as produced by the var-helper.
+func VarsFromBase64(s string) (map[string]interface{}, error) {
+	val, err := FromBase64(s)
+	if err != nil || val == nil {
+		return make(map[string]interface{}), err
+	}
+
+	vars, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("vars must be an object, got %T", val)
+	}
+	return vars, nil
+}
+
+// MocksFromBase64 decodes base64 encoded mock results keyed by dot ID.
+func MocksFromBase64(mocks64 map[string]string) (map[string]interface{}, error) {
+	mocks := make(map[string]interface{}, len(mocks64))
+	for id, mock64 := range mocks64 {
+		mock, err := FromBase64(mock64)
+		if err != nil {
+			return nil, err
+		}
+		mocks[id] = mock
+	}
+	return mocks, nil
+}
+
+// MarshalAsJsonSerializable marshals the input using Chainlink's custom
+// marshalling logic.
+func MarshalAsJsonSerializable(input interface{}) ([]byte, error) {
+	asJsonSerializable := pipeline.JSONSerializable{
+		Valid: true,
+		Val:   input,
+	}
+
+	return asJsonSerializable.MarshalJSON()
+}
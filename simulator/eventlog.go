@@ -0,0 +1,71 @@
+package simulator
+
+import (
+	"strings"
+
+	"github.com/pickleyd/jobspecviz/contracts"
+)
+
+// The jobRun vars a directrequest job receives its log in, which
+// ethabidecodelog reads with data="$(jobRun.logData)" and
+// topics="$(jobRun.logTopics)".
+const (
+	LogDataVar   = "logData"
+	LogTopicsVar = "logTopics"
+)
+
+// EventLog is a log built from an event and typed argument values, for
+// testing ethabidecodelog.
+type EventLog struct {
+	Topics []string `json:"topics"`
+	Data   string   `json:"data"`
+	// JobRun holds the log as jobRun vars for the var-helper, typed as the
+	// frontend types them
+	JobRun map[string]Var `json:"jobRun"`
+	// Options are the ethabidecodelog options that decode the log from the
+	// jobRun vars
+	Options map[string]interface{} `json:"options"`
+}
+
+// BuildEventLog encodes the args, converted as the var-helper converts them,
+// into the topics and data of a log of the event. The event is in the form
+// ethabidecodelog's abi takes, e.g.
+// "Transfer(address indexed from, address indexed to, uint256 value)".
+// Indexed strings, bytes, arrays and tuples are hashed into their topics.
+func BuildEventLog(event string, args []Var) (*EventLog, error) {
+	signature, err := contracts.ParseEvent(event)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if values[i], err = ConvertVar(arg); err != nil {
+			return nil, err
+		}
+	}
+	encoded, err := signature.Encode(values)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := Var{Type: "string", Values: encoded.Topics}
+	if len(encoded.Topics) == 0 {
+		// An anonymous event without indexed arguments
+		topics = Var{Keep: []interface{}{}}
+	}
+
+	return &EventLog{
+		Topics: encoded.Topics,
+		Data:   encoded.Data,
+		JobRun: map[string]Var{
+			LogTopicsVar: topics,
+			LogDataVar:   {Type: "bytes", FromType: "hex", Value: encoded.Data},
+		},
+		Options: map[string]interface{}{
+			"abi":    strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(event), "event ")),
+			"data":   "$(jobRun." + LogDataVar + ")",
+			"topics": "$(jobRun." + LogTopicsVar + ")",
+		},
+	}, nil
+}
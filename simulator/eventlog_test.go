@@ -0,0 +1,126 @@
+package simulator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestBuildEventLog(t *testing.T) {
+	const transfer = "Transfer(address indexed from, address indexed to, uint256 value)"
+	from := "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"
+	to := "0x000000000000000000000000000000000000dEaD"
+
+	tests := []struct {
+		name       string
+		event      string
+		args       []Var
+		wantTopics []string
+		wantData   string
+		wantAbi    string
+		wantErr    bool
+	}{
+		{
+			name:  "transfer",
+			event: transfer,
+			args: []Var{
+				{Value: from, Type: "address"},
+				{Value: to, Type: "address"},
+				{Value: "42", Type: "int"},
+			},
+			wantTopics: []string{
+				"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+				"0x0000000000000000000000005f4ec3df9cbd43714fe2740f5e3616155c5b8419",
+				"0x000000000000000000000000000000000000000000000000000000000000dead",
+			},
+			wantData: "0x000000000000000000000000000000000000000000000000000000000000002a",
+			wantAbi:  transfer,
+		},
+		{
+			name:  "with the event keyword",
+			event: " event Note(string indexed note)",
+			args:  []Var{{Value: "hi", Type: "string"}},
+			wantTopics: []string{
+				hexutil.Encode(crypto.Keccak256([]byte("Note(string)"))),
+				hexutil.Encode(crypto.Keccak256([]byte("hi"))),
+			},
+			wantData: "0x",
+			wantAbi:  "Note(string indexed note)",
+		},
+		{
+			name:       "anonymous without indexed arguments",
+			event:      "Ping(uint256 id) anonymous",
+			args:       []Var{{Value: "1", Type: "int"}},
+			wantTopics: []string{},
+			wantData:   "0x0000000000000000000000000000000000000000000000000000000000000001",
+			wantAbi:    "Ping(uint256 id) anonymous",
+		},
+		{
+			name:    "missing argument",
+			event:   transfer,
+			args:    []Var{{Value: from, Type: "address"}},
+			wantErr: true,
+		},
+		{
+			name:    "not an event",
+			event:   "Transfer(",
+			wantErr: true,
+		},
+		{
+			name:    "unconvertible var",
+			event:   "Ping(uint256 id)",
+			args:    []Var{{Value: "x", Type: "int"}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log, err := BuildEventLog(test.event, test.args)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(log.Topics, test.wantTopics) {
+				t.Errorf("topics %v, want %v", log.Topics, test.wantTopics)
+			}
+			if log.Data != test.wantData {
+				t.Errorf("data %s, want %s", log.Data, test.wantData)
+			}
+			if log.Options["abi"] != test.wantAbi {
+				t.Errorf("abi option %q, want %q", log.Options["abi"], test.wantAbi)
+			}
+
+			// The jobRun vars must load back to the log the task decodes
+			data, err := ConvertVar(log.JobRun[LogDataVar])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hexutil.Encode(data.([]byte)); got != test.wantData {
+				t.Errorf("logData var %s, want %s", got, test.wantData)
+			}
+			topics, err := ConvertVar(log.JobRun[LogTopicsVar])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(test.wantTopics) == 0 {
+				if !reflect.DeepEqual(topics, []interface{}{}) {
+					t.Errorf("logTopics var %#v, want an empty list", topics)
+				}
+			} else if !reflect.DeepEqual(topics, test.wantTopics) {
+				t.Errorf("logTopics var %v, want %v", topics, test.wantTopics)
+			}
+			for _, option := range []string{"data", "topics"} {
+				if ref, _ := log.Options[option].(string); !strings.HasPrefix(ref, "$(jobRun.") {
+					t.Errorf("%s option %q doesn't read a jobRun var", option, ref)
+				}
+			}
+		})
+	}
+}
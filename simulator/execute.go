@@ -0,0 +1,215 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/logger"
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/chain"
+)
+
+var (
+	ErrTaskTimedOut  = errors.New("task timed out")
+	ErrTaskCancelled = errors.New("task cancelled")
+	ErrTaskPanicked  = errors.New("task panicked")
+)
+
+const defaultMaxTaskDuration = 10 * time.Second
+
+// MaxTaskDuration caps how long any single task may run, whatever its own
+// timeout attribute says, so that a hung task fails before the platform kills
+// the function. It can be set with the MAX_TASK_DURATION env var, where a
+// value of 0 removes the cap.
+var MaxTaskDuration = maxTaskDurationFromEnv()
+
+func maxTaskDurationFromEnv() time.Duration {
+	if s := os.Getenv("MAX_TASK_DURATION"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d
+		}
+	}
+	return defaultMaxTaskDuration
+}
+
+// TaskTimeout is the timeout a task runs with: its timeout attribute bounded
+// by MaxTaskDuration. Zero means no timeout.
+func TaskTimeout(task pipeline.Task) time.Duration {
+	timeout := MaxTaskDuration
+	if t, isSet := task.TaskTimeout(); isSet && t > 0 && (timeout <= 0 || t < timeout) {
+		timeout = t
+	}
+	return timeout
+}
+
+// ExecuteOptions tune a simulation without changing the spec.
+type ExecuteOptions struct {
+	// BackoffCompression divides the delays between retries, so that specs
+	// with long backoffs can be tested quickly. 0 and 1 keep the real delays.
+	BackoffCompression float64
+	// Faults are injected into the task, see Fault. Only faults targeting the
+	// task being run should be passed.
+	Faults []Fault
+	// Rand decides whether faults with a rate are injected. Nil uses the
+	// global source.
+	Rand *rand.Rand
+	// Chains answer ethcall and ethtx tasks by their evmChainID, see
+	// chain.Chain. Without chains the tasks run as they would on a node.
+	Chains chain.Chains
+}
+
+// Attempt records one try at running a task.
+type Attempt struct {
+	Error    error
+	Duration time.Duration
+	// Backoff is the delay a node would wait before the next attempt. It is
+	// zero for the final attempt.
+	Backoff time.Duration
+	// Fault is the fault injected into the attempt, if any
+	Fault *Fault
+}
+
+// ExecuteTask runs the task against the vars and inputs. Unlike calling Run
+// directly it honours the task's timeout, returns as soon as the context is
+// cancelled and turns panics into errors. A task that runs out of time fails
+// with ErrTaskTimedOut and one that is cancelled with ErrTaskCancelled, so
+// that callers can tell them apart from the task failing on its own.
+//
+// Like a node, a task that fails with a retryable error is retried up to its
+// retries attribute, backing off exponentially from minBackoff to
+// maxBackoff. Every attempt is returned.
+//
+// Faults in the options are rolled for on every attempt, so a fault with a
+// rate can fail one attempt and let a retry succeed.
+func ExecuteTask(ctx context.Context, task pipeline.Task, vars map[string]interface{}, inputs []pipeline.Result, opts ExecuteOptions) (pipeline.Result, []Attempt) {
+	attempts := []Attempt{}
+	retries := task.TaskRetries()
+
+	for n := uint32(0); ; n++ {
+		fault := pickFault(opts.Faults, opts.Rand)
+
+		started := time.Now()
+		result, runInfo := executeOnce(ctx, task, vars, inputs, fault, opts.Chains)
+
+		attempt := Attempt{
+			Error:    result.Error,
+			Duration: time.Since(started),
+			Fault:    fault,
+		}
+
+		if result.Error == nil || !runInfo.IsRetryable || n >= retries || ctx.Err() != nil {
+			attempts = append(attempts, attempt)
+			return result, attempts
+		}
+
+		attempt.Backoff = Backoff(task, n)
+		attempts = append(attempts, attempt)
+
+		wait := attempt.Backoff
+		if opts.BackoffCompression > 1 {
+			wait = time.Duration(float64(wait) / opts.BackoffCompression)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return pipeline.Result{Error: fmt.Errorf("%w: %v", ErrTaskCancelled, ctx.Err())}, attempts
+		}
+	}
+}
+
+// Backoff is the delay before retrying a task after the given (zero based)
+// failed attempt. It doubles with every attempt, starting at the task's
+// minBackoff and capped at its maxBackoff.
+func Backoff(task pipeline.Task, attempt uint32) time.Duration {
+	minBackoff, maxBackoff := task.TaskMinBackoff(), task.TaskMaxBackoff()
+
+	delay := minBackoff
+	for i := uint32(0); i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if maxBackoff > 0 && delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+func executeOnce(ctx context.Context, task pipeline.Task, vars map[string]interface{}, inputs []pipeline.Result, fault *Fault, chains chain.Chains) (pipeline.Result, pipeline.RunInfo) {
+	timeout := TaskTimeout(task)
+
+	var taskCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		taskCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		taskCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// Each task gets its own copy of the vars, as on a node
+	varsCopy := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		varsCopy[k] = v
+	}
+
+	type outcome struct {
+		result  pipeline.Result
+		runInfo pipeline.RunInfo
+	}
+
+	outcomeCh := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				outcomeCh <- outcome{result: pipeline.Result{Error: fmt.Errorf("%w: %v", ErrTaskPanicked, rec)}}
+			}
+		}()
+
+		if fault != nil {
+			latency, _ := fault.latency()
+			select {
+			case <-time.After(latency):
+			case <-taskCtx.Done():
+				return
+			}
+
+			if result, runInfo, injected := fault.inject(); injected {
+				outcomeCh <- outcome{result, runInfo}
+				return
+			}
+		}
+
+		if result, runInfo, ok := runOnChain(task, varsCopy, chains); ok {
+			outcomeCh <- outcome{result, runInfo}
+			return
+		}
+
+		result, runInfo := task.Run(taskCtx, logger.NullLogger, pipeline.NewVarsFrom(varsCopy), inputs)
+		outcomeCh <- outcome{result, runInfo}
+	}()
+
+	var o outcome
+	select {
+	case o = <-outcomeCh:
+		if o.result.Error == nil {
+			return o.result, o.runInfo
+		}
+	case <-taskCtx.Done():
+		// Tasks that ignore their context are abandoned rather than waited on
+	}
+
+	// Tell a task that failed because it ran out of time, or because the
+	// simulation was cancelled, apart from one that failed on its own
+	switch {
+	case ctx.Err() != nil:
+		return pipeline.Result{Error: fmt.Errorf("%w: %v", ErrTaskCancelled, ctx.Err())}, pipeline.RunInfo{}
+	case taskCtx.Err() == context.DeadlineExceeded:
+		// A node retries tasks that time out
+		return pipeline.Result{Error: fmt.Errorf("%w after %v", ErrTaskTimedOut, timeout)}, pipeline.RunInfo{IsRetryable: true}
+	}
+	return o.result, o.runInfo
+}
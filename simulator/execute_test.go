@@ -0,0 +1,236 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/logger"
+	"github.com/pickleyd/chainlink/core/null"
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// flakyTask fails its first failures runs, with a retryable error unless
+// permanent is set, and then succeeds.
+type flakyTask struct {
+	pipeline.BaseTask
+	failures  int
+	permanent bool
+	runs      int
+}
+
+func (t *flakyTask) Type() pipeline.TaskType { return "flaky" }
+
+func (t *flakyTask) Run(context.Context, logger.Logger, pipeline.Vars, []pipeline.Result) (pipeline.Result, pipeline.RunInfo) {
+	t.runs++
+	if t.runs <= t.failures {
+		return pipeline.Result{Error: errors.New("flaked")}, pipeline.RunInfo{IsRetryable: !t.permanent}
+	}
+	return pipeline.Result{Value: "ok"}, pipeline.RunInfo{}
+}
+
+func newFlakyTask(retries uint32, minBackoff, maxBackoff time.Duration) *flakyTask {
+	task := &flakyTask{BaseTask: pipeline.NewBaseTask(0, "flaky", nil, nil, 0)}
+	task.Retries = null.Uint32From(retries)
+	task.MinBackoff, task.MaxBackoff = minBackoff, maxBackoff
+	return task
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		minBackoff time.Duration
+		maxBackoff time.Duration
+		want       []time.Duration
+	}{
+		{
+			name:       "doubles up to the max",
+			minBackoff: time.Second,
+			maxBackoff: 10 * time.Second,
+			want:       []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second},
+		},
+		{
+			name:       "min equal to max",
+			minBackoff: 5 * time.Second,
+			maxBackoff: 5 * time.Second,
+			want:       []time.Duration{5 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name: "no backoff",
+			want: []time.Duration{0, 0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := newFlakyTask(0, test.minBackoff, test.maxBackoff)
+			for attempt, want := range test.want {
+				if got := Backoff(task, uint32(attempt)); got != want {
+					t.Errorf("attempt %d backs off %v, want %v", attempt, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteTaskRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      uint32
+		failures     int
+		permanent    bool
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "succeeds first time", retries: 3, failures: 0, wantAttempts: 1},
+		{name: "succeeds on a retry", retries: 3, failures: 2, wantAttempts: 3},
+		{name: "runs out of retries", retries: 2, failures: 5, wantAttempts: 3, wantErr: true},
+		{name: "no retries", retries: 0, failures: 1, wantAttempts: 1, wantErr: true},
+		{name: "not retryable", retries: 3, failures: 1, permanent: true, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := newFlakyTask(test.retries, time.Second, 4*time.Second)
+			task.failures, task.permanent = test.failures, test.permanent
+
+			started := time.Now()
+			result, attempts := ExecuteTask(context.Background(), task, nil, nil, ExecuteOptions{BackoffCompression: 1000})
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Errorf("took %v despite the backoff compression", elapsed)
+			}
+
+			if (result.Error != nil) != test.wantErr {
+				t.Errorf("error %v, want an error: %v", result.Error, test.wantErr)
+			}
+			if len(attempts) != test.wantAttempts {
+				t.Fatalf("%d attempts, want %d", len(attempts), test.wantAttempts)
+			}
+			for i, attempt := range attempts[:len(attempts)-1] {
+				if want := Backoff(task, uint32(i)); attempt.Backoff != want {
+					t.Errorf("attempt %d backs off %v, want %v", i, attempt.Backoff, want)
+				}
+			}
+			if last := attempts[len(attempts)-1]; last.Backoff != 0 {
+				t.Errorf("the last attempt backs off %v", last.Backoff)
+			}
+		})
+	}
+}
+
+func TestExecuteTaskCancelledDuringBackoff(t *testing.T) {
+	task := newFlakyTask(3, time.Hour, time.Hour)
+	task.failures = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result, attempts := ExecuteTask(ctx, task, nil, nil, ExecuteOptions{})
+	if !errors.Is(result.Error, ErrTaskCancelled) {
+		t.Errorf("error %v, want ErrTaskCancelled", result.Error)
+	}
+	if len(attempts) != 1 {
+		t.Errorf("%d attempts, want 1", len(attempts))
+	}
+}
+
+// blockingTask runs until its context is done, or forever if it ignores it,
+// or panics.
+type blockingTask struct {
+	pipeline.BaseTask
+	ignoresContext bool
+	panics         bool
+}
+
+func (t *blockingTask) Type() pipeline.TaskType { return "blocking" }
+
+func (t *blockingTask) Run(ctx context.Context, _ logger.Logger, _ pipeline.Vars, _ []pipeline.Result) (pipeline.Result, pipeline.RunInfo) {
+	if t.panics {
+		panic("boom")
+	}
+	if t.ignoresContext {
+		select {}
+	}
+	<-ctx.Done()
+	return pipeline.Result{Error: ctx.Err()}, pipeline.RunInfo{}
+}
+
+func TestTaskTimeout(t *testing.T) {
+	defer func(max time.Duration) { MaxTaskDuration = max }(MaxTaskDuration)
+
+	tests := []struct {
+		name    string
+		max     time.Duration
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "no timeout", max: 10 * time.Second, want: 10 * time.Second},
+		{name: "shorter timeout", max: 10 * time.Second, timeout: time.Second, want: time.Second},
+		{name: "longer timeout is capped", max: 10 * time.Second, timeout: time.Minute, want: 10 * time.Second},
+		{name: "no cap", timeout: time.Minute, want: time.Minute},
+		{name: "neither"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			MaxTaskDuration = test.max
+			task := newFlakyTask(0, 0, 0)
+			if test.timeout > 0 {
+				task.Timeout = &test.timeout
+			}
+			if got := TaskTimeout(task); got != test.want {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExecuteTaskTimeouts(t *testing.T) {
+	tests := []struct {
+		name           string
+		ignoresContext bool
+		panics         bool
+		cancel         bool
+		wantErr        error
+	}{
+		{name: "times out", wantErr: ErrTaskTimedOut},
+		{name: "ignores its context", ignoresContext: true, wantErr: ErrTaskTimedOut},
+		{name: "cancelled", cancel: true, wantErr: ErrTaskCancelled},
+		{name: "cancelled while ignoring its context", ignoresContext: true, cancel: true, wantErr: ErrTaskCancelled},
+		{name: "panics", panics: true, wantErr: ErrTaskPanicked},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := &blockingTask{
+				BaseTask:       pipeline.NewBaseTask(0, "blocking", nil, nil, 0),
+				ignoresContext: test.ignoresContext,
+				panics:         test.panics,
+			}
+			timeout := 20 * time.Millisecond
+			if test.cancel {
+				timeout = time.Hour
+			}
+			task.Timeout = &timeout
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancel {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+
+			started := time.Now()
+			result, attempts := ExecuteTask(ctx, task, nil, nil, ExecuteOptions{})
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Errorf("took %v", elapsed)
+			}
+			if !errors.Is(result.Error, test.wantErr) {
+				t.Errorf("error %v, want %v", result.Error, test.wantErr)
+			}
+			if len(attempts) != 1 {
+				t.Errorf("%d attempts, want 1", len(attempts))
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// Fault is a failure injected into a task to rehearse how a spec copes with
+// it. Unlike a mock, which only replaces a task's value, a fault can make a
+// task fail, slow it down or make it lose its output altogether.
+type Fault struct {
+	// Task is the dot ID of the task to inject the fault into. An empty Task
+	// matches every task, which together with Rate gives randomised faults
+	// across the whole pipeline. It is ignored for single task runs.
+	Task string
+	// Error makes the task fail with this message instead of running
+	Error string
+	// Retryable marks the injected error as one a node would retry
+	Retryable bool
+	// Latency delays the task, e.g. "2s". It counts towards the task's
+	// timeout, so a long enough latency makes the task time out.
+	Latency string
+	// Value replaces the task's result without running it, for example with
+	// a malformed value that downstream tasks have to cope with
+	Value interface{}
+	// Drop makes the task's output disappear, so downstream tasks don't
+	// receive it as an input
+	Drop bool
+	// Rate is the probability the fault is injected on each attempt at
+	// running the task. 0 means always.
+	Rate float64
+}
+
+func (f Fault) Validate() error {
+	if f.Rate < 0 || f.Rate > 1 {
+		return fmt.Errorf("fault rate must be between 0 and 1, got %v", f.Rate)
+	}
+	if _, err := f.latency(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (f Fault) latency() (time.Duration, error) {
+	if f.Latency == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(f.Latency)
+	if err != nil {
+		return 0, fmt.Errorf("fault latency: %w", err)
+	}
+	return d, nil
+}
+
+// Matches reports whether the fault targets the task with the given dot ID.
+func (f Fault) Matches(dotID string) bool {
+	return f.Task == "" || f.Task == dotID
+}
+
+// pickFault rolls for each fault in turn and returns the first to trigger.
+func pickFault(faults []Fault, rng *rand.Rand) *Fault {
+	for i, fault := range faults {
+		if fault.Rate == 0 || roll(rng) < fault.Rate {
+			return &faults[i]
+		}
+	}
+	return nil
+}
+
+func roll(rng *rand.Rand) float64 {
+	if rng == nil {
+		return rand.Float64()
+	}
+	return rng.Float64()
+}
+
+// inject returns the result the fault replaces the task's with. It returns
+// false if the fault doesn't replace the result, in which case the task runs
+// as normal.
+func (f *Fault) inject() (pipeline.Result, pipeline.RunInfo, bool) {
+	switch {
+	case f.Error != "":
+		return pipeline.Result{Error: errors.New(f.Error)}, pipeline.RunInfo{IsRetryable: f.Retryable}, true
+	case f.Value != nil:
+		return pipeline.Result{Value: f.Value}, pipeline.RunInfo{}, true
+	}
+	return pipeline.Result{}, pipeline.RunInfo{}, false
+}
+
+// SetFaults validates the faults and arms them for the run. The seed makes
+// randomised faults reproducible, with 0 picking a random one.
+func (r *Run) SetFaults(faults []Fault, seed int64) error {
+	for _, fault := range faults {
+		if err := fault.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	r.Faults = faults
+	r.Options.Rand = rand.New(rand.NewSource(seed))
+	return nil
+}
+
+func (r *Run) faultsFor(task pipeline.Task) []Fault {
+	matching := []Fault{}
+	for _, fault := range r.Faults {
+		if fault.Matches(task.DotID()) {
+			matching = append(matching, fault)
+		}
+	}
+	return matching
+}
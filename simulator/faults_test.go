@@ -0,0 +1,173 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFaultValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fault   Fault
+		wantErr bool
+	}{
+		{name: "error", fault: Fault{Error: "boom"}},
+		{name: "rate", fault: Fault{Error: "boom", Rate: 0.5}},
+		{name: "latency", fault: Fault{Latency: "2s"}},
+		{name: "negative rate", fault: Fault{Rate: -0.1}, wantErr: true},
+		{name: "rate above 1", fault: Fault{Rate: 1.5}, wantErr: true},
+		{name: "bad latency", fault: Fault{Latency: "soon"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.fault.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("error %v, want an error: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteTaskFaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		fault        Fault
+		retries      uint32
+		timeout      time.Duration
+		wantValue    interface{}
+		wantErr      string
+		wantTimedOut bool
+		wantAttempts int
+	}{
+		{
+			name:         "error",
+			fault:        Fault{Error: "boom"},
+			retries:      3,
+			wantErr:      "boom",
+			wantAttempts: 1,
+		},
+		{
+			name:         "retryable error",
+			fault:        Fault{Error: "boom", Retryable: true},
+			retries:      2,
+			wantErr:      "boom",
+			wantAttempts: 3,
+		},
+		{
+			name:         "value",
+			fault:        Fault{Value: "malformed"},
+			wantValue:    "malformed",
+			wantAttempts: 1,
+		},
+		{
+			name:         "latency only",
+			fault:        Fault{Latency: "1ms"},
+			wantValue:    "ok",
+			wantAttempts: 1,
+		},
+		{
+			name:         "latency past the timeout",
+			fault:        Fault{Latency: "1h"},
+			timeout:      10 * time.Millisecond,
+			wantTimedOut: true,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			task := newFlakyTask(test.retries, time.Millisecond, time.Millisecond)
+			if test.timeout > 0 {
+				task.Timeout = &test.timeout
+			}
+
+			result, attempts := ExecuteTask(context.Background(), task, nil, nil, ExecuteOptions{
+				Faults: []Fault{test.fault},
+			})
+
+			switch {
+			case test.wantTimedOut:
+				if !errors.Is(result.Error, ErrTaskTimedOut) {
+					t.Errorf("error %v, want ErrTaskTimedOut", result.Error)
+				}
+			case test.wantErr != "":
+				if result.Error == nil || result.Error.Error() != test.wantErr {
+					t.Errorf("error %v, want %v", result.Error, test.wantErr)
+				}
+			case result.Error != nil:
+				t.Errorf("error %v", result.Error)
+			case result.Value != test.wantValue:
+				t.Errorf("value %v, want %v", result.Value, test.wantValue)
+			}
+			if len(attempts) != test.wantAttempts {
+				t.Fatalf("%d attempts, want %d", len(attempts), test.wantAttempts)
+			}
+			for i, attempt := range attempts {
+				if attempt.Fault == nil {
+					t.Errorf("attempt %d has no fault", i)
+				}
+			}
+			if task.runs != 0 && (test.fault.Error != "" || test.fault.Value != nil) {
+				t.Errorf("the task ran %d times despite the fault replacing it", task.runs)
+			}
+		})
+	}
+}
+
+// TestFaultRateSeed checks that a seed reproduces which attempts a fault
+// with a rate hits.
+func TestFaultRateSeed(t *testing.T) {
+	hits := func(seed int64) []bool {
+		rng := rand.New(rand.NewSource(seed))
+		faults := []Fault{{Error: "boom", Rate: 0.5}}
+		hit := make([]bool, 20)
+		for i := range hit {
+			hit[i] = pickFault(faults, rng) != nil
+		}
+		return hit
+	}
+
+	first, second := hits(42), hits(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("the same seed hit %v and then %v", first, second)
+	}
+
+	some, all := false, true
+	for _, hit := range first {
+		some = some || hit
+		all = all && hit
+	}
+	if !some || all {
+		t.Errorf("a rate of 0.5 hit %v", first)
+	}
+}
+
+func TestDropFault(t *testing.T) {
+	run := NewRun(mustParse(t, `
+		a [type=multiply input="2" times="3"]
+		b [type=multiply input="4" times="1"]
+		m [type=median]
+		a -> m
+		b -> m
+	`), nil)
+	if err := run.SetFaults([]Fault{{Task: "a", Drop: true}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := run.Execute(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !run.Results["a"].Dropped {
+		t.Error("a's output wasn't dropped")
+	}
+	if _, ok := run.Vars["a"]; ok {
+		t.Error("a's output is still a var")
+	}
+	if inputs := run.Results["m"].Inputs; len(inputs) != 1 {
+		t.Errorf("m received %d inputs, want only b's", len(inputs))
+	}
+}
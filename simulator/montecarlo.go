@@ -0,0 +1,342 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultTrials = 1000
+	MaxTrials     = 10000
+
+	defaultOutlierScale = 0.5
+)
+
+// SourceModel describes how the value of a source task varies between
+// trials. The source is usually the parse task feeding an aggregating task.
+type SourceModel struct {
+	Task string
+	// Value is the true value of the source
+	Value string
+	// Noise is the standard deviation of the source relative to its value,
+	// e.g. 0.01 for 1%
+	Noise float64
+	// OutlierRate is the probability the source reports an outlier, which is
+	// off from the value by OutlierScale (0.5, i.e. 50%, if not set)
+	OutlierRate  float64
+	OutlierScale float64
+	// FailureRate is the probability the source fails altogether
+	FailureRate float64
+}
+
+type AnalysisConfig struct {
+	Sources []SourceModel
+	// Target is the dot ID of the task whose value is the final answer. It
+	// defaults to the first median, mean or mode task.
+	Target string
+	Trials int
+	// Seed makes the analysis reproducible, with 0 picking a random one
+	Seed int64
+}
+
+// Distribution summarises the final answers of the successful trials.
+type Distribution struct {
+	Count       int                `json:"count"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Mean        float64            `json:"mean"`
+	StdDev      float64            `json:"stdDev"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// SourceInfluence relates a source to the deviations of the final answer.
+type SourceInfluence struct {
+	Task          string `json:"task"`
+	OutlierTrials int    `json:"outlierTrials"`
+	FailedTrials  int    `json:"failedTrials"`
+	// Correlation is the Pearson correlation between the source's value and
+	// the final answer across the trials where both have a value
+	Correlation float64 `json:"correlation"`
+}
+
+type Analysis struct {
+	Target    string `json:"target"`
+	Seed      int64  `json:"seed"`
+	Trials    int    `json:"trials"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	// FaultsExceeded counts the trials that failed because the target had
+	// more errored values than allowedFaults permits
+	FaultsExceeded int               `json:"faultsExceeded"`
+	Distribution   Distribution      `json:"distribution"`
+	Sources        []SourceInfluence `json:"sources"`
+	// Driver is the source most correlated with the final answer
+	Driver string `json:"driver"`
+	// Errors counts the distinct errors that failed trials, by message
+	Errors map[string]int `json:"errors"`
+}
+
+// Analyze runs the pipeline many times over, drawing the values of the
+// modelled sources from their distributions, to show how robust the final
+// answer is. Only the sources are mocked: the tasks upstream of them still
+// run, so those that reach the network should be mocked too. A trial fails
+// if the target has no numeric answer or the run as a whole fails, as it
+// would on a node, e.g. on a failEarly task or another terminal task failing.
+//
+// Every trial runs with the options, e.g. BackoffCompression so that retries
+// don't really sleep in each trial. Faults with a rate are rolled for with
+// the analysis' seed rather than FaultSeed, so that the trials differ.
+func Analyze(ctx context.Context, p *pipeline.Pipeline, vars map[string]interface{}, mocks map[string]interface{}, config AnalysisConfig, opts RunOptions) (*Analysis, error) {
+	if config.Trials <= 0 {
+		config.Trials = DefaultTrials
+	}
+	if config.Trials > MaxTrials {
+		return nil, fmt.Errorf("at most %d trials are allowed, got %d", MaxTrials, config.Trials)
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	target, err := analysisTarget(p, config.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]pipeline.Task, len(config.Sources))
+	centres := make([]float64, len(config.Sources))
+	for i, source := range config.Sources {
+		if sources[i] = taskByDotID(p, source.Task); sources[i] == nil {
+			return nil, fmt.Errorf(`unknown source task: "%v"`, source.Task)
+		}
+		centre, err := decimal.NewFromString(source.Value)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", source.Task, err)
+		}
+		centres[i], _ = centre.Float64()
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+
+	analysis := &Analysis{
+		Target: target.DotID(),
+		Seed:   config.Seed,
+		Trials: config.Trials,
+		Errors: map[string]int{},
+	}
+
+	answers := []float64{}
+	drawn := make([][]float64, len(sources))
+	paired := make([][]float64, len(sources))
+	influence := make([]SourceInfluence, len(sources))
+	for i, source := range config.Sources {
+		influence[i].Task = source.Task
+	}
+
+	for trial := 0; trial < config.Trials; trial++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		trialOpts := opts
+		trialOpts.Faults = append([]Fault{}, opts.Faults...)
+		if len(opts.Faults) > 0 {
+			trialOpts.FaultSeed = rng.Int63n(math.MaxInt64) + 1
+		}
+		run, err := PrepareRun(p, copyVars(vars), mocks, trialOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]float64, len(sources))
+		failed := make([]bool, len(sources))
+		for i, model := range config.Sources {
+			if rng.Float64() < model.FailureRate {
+				failed[i] = true
+				influence[i].FailedTrials++
+				delete(run.Mocks, model.Task)
+				run.Faults = append(run.Faults, Fault{Task: model.Task, Error: "source failed"})
+				continue
+			}
+
+			value := centres[i] * (1 + model.Noise*rng.NormFloat64())
+			if rng.Float64() < model.OutlierRate {
+				influence[i].OutlierTrials++
+				scale := model.OutlierScale
+				if scale == 0 {
+					scale = defaultOutlierScale
+				}
+				if rng.Intn(2) == 0 {
+					scale = -scale
+				}
+				value += centres[i] * scale
+			}
+
+			values[i] = value
+			run.Mocks[model.Task] = decimal.NewFromFloat(value)
+		}
+
+		if err := run.Execute(ctx); err != nil {
+			return nil, err
+		}
+
+		answer, err := numericResult(run, target)
+		if summary := run.Summarize(); !summary.Succeeded && (err == nil || run.FailedEarly != "") {
+			// The run failed elsewhere, or before the target could run
+			err = errors.New(summary.Reason)
+		}
+		if err != nil {
+			analysis.Failed++
+			analysis.Errors[err.Error()]++
+			if outcome := run.outcome(target); outcome.Faults != nil && outcome.Faults.Exceeded {
+				analysis.FaultsExceeded++
+			}
+			continue
+		}
+
+		analysis.Succeeded++
+		answers = append(answers, answer)
+		for i := range sources {
+			if !failed[i] {
+				drawn[i] = append(drawn[i], values[i])
+				paired[i] = append(paired[i], answer)
+			}
+		}
+	}
+
+	analysis.Distribution = distribution(answers)
+
+	strongest := 0.0
+	for i := range influence {
+		influence[i].Correlation = correlation(drawn[i], paired[i])
+		if math.Abs(influence[i].Correlation) > strongest {
+			strongest = math.Abs(influence[i].Correlation)
+			analysis.Driver = influence[i].Task
+		}
+	}
+	analysis.Sources = influence
+
+	return analysis, nil
+}
+
+func analysisTarget(p *pipeline.Pipeline, dotID string) (pipeline.Task, error) {
+	if dotID != "" {
+		if task := taskByDotID(p, dotID); task != nil {
+			return task, nil
+		}
+		return nil, fmt.Errorf(`unknown target task: "%v"`, dotID)
+	}
+
+	for _, task := range p.Tasks {
+		switch task.(type) {
+		case *pipeline.MedianTask, *pipeline.MeanTask, *pipeline.ModeTask:
+			return task, nil
+		}
+	}
+	return nil, fmt.Errorf("the pipeline has no median, mean or mode task, set a target")
+}
+
+// MarkUpstream marks the dot ID of every task the task depends on, directly
+// or through other tasks.
+func MarkUpstream(task pipeline.Task, upstream map[string]bool) {
+	for _, dep := range task.Inputs() {
+		if !upstream[dep.InputTask.DotID()] {
+			upstream[dep.InputTask.DotID()] = true
+			MarkUpstream(dep.InputTask, upstream)
+		}
+	}
+}
+
+func numericResult(run *Run, task pipeline.Task) (float64, error) {
+	taskRun, ok := run.Results[task.DotID()]
+	if !ok {
+		return 0, fmt.Errorf("task %s did not run", task.DotID())
+	}
+	if taskRun.Result.Error != nil {
+		return 0, taskRun.Result.Error
+	}
+
+	value := taskRun.Result.Value
+	// mode returns every value that occurs most often
+	if values, ok := value.([]interface{}); ok && len(values) > 0 {
+		value = values[0]
+	}
+
+	d, err := decimal.NewFromString(fmt.Sprintf("%v", value))
+	if err != nil {
+		return 0, fmt.Errorf("task %s returned a non-numeric value", task.DotID())
+	}
+	f, _ := d.Float64()
+	return f, nil
+}
+
+func distribution(values []float64) Distribution {
+	dist := Distribution{
+		Count:       len(values),
+		Percentiles: map[string]float64{},
+	}
+	if len(values) == 0 {
+		return dist
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	dist.Min, dist.Max = sorted[0], sorted[len(sorted)-1]
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	dist.Mean = sum / float64(len(sorted))
+
+	variance := 0.0
+	for _, v := range sorted {
+		variance += (v - dist.Mean) * (v - dist.Mean)
+	}
+	dist.StdDev = math.Sqrt(variance / float64(len(sorted)))
+
+	for _, p := range []int{1, 5, 25, 50, 75, 95, 99} {
+		// nearest rank
+		rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		dist.Percentiles[fmt.Sprintf("p%d", p)] = sorted[rank-1]
+	}
+
+	return dist
+}
+
+func correlation(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0
+	}
+
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
@@ -0,0 +1,132 @@
+package simulator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const analysisSpec = `
+a [type=multiply input="1" times="1"]
+b [type=multiply input="1" times="1"]
+c [type=multiply input="1" times="1" retries=2 minBackoff="1s" maxBackoff="1s"]
+m [type=median allowedFaults=1]
+a -> m
+b -> m
+c -> m
+`
+
+func TestAnalyze(t *testing.T) {
+	sources := []SourceModel{
+		{Task: "a", Value: "100", Noise: 0.01},
+		{Task: "b", Value: "100", Noise: 0.01},
+	}
+
+	tests := []struct {
+		name string
+		// spec defaults to analysisSpec
+		spec               string
+		config             AnalysisConfig
+		opts               RunOptions
+		wantSucceeded      int
+		wantFailed         int
+		wantFaultsExceeded int
+	}{
+		{
+			name:          "noise only",
+			config:        AnalysisConfig{Sources: sources, Trials: 20, Seed: 7},
+			wantSucceeded: 20,
+		},
+		{
+			name:   "retries are compressed in every trial",
+			config: AnalysisConfig{Sources: sources, Trials: 20, Seed: 7},
+			opts: RunOptions{
+				BackoffCompression: 1000,
+				Faults:             []Fault{{Task: "c", Error: "down", Retryable: true}},
+			},
+			wantSucceeded: 20,
+		},
+		{
+			name: "failing sources exceed allowedFaults",
+			config: AnalysisConfig{
+				Sources: []SourceModel{
+					{Task: "a", Value: "100", FailureRate: 1},
+					{Task: "b", Value: "100", FailureRate: 1},
+				},
+				Trials: 10,
+				Seed:   7,
+			},
+			wantFailed:         10,
+			wantFaultsExceeded: 10,
+		},
+		{
+			name: "tasks upstream of a source still run",
+			spec: analysisSpec + `
+fetch [type=multiply input="1" times="1"]
+fetch -> a
+`,
+			config:        AnalysisConfig{Sources: sources, Trials: 10, Seed: 7},
+			opts:          RunOptions{Faults: []Fault{{Task: "fetch", Error: "down"}}},
+			wantSucceeded: 10,
+		},
+		{
+			name: "a failEarly task upstream of a source fails the run",
+			spec: analysisSpec + `
+fetch [type=multiply input="1" times="1" failEarly=true]
+fetch -> a
+`,
+			config:     AnalysisConfig{Sources: sources, Trials: 10, Seed: 7},
+			opts:       RunOptions{Faults: []Fault{{Task: "fetch", Error: "down"}}},
+			wantFailed: 10,
+		},
+		{
+			name: "another terminal task failing fails the run",
+			spec: analysisSpec + `
+submit [type=multiply input="1" times="1"]
+m -> submit
+`,
+			config:     AnalysisConfig{Sources: sources, Trials: 10, Seed: 7},
+			opts:       RunOptions{Faults: []Fault{{Task: "submit", Error: "down"}}},
+			wantFailed: 10,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := test.spec
+			if spec == "" {
+				spec = analysisSpec
+			}
+			p := mustParse(t, spec)
+
+			started := time.Now()
+			analysis, err := Analyze(context.Background(), p, nil, nil, test.config, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Errorf("took %v", elapsed)
+			}
+
+			if analysis.Target != "m" {
+				t.Errorf("target %q, want m", analysis.Target)
+			}
+			if analysis.Succeeded != test.wantSucceeded || analysis.Failed != test.wantFailed {
+				t.Errorf("%d succeeded and %d failed, want %d and %d: %v",
+					analysis.Succeeded, analysis.Failed, test.wantSucceeded, test.wantFailed, analysis.Errors)
+			}
+			if analysis.FaultsExceeded != test.wantFaultsExceeded {
+				t.Errorf("%d trials exceeded allowedFaults, want %d", analysis.FaultsExceeded, test.wantFaultsExceeded)
+			}
+
+			again, err := Analyze(context.Background(), p, nil, nil, test.config, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again.Distribution, analysis.Distribution) {
+				t.Errorf("the same seed gave %+v and then %+v", analysis.Distribution, again.Distribution)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	WarningRounding     = "rounding"
+	WarningTruncation   = "truncation"
+	WarningOverflow     = "overflow"
+	WarningNegative     = "negative-unsigned"
+	WarningFloatPrecise = "float-precision"
+	WarningNonFinite    = "non-finite"
+)
+
+// PrecisionWarning flags a place where a numeric value quietly changes or
+// won't fit where it is going.
+type PrecisionWarning struct {
+	Kind    string `json:"kind"`
+	Arg     string `json:"arg,omitempty"`
+	Message string `json:"message"`
+}
+
+// PrecisionTrace describes the numeric value a task produced.
+type PrecisionTrace struct {
+	Id    string `json:"id"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	// Scale is the number of digits after the decimal point
+	Scale int32 `json:"scale"`
+	// Digits is the number of significant digits
+	Digits   int                `json:"digits"`
+	Warnings []PrecisionWarning `json:"warnings"`
+}
+
+// maxSafeFloatInteger is the largest integer a float64 holds exactly, 2^53.
+const maxSafeFloatInteger = 1 << 53
+
+// AnalyzePrecision traces the numeric values through a run that has been
+// executed, using the actual values the tasks produced. It warns when divide
+// or mean round away digits, when a float64 is too large to be exact, and when
+// a value passed to an integer ABI argument of ethabiencode would be
+// truncated, is out of range or is negative for an unsigned type. Tasks that
+// produced no numeric value and raised no warning are left out.
+func (r *Run) AnalyzePrecision() []PrecisionTrace {
+	traces := []PrecisionTrace{}
+
+	for _, task := range r.Pipeline.Tasks {
+		taskRun, ok := r.Results[task.DotID()]
+		if !ok || taskRun.Result.Error != nil {
+			continue
+		}
+
+		trace := PrecisionTrace{
+			Id:       task.DotID(),
+			Type:     task.Type().String(),
+			Warnings: []PrecisionWarning{},
+		}
+
+		value, numeric := toDecimal(taskRun.Result.Value)
+		if f, ok := floatValue(taskRun.Result.Value); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			trace.Value = fmt.Sprintf("%v", f)
+			trace.Warnings = append(trace.Warnings, PrecisionWarning{
+				Kind:    WarningNonFinite,
+				Message: fmt.Sprintf("%v is not a finite number, e.g. from a division by zero, and can't be encoded or compared", f),
+			})
+		}
+		if numeric {
+			trace.Value = value.String()
+			trace.Scale = scale(value)
+			trace.Digits = len(strings.TrimLeft(value.Coefficient().String(), "-"))
+			if f, isFloat := taskRun.Result.Value.(float64); isFloat && math.Abs(f) > maxSafeFloatInteger {
+				trace.Warnings = append(trace.Warnings, PrecisionWarning{
+					Kind:    WarningFloatPrecise,
+					Message: fmt.Sprintf("%v is a float64 above 2^53, so it may not be exact", f),
+				})
+			}
+		}
+
+		switch t := task.(type) {
+		case *pipeline.DivideTask:
+			trace.Warnings = append(trace.Warnings, r.divideWarnings(t, taskRun)...)
+		case *pipeline.MeanTask:
+			if numeric {
+				trace.Warnings = append(trace.Warnings, r.meanWarnings(t, taskRun, value)...)
+			}
+		case *pipeline.ETHABIEncodeTask:
+			trace.Warnings = append(trace.Warnings, r.abiWarnings(t)...)
+		}
+
+		if numeric || len(trace.Warnings) > 0 {
+			traces = append(traces, trace)
+		}
+	}
+
+	return traces
+}
+
+func (r *Run) divideWarnings(task *pipeline.DivideTask, taskRun *TaskRun) []PrecisionWarning {
+	result, ok := toDecimal(taskRun.Result.Value)
+	if !ok {
+		return nil
+	}
+
+	// Like the task, divide the input attribute if it is set, and otherwise
+	// the first input
+	input, err := resolveAttr(task.Input, r.Vars)
+	if err != nil {
+		return nil
+	}
+	if input == nil {
+		inputs := taskRun.Inputs
+		if inputs == nil {
+			inputs = r.Inputs(task)
+		}
+		if len(inputs) == 0 {
+			return nil
+		}
+		input = inputs[0].Value
+	}
+	dividend, ok := toDecimal(input)
+	if !ok {
+		return nil
+	}
+	divisor, ok := r.resolveDecimal(task.Divisor)
+	if !ok || divisor.IsZero() {
+		return nil
+	}
+
+	exact := dividend.DivRound(divisor, 64)
+	if exact.Equal(result) {
+		return nil
+	}
+	return []PrecisionWarning{{
+		Kind:    WarningRounding,
+		Message: fmt.Sprintf("%v / %v was rounded to %v, losing %v", dividend, divisor, result, exact.Sub(result)),
+	}}
+}
+
+func (r *Run) meanWarnings(task *pipeline.MeanTask, taskRun *TaskRun, result decimal.Decimal) []PrecisionWarning {
+	inputs := taskRun.Inputs
+	if inputs == nil {
+		inputs = r.Inputs(task)
+	}
+	// Only the simple case of the values coming from the inputs is checked
+	if task.Values != "" || len(inputs) == 0 {
+		return nil
+	}
+
+	sum := decimal.Zero
+	count := 0
+	for _, input := range inputs {
+		if input.Error != nil {
+			continue
+		}
+		value, ok := toDecimal(input.Value)
+		if !ok {
+			return nil
+		}
+		sum = sum.Add(value)
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+
+	exact := sum.DivRound(decimal.NewFromInt(int64(count)), 64)
+	if exact.Equal(result) {
+		return nil
+	}
+	return []PrecisionWarning{{
+		Kind:    WarningRounding,
+		Message: fmt.Sprintf("the mean %v was rounded to %v, losing %v", exact, result, exact.Sub(result)),
+	}}
+}
+
+func (r *Run) abiWarnings(task *pipeline.ETHABIEncodeTask) []PrecisionWarning {
+	signature, err := contracts.ParseSignature(task.ABI)
+	if err != nil {
+		return nil
+	}
+	// The data is resolved as the task resolves it, so a reference to a var
+	// that doesn't exist leaves nothing to check
+	var data pipeline.MapParam
+	vars := pipeline.NewVarsFrom(r.Vars)
+	if err := pipeline.ResolveParam(&data, pipeline.From(
+		pipeline.VarExpr(task.Data, vars),
+		pipeline.JSONWithVarExprs(task.Data, vars, false),
+	)); err != nil {
+		return nil
+	}
+
+	warnings := []PrecisionWarning{}
+	for _, arg := range signature.Inputs {
+		if arg.Type.T != abi.IntTy && arg.Type.T != abi.UintTy {
+			continue
+		}
+		value, ok := toDecimal(data[arg.Name])
+		if !ok {
+			continue
+		}
+
+		unsigned := arg.Type.T == abi.UintTy
+		bits := arg.Type.Size
+
+		if !value.Equal(value.Truncate(0)) {
+			warnings = append(warnings, PrecisionWarning{
+				Kind:    WarningTruncation,
+				Arg:     arg.Name,
+				Message: fmt.Sprintf("%v is encoded as %s, dropping its fractional part to give %v", value, arg.Type.String(), value.Truncate(0)),
+			})
+		}
+
+		if unsigned && value.IsNegative() {
+			warnings = append(warnings, PrecisionWarning{
+				Kind:    WarningNegative,
+				Arg:     arg.Name,
+				Message: fmt.Sprintf("%v is negative but %s is unsigned", value, arg.Type.String()),
+			})
+			continue
+		}
+
+		min, max := integerRange(bits, unsigned)
+		integer := value.Truncate(0).BigInt()
+		if integer.Cmp(min) < 0 || integer.Cmp(max) > 0 {
+			warnings = append(warnings, PrecisionWarning{
+				Kind:    WarningOverflow,
+				Arg:     arg.Name,
+				Message: fmt.Sprintf("%v is out of range for %s, which holds %v to %v", value, arg.Type.String(), min, max),
+			})
+		}
+	}
+	return warnings
+}
+
+func integerRange(bits int, unsigned bool) (*big.Int, *big.Int) {
+	one := big.NewInt(1)
+	if unsigned {
+		max := new(big.Int).Lsh(one, uint(bits))
+		return big.NewInt(0), max.Sub(max, one)
+	}
+	max := new(big.Int).Lsh(one, uint(bits-1))
+	min := new(big.Int).Neg(max)
+	return min, max.Sub(max, one)
+}
+
+// resolveDecimal resolves an attribute that is either a literal number or a
+// single var reference to a number.
+func (r *Run) resolveDecimal(attr string) (decimal.Decimal, bool) {
+	value, err := resolveAttr(attr, r.Vars)
+	if err != nil {
+		return decimal.Decimal{}, false
+	}
+	return toDecimal(value)
+}
+
+// floatValue returns float values as a float64.
+func floatValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func toDecimal(value interface{}) (decimal.Decimal, bool) {
+	switch v := value.(type) {
+	case decimal.Decimal:
+		return v, true
+	case *decimal.Decimal:
+		if v == nil {
+			return decimal.Decimal{}, false
+		}
+		return *v, true
+	case *big.Int:
+		if v == nil {
+			return decimal.Decimal{}, false
+		}
+		return decimal.NewFromBigInt(v, 0), true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return decimal.Decimal{}, false
+		}
+		return decimal.NewFromFloat(v), true
+	case float32:
+		if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+			return decimal.Decimal{}, false
+		}
+		return decimal.NewFromFloat32(v), true
+	case int:
+		return decimal.NewFromInt(int64(v)), true
+	case int64:
+		return decimal.NewFromInt(v), true
+	case uint64:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(v), 0), true
+	case string:
+		d, err := decimal.NewFromString(strings.TrimSpace(v))
+		return d, err == nil
+	}
+	return decimal.Decimal{}, false
+}
+
+func scale(d decimal.Decimal) int32 {
+	// Trailing zeros after the point don't count
+	normalised, _ := decimal.NewFromString(d.String())
+	if exp := normalised.Exponent(); exp < 0 {
+		return -exp
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package simulator
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/shopspring/decimal"
+)
+
+func TestToDecimal(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   string
+		wantOk bool
+	}{
+		{name: "decimal", value: decimal.RequireFromString("1.5"), want: "1.5", wantOk: true},
+		{name: "big int", value: big.NewInt(42), want: "42", wantOk: true},
+		{name: "nil big int", value: (*big.Int)(nil)},
+		{name: "float64", value: 0.25, want: "0.25", wantOk: true},
+		{name: "float32", value: float32(0.5), want: "0.5", wantOk: true},
+		{name: "int", value: -3, want: "-3", wantOk: true},
+		{name: "uint64", value: uint64(math.MaxUint64), want: "18446744073709551615", wantOk: true},
+		{name: "string", value: " 12.3 ", want: "12.3", wantOk: true},
+		{name: "not a number", value: "abc"},
+		{name: "NaN", value: math.NaN()},
+		{name: "+Inf", value: math.Inf(1)},
+		{name: "-Inf", value: math.Inf(-1)},
+		{name: "float32 Inf", value: float32(math.Inf(1))},
+		{name: "bool", value: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := toDecimal(test.value)
+			if ok != test.wantOk {
+				t.Fatalf("ok %v, want %v", ok, test.wantOk)
+			}
+			if ok && got.String() != test.want {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzePrecisionNonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		task := newFlakyTask(0, 0, 0)
+		run := NewRun(&pipeline.Pipeline{Tasks: []pipeline.Task{task}}, nil)
+		run.Results[task.DotID()] = &TaskRun{Task: task, Result: pipeline.Result{Value: value}}
+
+		traces := run.AnalyzePrecision()
+		if len(traces) != 1 {
+			t.Fatalf("%v: %d traces, want 1", value, len(traces))
+		}
+		if warnings := traces[0].Warnings; len(warnings) != 1 || warnings[0].Kind != WarningNonFinite {
+			t.Errorf("%v: warnings %+v, want one %s", value, warnings, WarningNonFinite)
+		}
+	}
+}
+
+func TestDivideWarnings(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		vars   map[string]interface{}
+		inputs []pipeline.Result
+		result string
+		// wantDivision is the division a rounding warning names, if any
+		wantDivision string
+	}{
+		{
+			name:         "input attribute over the inputs",
+			input:        "$(price)",
+			vars:         map[string]interface{}{"price": "1"},
+			inputs:       []pipeline.Result{{Value: "100"}},
+			result:       "0.33",
+			wantDivision: "1 / 3",
+		},
+		{
+			name:         "literal input attribute",
+			input:        "2",
+			inputs:       []pipeline.Result{{Value: "100"}},
+			result:       "0.67",
+			wantDivision: "2 / 3",
+		},
+		{
+			name:         "first of several inputs",
+			inputs:       []pipeline.Result{{Value: "1"}, {Value: "7"}},
+			result:       "0.33",
+			wantDivision: "1 / 3",
+		},
+		{
+			name:   "exact",
+			inputs: []pipeline.Result{{Value: "6"}},
+			result: "2",
+		},
+		{
+			name:   "no dividend",
+			result: "0.33",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run := NewRun(nil, test.vars)
+			task := &pipeline.DivideTask{Input: test.input, Divisor: "3"}
+			taskRun := &TaskRun{Task: task, Inputs: test.inputs, Result: pipeline.Result{Value: decimal.RequireFromString(test.result)}}
+
+			warnings := run.divideWarnings(task, taskRun)
+			if test.wantDivision == "" {
+				if len(warnings) != 0 {
+					t.Errorf("warnings %+v, want none", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.HasPrefix(warnings[0].Message, test.wantDivision+" ") {
+				t.Errorf("warnings %+v, want one for %s", warnings, test.wantDivision)
+			}
+		})
+	}
+}
+
+func TestABIWarnings(t *testing.T) {
+	task := &pipeline.ETHABIEncodeTask{
+		ABI:  "fulfill(bytes32 requestId, uint8 small, uint256 price, int256 ratio, int64 fine)",
+		Data: `{ "requestId": $(id), "small": 300, "price": $(negative), "ratio": 1.5, "fine": -7 }`,
+	}
+	run := NewRun(nil, map[string]interface{}{"id": "0x01", "negative": "-1"})
+
+	got := map[string]string{}
+	for _, warning := range run.abiWarnings(task) {
+		got[warning.Arg] = warning.Kind
+	}
+	want := map[string]string{
+		"small": WarningOverflow,
+		"price": WarningNegative,
+		"ratio": WarningTruncation,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings by argument %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,219 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// ErrFailedEarly is the error of tasks that never ran because a task with
+// failEarly set failed before them. The message matches the node's.
+var ErrFailedEarly = errors.New("task run cancelled (fail early)")
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// TaskOutcome explains how a task ended up with its result.
+type TaskOutcome struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	// ErroredInputs are the upstream tasks whose errors the task received in
+	// place of a value
+	ErroredInputs []string    `json:"erroredInputs"`
+	Faults        *FaultCount `json:"faults,omitempty"`
+}
+
+// FaultCount is how an aggregating task (median, mean, mode, sum) counted its
+// errored values against allowedFaults.
+type FaultCount struct {
+	Values   int  `json:"values"`
+	Faults   int  `json:"faults"`
+	Allowed  int  `json:"allowed"`
+	Exceeded bool `json:"exceeded"`
+}
+
+// Summary explains why a run as a whole failed or succeeded. As on a node, a
+// run fails if any of its terminal tasks (those without outputs) fails.
+type Summary struct {
+	Succeeded   bool          `json:"succeeded"`
+	Reason      string        `json:"reason"`
+	FailedEarly string        `json:"failedEarly"`
+	Tasks       []TaskOutcome `json:"tasks"`
+}
+
+// failEarly skips every task that hasn't run yet, as the node's scheduler
+// stops scheduling tasks once a task with failEarly set fails.
+func (r *Run) failEarly(failed pipeline.Task) {
+	r.FailedEarly = failed.DotID()
+
+	for _, task := range r.Pipeline.Tasks {
+		if _, done := r.Results[task.DotID()]; done {
+			continue
+		}
+		r.record(&TaskRun{
+			Task:    task,
+			Result:  pipeline.Result{Error: ErrFailedEarly},
+			Skipped: true,
+		})
+	}
+}
+
+func (r *Run) Summarize() Summary {
+	summary := Summary{
+		FailedEarly: r.FailedEarly,
+		Tasks:       []TaskOutcome{},
+	}
+
+	terminal, failed, pending := 0, []string{}, []string{}
+
+	for _, task := range r.Pipeline.Tasks {
+		outcome := r.outcome(task)
+		summary.Tasks = append(summary.Tasks, outcome)
+
+		if len(task.Outputs()) > 0 {
+			continue
+		}
+		terminal++
+		switch outcome.Status {
+		case StatusPending:
+			pending = append(pending, task.DotID())
+		case StatusFailed, StatusSkipped:
+			failed = append(failed, task.DotID())
+		}
+	}
+
+	switch {
+	case r.FailedEarly != "":
+		summary.Reason = fmt.Sprintf("task %s failed with failEarly set, so the tasks after it were skipped", r.FailedEarly)
+	case len(failed) > 0:
+		summary.Reason = fmt.Sprintf("terminal task(s) %s failed", strings.Join(failed, ", "))
+	case len(pending) > 0:
+		summary.Reason = fmt.Sprintf("terminal task(s) %s have not run yet", strings.Join(pending, ", "))
+	default:
+		summary.Succeeded = true
+		summary.Reason = fmt.Sprintf("all %d terminal task(s) succeeded", terminal)
+		if tolerated := r.toleratedErrors(); len(tolerated) > 0 {
+			summary.Reason += fmt.Sprintf(", errors in %s did not reach them", strings.Join(tolerated, ", "))
+		}
+	}
+
+	return summary
+}
+
+func (r *Run) outcome(task pipeline.Task) TaskOutcome {
+	outcome := TaskOutcome{
+		Id:            task.DotID(),
+		Status:        StatusPending,
+		ErroredInputs: []string{},
+	}
+
+	taskRun, ok := r.Results[task.DotID()]
+	if !ok {
+		return outcome
+	}
+
+	switch {
+	case taskRun.Skipped:
+		outcome.Status = StatusSkipped
+	case taskRun.Result.Error != nil:
+		outcome.Status = StatusFailed
+	default:
+		outcome.Status = StatusSucceeded
+	}
+	if taskRun.Result.Error != nil {
+		outcome.Error = taskRun.Result.Error.Error()
+	}
+
+	for _, dep := range task.Inputs() {
+		if !dep.PropagateResult {
+			continue
+		}
+		if upstream, ok := r.Results[dep.InputTask.DotID()]; ok && upstream.Result.Error != nil {
+			outcome.ErroredInputs = append(outcome.ErroredInputs, dep.InputTask.DotID())
+		}
+	}
+
+	if !taskRun.Skipped {
+		outcome.Faults = r.faultCount(task, taskRun)
+	}
+
+	return outcome
+}
+
+// toleratedErrors lists the tasks that failed without failing the run.
+func (r *Run) toleratedErrors() []string {
+	tolerated := []string{}
+	for _, task := range r.Pipeline.Tasks {
+		if taskRun, ok := r.Results[task.DotID()]; ok && taskRun.Result.Error != nil {
+			tolerated = append(tolerated, task.DotID())
+		}
+	}
+	return tolerated
+}
+
+// faultCount counts the faults of an aggregating task that has run, see
+// CountFaults.
+func (r *Run) faultCount(task pipeline.Task, taskRun *TaskRun) *FaultCount {
+	inputs := taskRun.Inputs
+	if inputs == nil {
+		inputs = r.Inputs(task)
+	}
+	return CountFaults(task, r.Vars, inputs)
+}
+
+// CountFaults counts faults the way the aggregating tasks do: allowedFaults
+// is a literal that defaults to one less than the number of values, and the
+// values are a var holding a list, a JSON list with var references or else
+// the task's inputs, with every error among them a fault. It returns nil for
+// other tasks, or if the task itself would reject its attributes.
+func CountFaults(task pipeline.Task, vars map[string]interface{}, inputs []pipeline.Result) *FaultCount {
+	var values, allowedFaults string
+	switch t := task.(type) {
+	case *pipeline.MedianTask:
+		values, allowedFaults = t.Values, t.AllowedFaults
+	case *pipeline.MeanTask:
+		values, allowedFaults = t.Values, t.AllowedFaults
+	case *pipeline.ModeTask:
+		values, allowedFaults = t.Values, t.AllowedFaults
+	case *pipeline.SumTask:
+		values, allowedFaults = t.Values, t.AllowedFaults
+	default:
+		return nil
+	}
+	pipelineVars := pipeline.NewVarsFrom(vars)
+
+	var (
+		maybeAllowedFaults pipeline.MaybeUint64Param
+		valuesAndErrs      pipeline.SliceParam
+	)
+	if err := pipeline.ResolveParam(&maybeAllowedFaults, pipeline.From(allowedFaults)); err != nil {
+		return nil
+	}
+	if err := pipeline.ResolveParam(&valuesAndErrs, pipeline.From(
+		pipeline.VarExpr(values, pipelineVars),
+		pipeline.JSONWithVarExprs(values, pipelineVars, true),
+		pipeline.Inputs(inputs),
+	)); err != nil {
+		return nil
+	}
+
+	_, faults := valuesAndErrs.FilterErrors()
+	count := &FaultCount{
+		Values:  len(valuesAndErrs),
+		Faults:  faults,
+		Allowed: len(valuesAndErrs) - 1,
+	}
+	if allowed, isSet := maybeAllowedFaults.Uint64(); isSet {
+		count.Allowed = int(allowed)
+	}
+	count.Exceeded = count.Faults > count.Allowed
+
+	return count
+}
@@ -0,0 +1,124 @@
+package simulator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name            string
+		spec            string
+		wantSucceeded   bool
+		wantFailedEarly string
+		wantStatuses    map[string]string
+		wantFaults      *FaultCount
+	}{
+		{
+			name: "failEarly skips the rest of the run",
+			spec: `
+				a [type=divide input="1" divisor="0" failEarly=true]
+				b [type=multiply input="$(a)" times="2"]
+				c [type=multiply input="$(b)" times="2"]
+				a -> b -> c
+			`,
+			wantFailedEarly: "a",
+			wantStatuses:    map[string]string{"a": StatusFailed, "b": StatusSkipped, "c": StatusSkipped},
+		},
+		{
+			name: "errors propagate without failEarly",
+			spec: `
+				a [type=divide input="1" divisor="0"]
+				b [type=multiply input="$(a)" times="2"]
+				a -> b
+			`,
+			wantStatuses: map[string]string{"a": StatusFailed, "b": StatusFailed},
+		},
+		{
+			name: "median tolerates allowedFaults",
+			spec: `
+				a [type=divide input="1" divisor="0"]
+				b [type=multiply input="2" times="3"]
+				c [type=multiply input="4" times="1"]
+				m [type=median values=<[ $(a), $(b), $(c) ]> allowedFaults=1]
+				a -> m
+				b -> m
+				c -> m
+			`,
+			wantSucceeded: true,
+			wantStatuses:  map[string]string{"a": StatusFailed, "b": StatusSucceeded, "c": StatusSucceeded, "m": StatusSucceeded},
+			wantFaults:    &FaultCount{Values: 3, Faults: 1, Allowed: 1},
+		},
+		{
+			name: "median exceeds allowedFaults",
+			spec: `
+				a [type=divide input="1" divisor="0"]
+				b [type=multiply input="2" times="3"]
+				m [type=median values=<[ $(a), $(b) ]> allowedFaults=0]
+				a -> m
+				b -> m
+			`,
+			wantStatuses: map[string]string{"a": StatusFailed, "b": StatusSucceeded, "m": StatusFailed},
+			wantFaults:   &FaultCount{Values: 2, Faults: 1, Allowed: 0, Exceeded: true},
+		},
+		{
+			name: "literal values count",
+			spec: `
+				b [type=multiply input="2" times="3"]
+				m [type=median values=<[ $(b), 7, 9 ]>]
+				b -> m
+			`,
+			wantSucceeded: true,
+			wantStatuses:  map[string]string{"b": StatusSucceeded, "m": StatusSucceeded},
+			wantFaults:    &FaultCount{Values: 3, Faults: 0, Allowed: 2},
+		},
+		{
+			name: "inputs are the values by default",
+			spec: `
+				a [type=divide input="1" divisor="0"]
+				b [type=multiply input="2" times="3"]
+				c [type=multiply input="4" times="1"]
+				m [type=median]
+				a -> m
+				b -> m
+				c -> m
+			`,
+			wantSucceeded: true,
+			wantStatuses:  map[string]string{"a": StatusFailed, "b": StatusSucceeded, "c": StatusSucceeded, "m": StatusSucceeded},
+			wantFaults:    &FaultCount{Values: 3, Faults: 1, Allowed: 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run := NewRun(mustParse(t, test.spec), nil)
+			if err := run.Execute(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			summary := run.Summarize()
+
+			if summary.Succeeded != test.wantSucceeded {
+				t.Errorf("succeeded %v, want %v: %s", summary.Succeeded, test.wantSucceeded, summary.Reason)
+			}
+			if summary.FailedEarly != test.wantFailedEarly {
+				t.Errorf("failed early at %q, want %q", summary.FailedEarly, test.wantFailedEarly)
+			}
+
+			statuses := map[string]string{}
+			var faults *FaultCount
+			for _, outcome := range summary.Tasks {
+				statuses[outcome.Id] = outcome.Status
+				if outcome.Id == "m" {
+					faults = outcome.Faults
+				}
+			}
+			if !reflect.DeepEqual(statuses, test.wantStatuses) {
+				t.Errorf("statuses %v, want %v", statuses, test.wantStatuses)
+			}
+			if !reflect.DeepEqual(faults, test.wantFaults) {
+				t.Errorf("faults %+v, want %+v", faults, test.wantFaults)
+			}
+		})
+	}
+}
@@ -0,0 +1,462 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+// TaskRun records a single execution of a task within a Run.
+type TaskRun struct {
+	Task     pipeline.Task
+	Inputs   []pipeline.Result
+	Result   pipeline.Result
+	Attempts []Attempt
+	Mocked   bool
+	// Skipped tasks never ran because a task with failEarly set failed
+	Skipped bool
+	// Dropped tasks lost their output to an injected fault
+	Dropped  bool
+	Started  time.Time
+	Duration time.Duration
+}
+
+// Run simulates a whole pipeline. Tasks are executed one at a time in the
+// order pipeline.Parse assigns to task IDs, which is a topological sort of
+// the DAG, so running them in order is equivalent to what a node does with
+// its scheduler minus the concurrency.
+type Run struct {
+	Pipeline *pipeline.Pipeline
+	Vars     map[string]interface{}
+	// Mocks replace the result of a task, keyed by dot ID. Mocked tasks are
+	// not executed at all.
+	Mocks   map[string]interface{}
+	Results map[string]*TaskRun
+	Options ExecuteOptions
+	// Faults are injected into the tasks they match, see SetFaults
+	Faults []Fault
+	// FailedEarly is the dot ID of the task with failEarly set whose failure
+	// ended the run, if any
+	FailedEarly string
+	// PausedAt is the dot ID of the task a breakpoint paused the run before,
+	// which Continue runs rather than pausing at again
+	PausedAt string
+}
+
+func NewRun(p *pipeline.Pipeline, vars map[string]interface{}) *Run {
+	if vars == nil {
+		vars = make(map[string]interface{})
+	}
+
+	return &Run{
+		Pipeline: p,
+		Vars:     vars,
+		Mocks:    make(map[string]interface{}),
+		Results:  make(map[string]*TaskRun),
+	}
+}
+
+// Next returns the next task that is ready to run, or nil if every task has
+// a result.
+func (r *Run) Next() pipeline.Task {
+	for _, task := range r.Pipeline.Tasks {
+		if _, done := r.Results[task.DotID()]; done {
+			continue
+		}
+		if r.isReady(task) {
+			return task
+		}
+	}
+	return nil
+}
+
+func (r *Run) Done() bool {
+	return r.Next() == nil
+}
+
+func (r *Run) isReady(task pipeline.Task) bool {
+	for _, dep := range task.Inputs() {
+		if _, done := r.Results[dep.InputTask.DotID()]; !done {
+			return false
+		}
+	}
+	return true
+}
+
+// Inputs collects the results the task receives from its upstream tasks,
+// ordered by their output index like the node's scheduler does.
+func (r *Run) Inputs(task pipeline.Task) []pipeline.Result {
+	type input struct {
+		index  int32
+		result pipeline.Result
+	}
+
+	collected := []input{}
+	for _, dep := range task.Inputs() {
+		if !dep.PropagateResult {
+			continue
+		}
+		if upstream, ok := r.Results[dep.InputTask.DotID()]; ok && !upstream.Dropped {
+			collected = append(collected, input{dep.InputTask.OutputIndex(), upstream.Result})
+		}
+	}
+
+	sort.SliceStable(collected, func(i, j int) bool {
+		return collected[i].index < collected[j].index
+	})
+
+	inputs := make([]pipeline.Result, 0, len(collected))
+	for _, in := range collected {
+		inputs = append(inputs, in.result)
+	}
+	return inputs
+}
+
+// Step runs the next ready task. It returns nil once the run is done.
+func (r *Run) Step(ctx context.Context) *TaskRun {
+	task := r.Next()
+	if task == nil {
+		return nil
+	}
+	return r.RunTask(ctx, task, r.Inputs(task))
+}
+
+// Execute steps through the run until every task has a result or the
+// context is cancelled.
+func (r *Run) Execute(ctx context.Context) error {
+	for !r.Done() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r.Step(ctx)
+	}
+	return nil
+}
+
+// RunTask executes the task with the given inputs against the current vars
+// and records the result, replacing any previous result for that task.
+func (r *Run) RunTask(ctx context.Context, task pipeline.Task, inputs []pipeline.Result) *TaskRun {
+	taskRun := &TaskRun{
+		Task:    task,
+		Inputs:  inputs,
+		Started: time.Now(),
+	}
+
+	if mock, ok := r.Mocks[task.DotID()]; ok {
+		taskRun.Mocked = true
+		taskRun.Result = pipeline.Result{Value: mock}
+	} else {
+		taskRun.Result, taskRun.Attempts = r.execute(ctx, task, inputs)
+		if last := taskRun.Attempts[len(taskRun.Attempts)-1]; last.Fault != nil && last.Fault.Drop {
+			taskRun.Dropped = true
+		}
+	}
+	taskRun.Duration = time.Since(taskRun.Started)
+
+	r.record(taskRun)
+
+	if taskRun.Result.Error != nil && task.Base().FailEarly {
+		r.failEarly(task)
+	}
+
+	return taskRun
+}
+
+func (r *Run) execute(ctx context.Context, parsed pipeline.Task, inputs []pipeline.Result) (pipeline.Result, []Attempt) {
+	task, err := NewTaskFromParsed(parsed)
+	if err != nil {
+		return pipeline.Result{Error: err}, []Attempt{{Error: err}}
+	}
+
+	opts := r.Options
+	opts.Faults = r.faultsFor(parsed)
+
+	return ExecuteTask(ctx, task, r.Vars, inputs, opts)
+}
+
+func (r *Run) record(taskRun *TaskRun) {
+	dotID := taskRun.Task.DotID()
+
+	r.Results[dotID] = taskRun
+
+	if taskRun.Dropped {
+		delete(r.Vars, dotID)
+		return
+	}
+
+	// Like the node, downstream tasks see the error in place of the value
+	if taskRun.Result.Error != nil {
+		r.Vars[dotID] = taskRun.Result.Error
+	} else {
+		r.Vars[dotID] = taskRun.Result.Value
+	}
+}
+
+// SetVar overwrites a var, given as a key path like $(a.b) without the
+// brackets. Missing maps along the path are created. If the var holds the
+// output of a task that has already run, or is nested in it, the task's
+// result is overwritten too so that downstream tasks receive the new value
+// as their input.
+func (r *Run) SetVar(keypath string, value interface{}) error {
+	if err := SetKeypath(r.Vars, keypath, value); err != nil {
+		return err
+	}
+
+	key := strings.SplitN(keypath, ".", 2)[0]
+	if taskRun, ok := r.Results[key]; ok {
+		taskRun.Result = pipeline.Result{Value: r.Vars[key]}
+	}
+	return nil
+}
+
+// SetKeypath sets a var by its dot separated keypath, creating any missing
+// maps along the way. It fails rather than replace a value that isn't a map.
+func SetKeypath(vars map[string]interface{}, keypath string, value interface{}) error {
+	keys := strings.Split(keypath, ".")
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf(`"%v" is not a var`, keypath)
+		}
+	}
+
+	for i, key := range keys[:len(keys)-1] {
+		switch nested := vars[key].(type) {
+		case map[string]interface{}:
+			vars = nested
+		case nil:
+			created := make(map[string]interface{})
+			vars[key] = created
+			vars = created
+		default:
+			return fmt.Errorf("cannot set %v: %v is a %T, not a map", keypath, strings.Join(keys[:i+1], "."), nested)
+		}
+	}
+	vars[keys[len(keys)-1]] = value
+	return nil
+}
+
+// Reset discards the result of the task and of every task downstream of it
+// so that they run again.
+func (r *Run) Reset(task pipeline.Task) {
+	dotID := task.DotID()
+
+	// Tasks skipped because this one failed get their chance to run again
+	if dotID == r.FailedEarly {
+		r.FailedEarly = ""
+		for id, taskRun := range r.Results {
+			if taskRun.Skipped {
+				delete(r.Results, id)
+				delete(r.Vars, id)
+			}
+		}
+	}
+
+	if _, ok := r.Results[dotID]; ok {
+		delete(r.Results, dotID)
+		delete(r.Vars, dotID)
+	}
+
+	for _, output := range task.Outputs() {
+		r.Reset(output)
+	}
+}
+
+// Rerun resets the task and its downstream tasks and runs it again. If inputs
+// is nil the task receives the results of its upstream tasks.
+func (r *Run) Rerun(ctx context.Context, task pipeline.Task, inputs []pipeline.Result) (*TaskRun, error) {
+	if !r.isReady(task) {
+		return nil, fmt.Errorf("task %s cannot run until all of its inputs have run", task.DotID())
+	}
+
+	r.Reset(task)
+
+	if inputs == nil {
+		inputs = r.Inputs(task)
+	}
+	return r.RunTask(ctx, task, inputs), nil
+}
+
+// Task looks up a task of the pipeline by its dot ID.
+func (r *Run) Task(dotID string) (pipeline.Task, error) {
+	if task := taskByDotID(r.Pipeline, dotID); task != nil {
+		return task, nil
+	}
+	return nil, fmt.Errorf(`unknown task: "%v"`, dotID)
+}
+
+func taskByDotID(p *pipeline.Pipeline, dotID string) pipeline.Task {
+	for _, task := range p.Tasks {
+		if task.DotID() == dotID {
+			return task
+		}
+	}
+	return nil
+}
+
+// Faulted reports whether a fault was injected into any of the attempts.
+func Faulted(attempts []Attempt) bool {
+	for _, attempt := range attempts {
+		if attempt.Fault != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskRunResult is the serialisable form of a TaskRun. Its fields mirror the
+// api/task response so the frontend can treat both the same way.
+type TaskRunResult struct {
+	Id               string `json:"id"`
+	Type             string `json:"type"`
+	Value            string `json:"value"`
+	Val64            string `json:"val64"`
+	Error            string `json:"error"`
+	SideEffectData   string `json:"sideEffectData"`
+	SideEffectData64 string `json:"sideEffectData64"`
+	Mocked           bool   `json:"mocked"`
+	Skipped          bool   `json:"skipped"`
+	Faulted          bool   `json:"faulted"`
+	Dropped          bool   `json:"dropped"`
+	TimedOut         bool   `json:"timedOut"`
+	Cancelled        bool   `json:"cancelled"`
+	DurationMs       int64  `json:"durationMs"`
+	// Attempts is only set when a task was retried
+	Attempts []AttemptResult `json:"attempts,omitempty"`
+}
+
+type AttemptResult struct {
+	Error      string `json:"error"`
+	DurationMs int64  `json:"durationMs"`
+	BackoffMs  int64  `json:"backoffMs"`
+	Faulted    bool   `json:"faulted"`
+}
+
+// EncodeAttempts serialises the attempts at running a task. It returns nil
+// unless the task was retried, as a single attempt says nothing the task
+// result doesn't.
+func EncodeAttempts(attempts []Attempt) []AttemptResult {
+	if len(attempts) < 2 {
+		return nil
+	}
+
+	encoded := make([]AttemptResult, 0, len(attempts))
+	for _, attempt := range attempts {
+		a := AttemptResult{
+			DurationMs: attempt.Duration.Milliseconds(),
+			BackoffMs:  attempt.Backoff.Milliseconds(),
+			Faulted:    attempt.Fault != nil,
+		}
+		if attempt.Error != nil {
+			a.Error = attempt.Error.Error()
+		}
+		encoded = append(encoded, a)
+	}
+	return encoded
+}
+
+func (tr *TaskRun) Encode() (TaskRunResult, error) {
+	val64, err := ToBase64(tr.Result.Value)
+	if err != nil {
+		return TaskRunResult{}, err
+	}
+
+	encoded := TaskRunResult{
+		Id:         tr.Task.DotID(),
+		Type:       tr.Task.Type().String(),
+		Value:      fmt.Sprintf("%v", tr.Result.Value),
+		Val64:      val64,
+		Mocked:     tr.Mocked,
+		Skipped:    tr.Skipped,
+		Faulted:    Faulted(tr.Attempts),
+		Dropped:    tr.Dropped,
+		DurationMs: tr.Duration.Milliseconds(),
+		Attempts:   EncodeAttempts(tr.Attempts),
+	}
+
+	if tr.Result.Error != nil {
+		encoded.Error = tr.Result.Error.Error()
+		encoded.TimedOut = errors.Is(tr.Result.Error, ErrTaskTimedOut)
+		encoded.Cancelled = errors.Is(tr.Result.Error, ErrTaskCancelled)
+	}
+
+	if tr.Result.SideEffectData != nil {
+		encoded.SideEffectData = fmt.Sprintf("%v", tr.Result.SideEffectData)
+		encoded.SideEffectData64, err = ToBase64(tr.Result.SideEffectData)
+		if err != nil {
+			return TaskRunResult{}, err
+		}
+	}
+
+	return encoded, nil
+}
+
+// EncodeResults serialises the results recorded so far in execution order.
+func (r *Run) EncodeResults() ([]TaskRunResult, error) {
+	results := []TaskRunResult{}
+	for _, task := range r.Pipeline.Tasks {
+		taskRun, ok := r.Results[task.DotID()]
+		if !ok {
+			continue
+		}
+		encoded, err := taskRun.Encode()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, encoded)
+	}
+	return results, nil
+}
+
+// LoadResults restores results previously produced by EncodeResults, so that
+// a run can be continued across stateless requests.
+func (r *Run) LoadResults(results []TaskRunResult) error {
+	for _, encoded := range results {
+		task, err := r.Task(encoded.Id)
+		if err != nil {
+			return err
+		}
+
+		val, err := FromBase64(encoded.Val64)
+		if err != nil {
+			return err
+		}
+
+		taskRun := &TaskRun{
+			Task:     task,
+			Result:   pipeline.Result{Value: val},
+			Mocked:   encoded.Mocked,
+			Skipped:  encoded.Skipped,
+			Dropped:  encoded.Dropped,
+			Duration: time.Duration(encoded.DurationMs) * time.Millisecond,
+		}
+		if encoded.Error != "" {
+			taskRun.Result.Error = errors.New(encoded.Error)
+		}
+
+		r.record(taskRun)
+
+		if taskRun.Result.Error != nil && !taskRun.Skipped && task.Base().FailEarly {
+			r.FailedEarly = task.DotID()
+		}
+	}
+	return nil
+}
+
+// EncodeVars serialises the vars. Errors recorded for failed tasks are
+// replaced by their message as they have no JSON representation.
+func (r *Run) EncodeVars() (string, error) {
+	vars := make(map[string]interface{}, len(r.Vars))
+	for k, v := range r.Vars {
+		if err, ok := v.(error); ok {
+			vars[k] = err.Error()
+		} else {
+			vars[k] = v
+		}
+	}
+	return ToBase64(vars)
+}
@@ -0,0 +1,195 @@
+// Package simulator simulates Chainlink job spec pipelines, running tasks with
+// the node's own implementations. It can be embedded in Go services; the
+// app's HTTP handlers are thin adapters over it.
+//
+// The entry points are Parse, RunTask, RunPipeline and ConvertVars. Values
+// are the Go types a node holds, e.g. *big.Int or decimal.Decimal, and
+// Normalize gives them the shape they have after being exchanged with the app.
+package simulator
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/jobspec"
+)
+
+// Parse parses a spec, which is either a bare pipeline in DOT or a TOML job
+// spec with the pipeline in its observationSource.
+func Parse(spec string) (*pipeline.Pipeline, error) {
+	s, err := jobspec.Load([]byte(spec))
+	if err != nil {
+		return nil, err
+	}
+	return s.Pipeline, s.ParseErr
+}
+
+// TaskResult is the outcome of RunTask.
+type TaskResult struct {
+	Value          interface{}
+	Error          error
+	SideEffectData interface{}
+	// Attempts has an entry for every time the task ran, including retries
+	Attempts []Attempt
+}
+
+func (r *TaskResult) TimedOut() bool {
+	return errors.Is(r.Error, ErrTaskTimedOut)
+}
+
+func (r *TaskResult) Cancelled() bool {
+	return errors.Is(r.Error, ErrTaskCancelled)
+}
+
+func (r *TaskResult) Panicked() bool {
+	return errors.Is(r.Error, ErrTaskPanicked)
+}
+
+// Dropped reports whether a fault made the task lose its output.
+func (r *TaskResult) Dropped() bool {
+	last := r.Attempts[len(r.Attempts)-1].Fault
+	return last != nil && last.Drop
+}
+
+// RunTask runs a single task of the given type, configured by its options as
+// they would appear in a spec. It only returns an error if the task can't be
+// created; the task failing is reported in the result.
+func RunTask(ctx context.Context, taskType TaskType, options map[string]interface{}, vars map[string]interface{}, inputs []interface{}, opts ExecuteOptions) (*TaskResult, error) {
+	task, err := NewTask(taskType, options)
+	if err != nil {
+		return nil, err
+	}
+
+	taskInputs := make([]pipeline.Result, 0, len(inputs))
+	for _, input := range inputs {
+		taskInputs = append(taskInputs, pipeline.Result{Value: input})
+	}
+
+	result, attempts := ExecuteTask(ctx, task, vars, taskInputs, opts)
+
+	return &TaskResult{
+		Value:          result.Value,
+		Error:          result.Error,
+		SideEffectData: result.SideEffectData,
+		Attempts:       attempts,
+	}, nil
+}
+
+// RunOptions configure a whole pipeline run.
+type RunOptions struct {
+	// BackoffCompression divides the delays between retries, for fast testing
+	BackoffCompression float64
+	Faults             []Fault
+	// FaultSeed makes faults with a rate reproducible, with 0 picking a
+	// random one
+	FaultSeed int64
+	// Chains answer ethcall and ethtx tasks, see ExecuteOptions
+	Chains chain.Chains
+}
+
+// PrepareRun sets up a run of the pipeline without starting it, for callers
+// that drive it themselves, e.g. step by step.
+func PrepareRun(p *pipeline.Pipeline, vars map[string]interface{}, mocks map[string]interface{}, opts RunOptions) (*Run, error) {
+	run := NewRun(p, vars)
+	run.Options.BackoffCompression = opts.BackoffCompression
+	run.Options.Chains = opts.Chains
+
+	for id, mock := range mocks {
+		run.Mocks[id] = mock
+	}
+
+	if err := run.SetFaults(opts.Faults, opts.FaultSeed); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// RunRequest is a whole pipeline run as the API endpoints take it, with the
+// vars and mocks base64 encoded as the var-helper prepares them.
+type RunRequest struct {
+	Spec    string
+	Vars64  string
+	Mocks64 map[string]string
+	// Divides the delays between retries, for fast testing
+	BackoffCompression float64
+	// Faults are injected into the tasks they target, see Fault
+	Faults []Fault
+	// FaultSeed makes faults with a rate reproducible
+	FaultSeed int64
+	// Contracts are the ABI registry that abi attributes can refer to as
+	// Contract.method
+	Contracts []contracts.Source
+	// Chains answer ethcall and ethtx by evmChainID, see chain.Config
+	Chains []chain.Config
+}
+
+// Prepare parses the spec, resolving its Contract.method references, and
+// sets up the run without starting it.
+func (r RunRequest) Prepare() (*Run, error) {
+	parsed, err := Parse(r.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := contracts.Load(r.Contracts)
+	if err != nil {
+		return nil, err
+	}
+	if errs := registry.ResolvePipeline(parsed); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	vars, err := VarsFromBase64(r.Vars64)
+	if err != nil {
+		return nil, err
+	}
+
+	mocks, err := MocksFromBase64(r.Mocks64)
+	if err != nil {
+		return nil, err
+	}
+
+	chains, err := chain.NewChains(r.Chains)
+	if err != nil {
+		return nil, err
+	}
+
+	return PrepareRun(parsed, vars, mocks, RunOptions{
+		BackoffCompression: r.BackoffCompression,
+		Faults:             r.Faults,
+		FaultSeed:          r.FaultSeed,
+		Chains:             chains,
+	})
+}
+
+// RunPipeline parses the spec and runs the whole pipeline, with the mocked
+// tasks' values, by dot ID, standing in for running them. The returned run
+// holds every task's result; see Run.Summarize for whether it succeeded.
+func RunPipeline(ctx context.Context, spec string, vars map[string]interface{}, mocks map[string]interface{}, opts RunOptions) (*Run, error) {
+	p, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := PrepareRun(p, vars, mocks, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return run, run.Execute(ctx)
+}
+
+// Normalize round-trips the value through the node's JSON encoding, as
+// happens to every value exchanged with the app, e.g. vars and inputs
+// prepared by the var-helper.
+func Normalize(value interface{}) (interface{}, error) {
+	encoded, err := ToBase64(value)
+	if err != nil {
+		return nil, err
+	}
+	return FromBase64(encoded)
+}
@@ -0,0 +1,246 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/contracts"
+)
+
+const aggregatorABI = `[{"type":"function","name":"latestAnswer","inputs":[],"outputs":[{"name":"","type":"int256"}],"stateMutability":"view"}]`
+
+func TestRunRequestPrepare(t *testing.T) {
+	aggregator := []contracts.Source{{Name: "Aggregator", ABI: json.RawMessage(aggregatorABI)}}
+	encode := `encode [type=ethabiencode abi="Aggregator.latestAnswer"]`
+
+	tests := []struct {
+		name       string
+		request    RunRequest
+		wantErr    bool
+		wantABI    string
+		wantChains int
+	}{
+		{
+			name:    "contract reference",
+			request: RunRequest{Spec: encode, Contracts: aggregator},
+			wantABI: "latestAnswer()",
+		},
+		{
+			name:    "reference without contracts",
+			request: RunRequest{Spec: encode},
+			wantErr: true,
+		},
+		{
+			name:    "invalid ABI",
+			request: RunRequest{Spec: encode, Contracts: []contracts.Source{{Name: "Aggregator", ABI: json.RawMessage(`{`)}}},
+			wantErr: true,
+		},
+		{
+			name: "chains",
+			request: RunRequest{
+				Spec:   `a [type=any]`,
+				Chains: []chain.Config{{ChainID: "1"}, {ChainID: "137"}},
+			},
+			wantChains: 2,
+		},
+		{
+			name: "chain configured twice",
+			request: RunRequest{
+				Spec:   `a [type=any]`,
+				Chains: []chain.Config{{ChainID: "1"}, {ChainID: "1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "vars that aren't an object",
+			request: RunRequest{Spec: `a [type=any]`, Vars64: "WzFd"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run, err := test.request.Prepare()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if test.wantABI != "" {
+				task, ok := run.Pipeline.Tasks[0].(*pipeline.ETHABIEncodeTask)
+				if !ok || task.ABI != test.wantABI {
+					t.Errorf("abi %+v, want %q", run.Pipeline.Tasks[0], test.wantABI)
+				}
+			}
+			if len(run.Options.Chains) != test.wantChains {
+				t.Errorf("%d chains, want %d", len(run.Options.Chains), test.wantChains)
+			}
+		})
+	}
+}
+
+func TestRunTask(t *testing.T) {
+	tests := []struct {
+		name         string
+		taskType     TaskType
+		options      map[string]interface{}
+		vars         map[string]interface{}
+		inputs       []interface{}
+		want         string
+		wantErr      bool
+		wantTaskErr  bool
+		wantAttempts int
+	}{
+		{
+			name:         "multiply its input",
+			taskType:     TaskTypeMultiply,
+			options:      map[string]interface{}{"times": "10"},
+			inputs:       []interface{}{"2.5"},
+			want:         "25",
+			wantAttempts: 1,
+		},
+		{
+			name:         "multiply a var",
+			taskType:     TaskTypeMultiply,
+			options:      map[string]interface{}{"input": "$(price)", "times": "2"},
+			vars:         map[string]interface{}{"price": "3"},
+			want:         "6",
+			wantAttempts: 1,
+		},
+		{
+			name:         "task failure",
+			taskType:     TaskTypeDivide,
+			options:      map[string]interface{}{"input": "1", "divisor": "0"},
+			wantTaskErr:  true,
+			wantAttempts: 1,
+		},
+		{
+			name:     "unknown task type",
+			taskType: "nope",
+			wantErr:  true,
+		},
+		{
+			name:     "bad timeout",
+			taskType: TaskTypeMultiply,
+			options:  map[string]interface{}{"timeout": "soon", "times": "2"},
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := RunTask(context.Background(), test.taskType, test.options, test.vars, test.inputs, ExecuteOptions{})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if (result.Error != nil) != test.wantTaskErr {
+				t.Fatalf("task error %v, want an error: %v", result.Error, test.wantTaskErr)
+			}
+			if !test.wantTaskErr && fmt.Sprint(result.Value) != test.want {
+				t.Errorf("%v, want %v", result.Value, test.want)
+			}
+			if len(result.Attempts) != test.wantAttempts {
+				t.Errorf("%d attempts, want %d", len(result.Attempts), test.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRunPipeline(t *testing.T) {
+	const spec = `
+fetch [type=http method=GET url="http://localhost:1"]
+double [type=multiply times=2]
+fetch -> double
+`
+
+	tests := []struct {
+		name    string
+		spec    string
+		vars    map[string]interface{}
+		mocks   map[string]interface{}
+		task    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "mocked task",
+			spec:  spec,
+			mocks: map[string]interface{}{"fetch": "4"},
+			task:  "double",
+			want:  "8",
+		},
+		{
+			name: "vars",
+			spec: `double [type=multiply input="$(price)" times=2]`,
+			vars: map[string]interface{}{"price": "1.5"},
+			task: "double",
+			want: "3",
+		},
+		{
+			name:    "invalid spec",
+			spec:    `double [type=multiply`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run, err := RunPipeline(context.Background(), test.spec, test.vars, test.mocks, RunOptions{})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			result, ok := run.Results[test.task]
+			if !ok {
+				t.Fatalf("%s didn't run", test.task)
+			}
+			if result.Result.Error != nil {
+				t.Fatalf("%s failed: %v", test.task, result.Result.Error)
+			}
+			if got := fmt.Sprint(result.Result.Value); got != test.want {
+				t.Errorf("%s %v, want %v", test.task, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "string", value: "abc", want: "abc"},
+		{name: "nil", value: nil, want: nil},
+		{name: "map", value: map[string]interface{}{"a": "b"}, want: map[string]interface{}{"a": "b"}},
+		{name: "list", value: []interface{}{"a", true}, want: []interface{}{"a", true}},
+		{name: "unserialisable", value: make(chan int), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Normalize(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
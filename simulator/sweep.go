@@ -0,0 +1,258 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/shopspring/decimal"
+)
+
+// MaxSweepRows caps the number of runs in a sweep, across all its axes.
+const MaxSweepRows = 1000
+
+// Axis is one dimension of a sweep. It varies either a var, by keypath (e.g.
+// "jobRun.requestBody.price"), or the mocked output of a task, over an
+// explicit list of values or a decimal range.
+type Axis struct {
+	Var  string
+	Task string
+	// Values are used as decimals where they parse as one, and as strings
+	// otherwise
+	Values []string
+	// From, To and Step give an inclusive decimal range instead of Values
+	From string
+	To   string
+	Step string
+}
+
+func (a Axis) Name() string {
+	if a.Task != "" {
+		return a.Task
+	}
+	return a.Var
+}
+
+func (a Axis) points() ([]interface{}, error) {
+	if (a.Var == "") == (a.Task == "") {
+		return nil, fmt.Errorf("a sweep axis must set exactly one of var or task")
+	}
+
+	if len(a.Values) > 0 {
+		points := make([]interface{}, 0, len(a.Values))
+		for _, v := range a.Values {
+			if d, err := decimal.NewFromString(v); err == nil {
+				points = append(points, d)
+			} else {
+				points = append(points, v)
+			}
+		}
+		return points, nil
+	}
+
+	from, err := decimal.NewFromString(a.From)
+	if err != nil {
+		return nil, fmt.Errorf("axis %s from: %w", a.Name(), err)
+	}
+	to, err := decimal.NewFromString(a.To)
+	if err != nil {
+		return nil, fmt.Errorf("axis %s to: %w", a.Name(), err)
+	}
+	step, err := decimal.NewFromString(a.Step)
+	if err != nil {
+		return nil, fmt.Errorf("axis %s step: %w", a.Name(), err)
+	}
+	if !step.IsPositive() {
+		return nil, fmt.Errorf("axis %s step must be positive", a.Name())
+	}
+
+	points := []interface{}{}
+	for v := from; v.LessThanOrEqual(to); v = v.Add(step) {
+		if len(points) == MaxSweepRows {
+			return nil, fmt.Errorf("axis %s has more than %d points", a.Name(), MaxSweepRows)
+		}
+		points = append(points, v)
+	}
+	return points, nil
+}
+
+// SweepRow is the outcome of one run of a sweep.
+type SweepRow struct {
+	Params map[string]string `json:"params"`
+	// Values and Errors hold the result of every task that ran, by dot ID
+	Values    map[string]string `json:"values"`
+	Errors    map[string]string `json:"errors"`
+	Succeeded bool              `json:"succeeded"`
+	Reason    string            `json:"reason"`
+}
+
+type Sweep struct {
+	Axes []string `json:"axes"`
+	// Final are the dot IDs of the terminal tasks, whose values are the final
+	// results of each row
+	Final []string `json:"final"`
+	// Skipped are the dot IDs of the tasks that only feed swept tasks, which
+	// aren't run as their results would be replaced anyway
+	Skipped []string   `json:"skipped"`
+	Rows    []SweepRow `json:"rows"`
+}
+
+// RunSweep re-runs the pipeline for every combination of the axes' values.
+// Tasks upstream of a swept task that only feed swept tasks, e.g. the fetch
+// before a swept parse, are not run, so that a sweep doesn't make a request
+// per row whose result is thrown away. Every row runs with the options.
+func RunSweep(ctx context.Context, p *pipeline.Pipeline, vars map[string]interface{}, mocks map[string]interface{}, axes []Axis, opts RunOptions) (*Sweep, error) {
+	if len(axes) == 0 || len(axes) > 2 {
+		return nil, fmt.Errorf("a sweep needs one or two axes, got %d", len(axes))
+	}
+
+	sweep := &Sweep{
+		Axes:    []string{},
+		Final:   []string{},
+		Skipped: []string{},
+		Rows:    []SweepRow{},
+	}
+
+	points := make([][]interface{}, len(axes))
+	rows := 1
+	for i, axis := range axes {
+		var err error
+		if points[i], err = axis.points(); err != nil {
+			return nil, err
+		}
+		if axis.Task != "" && taskByDotID(p, axis.Task) == nil {
+			return nil, fmt.Errorf(`unknown task: "%v"`, axis.Task)
+		}
+		sweep.Axes = append(sweep.Axes, axis.Name())
+		rows *= len(points[i])
+	}
+	if rows > MaxSweepRows {
+		return nil, fmt.Errorf("the sweep has %d rows, at most %d are allowed", rows, MaxSweepRows)
+	}
+
+	swept := map[string]bool{}
+	for _, axis := range axes {
+		if axis.Task != "" {
+			swept[axis.Task] = true
+		}
+	}
+	skipped := feedingOnly(p, swept)
+
+	for _, task := range p.Tasks {
+		if len(task.Outputs()) == 0 {
+			sweep.Final = append(sweep.Final, task.DotID())
+		}
+		if skipped[task.DotID()] {
+			sweep.Skipped = append(sweep.Skipped, task.DotID())
+		}
+	}
+
+	for _, combination := range combinations(points) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		run, err := PrepareRun(p, copyVars(vars), mocks, opts)
+		if err != nil {
+			return nil, err
+		}
+		for id := range skipped {
+			run.Mocks[id] = nil
+		}
+
+		row := SweepRow{
+			Params: map[string]string{},
+			Values: map[string]string{},
+			Errors: map[string]string{},
+		}
+
+		for i, axis := range axes {
+			value := combination[i]
+			row.Params[axis.Name()] = fmt.Sprintf("%v", value)
+			if axis.Task != "" {
+				run.Mocks[axis.Task] = value
+			} else if err := SetKeypath(run.Vars, axis.Var, value); err != nil {
+				return nil, fmt.Errorf("axis %s: %w", axis.Name(), err)
+			}
+		}
+
+		if err := run.Execute(ctx); err != nil {
+			return nil, err
+		}
+
+		for id, taskRun := range run.Results {
+			if skipped[id] {
+				continue
+			}
+			if taskRun.Result.Error != nil {
+				row.Errors[id] = taskRun.Result.Error.Error()
+			} else {
+				row.Values[id] = fmt.Sprintf("%v", taskRun.Result.Value)
+			}
+		}
+
+		summary := run.Summarize()
+		row.Succeeded, row.Reason = summary.Succeeded, summary.Reason
+
+		sweep.Rows = append(sweep.Rows, row)
+	}
+
+	return sweep, nil
+}
+
+// feedingOnly finds the tasks whose outputs all lead to the given tasks,
+// directly or through other such tasks, so that their results only matter
+// to tasks whose results are replaced.
+func feedingOnly(p *pipeline.Pipeline, replaced map[string]bool) map[string]bool {
+	feeding := map[string]bool{}
+	for changed := true; changed; {
+		changed = false
+		for _, task := range p.Tasks {
+			id := task.DotID()
+			if replaced[id] || feeding[id] || len(task.Outputs()) == 0 {
+				continue
+			}
+			only := true
+			for _, output := range task.Outputs() {
+				if !replaced[output.DotID()] && !feeding[output.DotID()] {
+					only = false
+					break
+				}
+			}
+			if only {
+				feeding[id] = true
+				changed = true
+			}
+		}
+	}
+	return feeding
+}
+
+func combinations(points [][]interface{}) [][]interface{} {
+	result := [][]interface{}{{}}
+	for _, axisPoints := range points {
+		next := [][]interface{}{}
+		for _, prefix := range result {
+			for _, point := range axisPoints {
+				combination := append(append([]interface{}{}, prefix...), point)
+				next = append(next, combination)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// copyVars copies the vars deeply enough that SetKeypath on the copy leaves
+// the original untouched.
+func copyVars(vars map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		if nested, ok := v.(map[string]interface{}); ok {
+			copied[k] = copyVars(nested)
+		} else {
+			copied[k] = v
+		}
+	}
+	return copied
+}
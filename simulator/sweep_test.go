@@ -0,0 +1,132 @@
+package simulator
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRunSweep(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		vars        map[string]interface{}
+		axes        []Axis
+		wantSkipped []string
+		wantFinal   []string
+		// wantValues are the values of the final tasks, by row
+		wantValues []map[string]string
+	}{
+		{
+			name: "upstream of a swept task is skipped",
+			spec: `
+				fetch [type=multiply input="1" times="1"]
+				parse [type=multiply input="$(fetch)" times="1"]
+				scale [type=multiply input="$(parse)" times="2"]
+				fetch -> parse -> scale
+			`,
+			axes:        []Axis{{Task: "parse", Values: []string{"1", "2"}}},
+			wantSkipped: []string{"fetch"},
+			wantFinal:   []string{"scale"},
+			wantValues:  []map[string]string{{"scale": "2"}, {"scale": "4"}},
+		},
+		{
+			name: "upstream that feeds other tasks still runs",
+			spec: `
+				fetch [type=multiply input="1" times="3"]
+				parse [type=multiply input="$(fetch)" times="1"]
+				other [type=multiply input="$(fetch)" times="1"]
+				fetch -> parse
+				fetch -> other
+			`,
+			axes:        []Axis{{Task: "parse", Values: []string{"5"}}},
+			wantSkipped: []string{},
+			wantFinal:   []string{"parse", "other"},
+			wantValues:  []map[string]string{{"parse": "5", "other": "3"}},
+		},
+		{
+			name: "var axis",
+			spec: `
+				scale [type=multiply input="$(price)" times="2"]
+			`,
+			vars:        map[string]interface{}{"price": 0},
+			axes:        []Axis{{Var: "price", From: "1", To: "3", Step: "1"}},
+			wantSkipped: []string{},
+			wantFinal:   []string{"scale"},
+			wantValues:  []map[string]string{{"scale": "2"}, {"scale": "4"}, {"scale": "6"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sweep, err := RunSweep(context.Background(), mustParse(t, test.spec), test.vars, nil, test.axes, RunOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(sweep.Skipped, test.wantSkipped) {
+				t.Errorf("skipped %v, want %v", sweep.Skipped, test.wantSkipped)
+			}
+			// Tasks on separate branches can run in either order
+			sort.Strings(sweep.Final)
+			sort.Strings(test.wantFinal)
+			if !reflect.DeepEqual(sweep.Final, test.wantFinal) {
+				t.Errorf("final %v, want %v", sweep.Final, test.wantFinal)
+			}
+			if len(sweep.Rows) != len(test.wantValues) {
+				t.Fatalf("%d rows, want %d", len(sweep.Rows), len(test.wantValues))
+			}
+			for i, row := range sweep.Rows {
+				for _, id := range test.wantSkipped {
+					if _, ok := row.Values[id]; ok {
+						t.Errorf("row %d has a value for skipped task %s", i, id)
+					}
+				}
+				for id, want := range test.wantValues[i] {
+					if got := row.Values[id]; got != want {
+						t.Errorf("row %d: %s is %q, want %q", i, id, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunSweepAxes(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]interface{}
+		axes []Axis
+		// wantErr is part of the error, if it matters
+		wantErr string
+	}{
+		{name: "no axes"},
+		{name: "three axes", axes: []Axis{{Var: "a", Values: []string{"1"}}, {Var: "b", Values: []string{"1"}}, {Var: "c", Values: []string{"1"}}}},
+		{name: "var and task", axes: []Axis{{Var: "a", Task: "scale", Values: []string{"1"}}}},
+		{name: "unknown task", axes: []Axis{{Task: "missing", Values: []string{"1"}}}},
+		{name: "zero step", axes: []Axis{{Var: "a", From: "1", To: "2", Step: "0"}}},
+		{name: "too many rows", axes: []Axis{{Var: "a", From: "1", To: "100", Step: "1"}, {Var: "b", From: "1", To: "100", Step: "1"}}},
+		{
+			name:    "var nested in a value that isn't a map",
+			vars:    map[string]interface{}{"price": "1"},
+			axes:    []Axis{{Var: "price.usd", Values: []string{"1"}}},
+			wantErr: "axis price.usd",
+		},
+		{name: "empty key in the var", axes: []Axis{{Var: "price..usd", Values: []string{"1"}}}, wantErr: "axis price..usd"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := mustParse(t, `scale [type=multiply input="1" times="2"]`)
+			_, err := RunSweep(context.Background(), p, test.vars, nil, test.axes, RunOptions{})
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("error %q, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,542 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/config"
+	"github.com/pickleyd/chainlink/core/logger"
+	"github.com/pickleyd/chainlink/core/null"
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+type TaskType string
+
+func (t TaskType) String() string {
+	return string(t)
+}
+
+const (
+	TaskTypeHTTP      TaskType = "http"
+	TaskTypeBridge    TaskType = "bridge"
+	TaskTypeMean      TaskType = "mean"
+	TaskTypeMedian    TaskType = "median"
+	TaskTypeMode      TaskType = "mode"
+	TaskTypeSum       TaskType = "sum"
+	TaskTypeMultiply  TaskType = "multiply"
+	TaskTypeDivide    TaskType = "divide"
+	TaskTypeJSONParse TaskType = "jsonparse"
+	TaskTypeCBORParse TaskType = "cborparse"
+	TaskTypeAny       TaskType = "any"
+	// TaskTypeVRF              TaskType = "vrf"
+	// TaskTypeVRFV2            TaskType = "vrfv2"
+	// TaskTypeEstimateGasLimit TaskType = "estimategaslimit"
+	TaskTypeETHCall      TaskType = "ethcall"
+	TaskTypeETHTx        TaskType = "ethtx"
+	TaskTypeETHABIEncode TaskType = "ethabiencode"
+	// TaskTypeETHABIEncode2    TaskType = "ethabiencode2"
+	TaskTypeETHABIDecode    TaskType = "ethabidecode"
+	TaskTypeETHABIDecodeLog TaskType = "ethabidecodelog"
+	TaskTypeMerge           TaskType = "merge"
+	TaskTypeLowercase       TaskType = "lowercase"
+	TaskTypeUppercase       TaskType = "uppercase"
+	TaskTypeConditional     TaskType = "conditional"
+	TaskTypeHexDecode       TaskType = "hexdecode"
+	TaskTypeHexEncode       TaskType = "hexencode"
+	TaskTypeBase64Decode    TaskType = "base64decode"
+	TaskTypeBase64Encode    TaskType = "base64encode"
+	TaskTypeLessThan        TaskType = "lessthan"
+	TaskTypeLength          TaskType = "length"
+	TaskTypeLookup          TaskType = "lookup"
+	// // Testing only.
+	// TaskTypePanic TaskType = "panic"
+	// TaskTypeMemo  TaskType = "memo"
+	// TaskTypeFail  TaskType = "fail"
+)
+
+// NewTask builds a runnable task of the given type from its options, as sent
+// by the frontend for a single task run.
+func NewTask(taskType TaskType, options map[string]interface{}) (pipeline.Task, error) {
+
+	// seeing as we're just running a single task with no context
+	// we can just use an empty base task, bar the attributes every task has
+	baseTask, options, err := baseTaskFromOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert map to json
+	jsonString, _ := json.Marshal(options)
+
+	return newTask(taskType, baseTask, jsonString)
+}
+
+// baseTaskFromOptions pulls the attributes shared by all tasks out of the
+// options. They are written as strings in the spec (e.g. timeout="10s"), which
+// can't be unmarshalled into the BaseTask fields directly.
+func baseTaskFromOptions(options map[string]interface{}) (pipeline.BaseTask, map[string]interface{}, error) {
+	baseTask := pipeline.NewBaseTask(0, "", nil, nil, 0)
+
+	remaining := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		remaining[k] = v
+	}
+
+	if raw, ok := remaining["timeout"]; ok {
+		delete(remaining, "timeout")
+		timeout, err := durationOption("timeout", raw)
+		if err != nil {
+			return baseTask, nil, err
+		}
+		if timeout > 0 {
+			baseTask.Timeout = &timeout
+		}
+	}
+
+	if raw, ok := remaining["retries"]; ok {
+		delete(remaining, "retries")
+		retries, err := uintOption("retries", raw)
+		if err != nil {
+			return baseTask, nil, err
+		}
+		baseTask.Retries = null.Uint32From(retries)
+	}
+
+	if raw, ok := remaining["minBackoff"]; ok {
+		delete(remaining, "minBackoff")
+		minBackoff, err := durationOption("minBackoff", raw)
+		if err != nil {
+			return baseTask, nil, err
+		}
+		baseTask.MinBackoff = minBackoff
+	}
+
+	if raw, ok := remaining["maxBackoff"]; ok {
+		delete(remaining, "maxBackoff")
+		maxBackoff, err := durationOption("maxBackoff", raw)
+		if err != nil {
+			return baseTask, nil, err
+		}
+		baseTask.MaxBackoff = maxBackoff
+	}
+
+	return baseTask, remaining, nil
+}
+
+func uintOption(name string, raw interface{}) (uint32, error) {
+	switch v := raw.(type) {
+	case float64:
+		if v < 0 || v != float64(uint32(v)) {
+			return 0, fmt.Errorf("%s must be a whole number, got %v", name, v)
+		}
+		return uint32(v), nil
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		return uint32(n), nil
+	}
+	return 0, fmt.Errorf("%s must be a whole number, got %T", name, raw)
+}
+
+func durationOption(name string, raw interface{}) (time.Duration, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a duration string such as \"10s\", got %T", name, raw)
+	}
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
+
+// NewTaskFromParsed builds a runnable copy of a task produced by
+// pipeline.Parse. The parsed task keeps its attributes but lacks the
+// dependencies (http clients, config) a node would normally inject.
+func NewTaskFromParsed(parsed pipeline.Task) (pipeline.Task, error) {
+	jsonString, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask(TaskType(parsed.Type()), *parsed.Base(), jsonString)
+}
+
+func newTask(taskType TaskType, baseTask pipeline.BaseTask, jsonString []byte) (pipeline.Task, error) {
+
+	var task pipeline.Task
+	switch taskType {
+	// case TaskTypePanic:
+	// 	task = &PanicTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
+	case TaskTypeHTTP:
+		var opts pipeline.HTTPTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		httpTask := pipeline.HTTPTask{
+			BaseTask:                       baseTask,
+			Method:                         opts.Method,
+			URL:                            opts.URL,
+			RequestData:                    opts.RequestData,
+			AllowUnrestrictedNetworkAccess: opts.AllowUnrestrictedNetworkAccess,
+			Headers:                        opts.Headers,
+		}
+
+		config := config.NewGeneralConfig(logger.NullLogger)
+
+		c := http.DefaultClient
+		httpTask.HelperSetDependencies(config, c, c)
+
+		task = &httpTask
+
+	case TaskTypeBridge:
+		var opts pipeline.BridgeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.BridgeTask{
+			BaseTask:          baseTask,
+			Name:              opts.Name,
+			RequestData:       opts.RequestData,
+			IncludeInputAtKey: opts.IncludeInputAtKey,
+			Async:             opts.Async,
+			CacheTTL:          opts.CacheTTL,
+		}
+	case TaskTypeMean:
+		var opts pipeline.MeanTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.MeanTask{
+			BaseTask:      baseTask,
+			Values:        opts.Values,
+			AllowedFaults: opts.AllowedFaults,
+			Precision:     opts.Precision,
+		}
+	case TaskTypeMedian:
+		var opts pipeline.MedianTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.MedianTask{
+			BaseTask:      baseTask,
+			Values:        opts.Values,
+			AllowedFaults: opts.AllowedFaults,
+		}
+	case TaskTypeMode:
+		var opts pipeline.ModeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.ModeTask{
+			BaseTask:      baseTask,
+			Values:        opts.Values,
+			AllowedFaults: opts.AllowedFaults,
+		}
+	case TaskTypeSum:
+		var opts pipeline.SumTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.SumTask{
+			BaseTask:      baseTask,
+			Values:        opts.Values,
+			AllowedFaults: opts.AllowedFaults,
+		}
+	case TaskTypeAny:
+		task = &pipeline.AnyTask{BaseTask: baseTask}
+	case TaskTypeJSONParse:
+		var opts pipeline.JSONParseTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.JSONParseTask{
+			BaseTask:  baseTask,
+			Path:      opts.Path,
+			Separator: opts.Separator,
+			Data:      opts.Data,
+			Lax:       opts.Lax,
+		}
+	// case TaskTypeMemo:
+	// 	task = &MemoTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
+	case TaskTypeMultiply:
+		var opts pipeline.MultiplyTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.MultiplyTask{
+			BaseTask: baseTask,
+			Times:    opts.Times,
+		}
+	case TaskTypeDivide:
+		var opts pipeline.DivideTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.DivideTask{
+			BaseTask:  baseTask,
+			Divisor:   opts.Divisor,
+			Precision: opts.Precision,
+		}
+	// case TaskTypeVRF:
+	// 	task = &VRFTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
+	// case TaskTypeVRFV2:
+	// 	task = &VRFTaskV2{BaseTask: BaseTask{id: ID, dotID: dotID}}
+	// case TaskTypeEstimateGasLimit:
+	// 	task = &EstimateGasLimitTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
+	case TaskTypeETHCall:
+		var opts pipeline.ETHCallTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.ETHCallTask{
+			BaseTask:            baseTask,
+			From:                opts.From,
+			Data:                opts.Data,
+			EVMChainID:          opts.EVMChainID,
+			Contract:            opts.Contract,
+			Gas:                 opts.Gas,
+			GasPrice:            opts.GasPrice,
+			GasTipCap:           opts.GasTipCap,
+			GasFeeCap:           opts.GasFeeCap,
+			GasUnlimited:        opts.GasUnlimited,
+			ExtractRevertReason: opts.ExtractRevertReason,
+
+			// CUSTOM
+			SpecGasLimit: opts.SpecGasLimit,
+		}
+	case TaskTypeETHTx:
+		var opts pipeline.ETHTxTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.ETHTxTask{
+			BaseTask:         baseTask,
+			From:             opts.From,
+			To:               opts.To,
+			Data:             opts.Data,
+			GasLimit:         opts.GasLimit,
+			TxMeta:           opts.TxMeta,
+			MinConfirmations: opts.MinConfirmations,
+			FailOnRevert:     opts.FailOnRevert,
+			EVMChainID:       opts.EVMChainID,
+			TransmitChecker:  opts.TransmitChecker,
+		}
+	case TaskTypeETHABIEncode:
+		var opts pipeline.ETHABIEncodeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.ETHABIEncodeTask{
+			BaseTask: baseTask,
+			ABI:      opts.ABI,
+			Data:     opts.Data,
+		}
+	// case TaskTypeETHABIEncode2:
+	// 	task = &ETHABIEncodeTask2{BaseTask: BaseTask{id: ID, dotID: dotID}}
+	case TaskTypeETHABIDecode:
+		var opts pipeline.ETHABIDecodeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.ETHABIDecodeTask{
+			BaseTask: baseTask,
+			ABI:      opts.ABI,
+			Data:     opts.Data,
+		}
+	case TaskTypeETHABIDecodeLog:
+		var opts pipeline.ETHABIDecodeLogTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.ETHABIDecodeLogTask{
+			BaseTask: baseTask,
+			ABI:      opts.ABI,
+			Data:     opts.Data,
+			Topics:   opts.Topics,
+		}
+	case TaskTypeCBORParse:
+		var opts pipeline.CBORParseTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.CBORParseTask{
+			BaseTask: baseTask,
+			Data:     opts.Data,
+			Mode:     opts.Mode,
+		}
+	// case TaskTypeFail:
+	// 	task = &FailTask{BaseTask: BaseTask{id: ID, dotID: dotID}}
+	case TaskTypeMerge:
+		var opts pipeline.MergeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.MergeTask{
+			BaseTask: baseTask,
+			Left:     opts.Left,
+			Right:    opts.Right,
+		}
+	case TaskTypeLowercase:
+		var opts pipeline.LowercaseTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.LowercaseTask{
+			BaseTask: baseTask,
+			Input:    opts.Input,
+		}
+	case TaskTypeUppercase:
+		var opts pipeline.UppercaseTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.UppercaseTask{
+			BaseTask: baseTask,
+			Input:    opts.Input,
+		}
+	case TaskTypeConditional:
+		var opts pipeline.ConditionalTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.ConditionalTask{
+			BaseTask: baseTask,
+			Data:     opts.Data,
+		}
+	case TaskTypeHexEncode:
+		var opts pipeline.HexEncodeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.HexEncodeTask{
+			BaseTask: baseTask,
+			Input:    opts.Input,
+		}
+	case TaskTypeHexDecode:
+		var opts pipeline.HexDecodeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.HexDecodeTask{
+			BaseTask: baseTask,
+			Input:    opts.Input,
+		}
+	case TaskTypeBase64Encode:
+		var opts pipeline.Base64EncodeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.Base64EncodeTask{
+			BaseTask: baseTask,
+			Input:    opts.Input,
+		}
+	case TaskTypeBase64Decode:
+		var opts pipeline.Base64DecodeTask
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.Base64DecodeTask{
+			BaseTask: baseTask,
+			Input:    opts.Input,
+		}
+	case TaskTypeLessThan:
+		var opts pipeline.LessThanTask
+
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.LessThanTask{
+			BaseTask: baseTask,
+			Left:     opts.Left,
+			Right:    opts.Right,
+		}
+	case TaskTypeLength:
+		var opts pipeline.LengthTask
+
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.LengthTask{
+			BaseTask: baseTask,
+			Input:    opts.Input,
+		}
+	case TaskTypeLookup:
+		var opts pipeline.LookupTask
+
+		if err := json.Unmarshal(jsonString, &opts); err != nil {
+			return nil, err
+		}
+
+		task = &pipeline.LookupTask{
+			BaseTask: baseTask,
+			Key:      opts.Key,
+		}
+	default:
+		return nil, fmt.Errorf(`unknown task type: "%v"`, taskType)
+	}
+
+	return task, nil
+}
+
+// resolveAttr resolves an attribute that is either a single var reference or
+// a literal, as tasks resolve most of theirs with pipeline.VarExpr and
+// pipeline.NonemptyString. An empty attribute resolves to nil.
+func resolveAttr(attr string, vars map[string]interface{}) (interface{}, error) {
+	var value anyParam
+	err := pipeline.ResolveParam(&value, pipeline.From(
+		pipeline.VarExpr(attr, pipeline.NewVarsFrom(vars)),
+		pipeline.NonemptyString(attr),
+	))
+	if errors.Is(err, pipeline.ErrParameterEmpty) {
+		return nil, nil
+	}
+	return value.value, err
+}
+
+// anyParam keeps a resolved attribute as it is, for the caller to convert.
+type anyParam struct {
+	value interface{}
+}
+
+func (p *anyParam) UnmarshalPipelineParam(val interface{}) error {
+	p.value = val
+	return nil
+}
@@ -0,0 +1,242 @@
+package simulator
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/shopspring/decimal"
+)
+
+// Var describes a value by its type, as exchanged with the var-helper. Value
+// holds a single value and Values a flat array of them. Keep is used as is,
+// without any conversion.
+type Var struct {
+	Value    string      `yaml:"value,omitempty"`
+	Values   []string    `yaml:"values,omitempty"`
+	Type     string      `yaml:"type,omitempty"`
+	FromType string      `yaml:"fromType,omitempty"`
+	Keep     interface{} `yaml:"keep,omitempty"`
+}
+
+// IsSet reports whether the var holds anything to convert.
+func (v Var) IsSet() bool {
+	return v.Value != "" || v.Values != nil || v.Keep != nil
+}
+
+// ConvertVar converts the var to the Go type a Chainlink node would hold for
+// it, e.g. *big.Int for "int" or common.Address for "address".
+func ConvertVar(v Var) (interface{}, error) {
+	// TODO: Handle deeper nesting using recursion?
+	if v.Keep != nil {
+		return v.Keep, nil
+	}
+
+	var convert func(string, string) (interface{}, error)
+	switch v.Type {
+	case "string":
+		if v.Value != "" {
+			return v.Value, nil
+		} else if len(v.Values) > 0 {
+			return v.Values, nil
+		}
+	case "bytes32":
+		if v.Value != "" {
+			return toBytes32(v.Value), nil
+		} else if len(v.Values) > 0 {
+			s := [][32]byte{}
+			for _, val := range v.Values {
+				s = append(s, toBytes32(val))
+			}
+			return s, nil
+		}
+	case "bytes":
+		convert = toBytes
+	case "int":
+		convert = toInt
+	case "float":
+		convert = toFloat
+	case "decimal":
+		convert = parseDecimal
+	case "bool":
+		convert = toBool
+	case "address":
+		convert = toAddress
+	case "null":
+		return nil, nil
+	}
+
+	if convert != nil {
+		if v.Value != "" {
+			return convert(v.Value, v.FromType)
+		} else if len(v.Values) > 0 {
+			var s []interface{}
+			for _, val := range v.Values {
+				converted, err := convert(val, v.FromType)
+				if err != nil {
+					return nil, err
+				}
+				s = append(s, converted)
+			}
+			return typedSlice(v.Type, s), nil
+		}
+	}
+
+	// Empty values and types without a conversion are passed through as is
+	return v.Value, nil
+}
+
+// ConvertVars converts each of the vars.
+func ConvertVars(vars map[string]Var) (map[string]interface{}, error) {
+	converted := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		value, err := ConvertVar(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		converted[k] = value
+	}
+	return converted, nil
+}
+
+// BuildVars converts the vars and nests jobRun and jobSpec under their keys,
+// the same way the var-helper does. Both are always present, if empty.
+func BuildVars(vars, jobRun, jobSpec map[string]Var) (map[string]interface{}, error) {
+	varValues, err := ConvertVars(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if varValues["jobRun"], err = ConvertVars(jobRun); err != nil {
+		return nil, fmt.Errorf("jobRun.%w", err)
+	}
+
+	if varValues["jobSpec"], err = ConvertVars(jobSpec); err != nil {
+		return nil, fmt.Errorf("jobSpec.%w", err)
+	}
+
+	return varValues, nil
+}
+
+// LoadVar converts the var and normalizes it, giving the value a task sees
+// when the var is prepared by the var-helper.
+func LoadVar(v Var) (interface{}, error) {
+	converted, err := ConvertVar(v)
+	if err != nil {
+		return nil, err
+	}
+	return Normalize(converted)
+}
+
+// LoadVars is LoadVar for the vars, jobRun and jobSpec of BuildVars.
+func LoadVars(vars, jobRun, jobSpec map[string]Var) (map[string]interface{}, error) {
+	built, err := BuildVars(vars, jobRun, jobSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	vars64, err := ToBase64(built)
+	if err != nil {
+		return nil, err
+	}
+	return VarsFromBase64(vars64)
+}
+
+// typedSlice turns the converted values into a slice of their own type, as
+// that is what the tasks expect.
+func typedSlice(varType string, values []interface{}) interface{} {
+	switch varType {
+	case "bytes":
+		s := [][]byte{}
+		for _, v := range values {
+			s = append(s, v.([]byte))
+		}
+		return s
+	case "int":
+		s := []*big.Int{}
+		for _, v := range values {
+			s = append(s, v.(*big.Int))
+		}
+		return s
+	case "float":
+		s := []float64{}
+		for _, v := range values {
+			s = append(s, v.(float64))
+		}
+		return s
+	case "decimal":
+		s := []decimal.Decimal{}
+		for _, v := range values {
+			s = append(s, v.(decimal.Decimal))
+		}
+		return s
+	case "bool":
+		s := []bool{}
+		for _, v := range values {
+			s = append(s, v.(bool))
+		}
+		return s
+	case "address":
+		s := []common.Address{}
+		for _, v := range values {
+			s = append(s, v.(common.Address))
+		}
+		return s
+	}
+	return values
+}
+
+func toInt(s string, _ string) (interface{}, error) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an int", s)
+	}
+	return n, nil
+}
+
+func toFloat(s string, _ string) (interface{}, error) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a float", s)
+	}
+	return n, nil
+}
+
+func parseDecimal(s string, _ string) (interface{}, error) {
+	n, err := decimal.NewFromString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a decimal", s)
+	}
+	return n, nil
+}
+
+func toAddress(s string, _ string) (interface{}, error) {
+	return common.HexToAddress(s), nil
+}
+
+func toBool(s string, _ string) (interface{}, error) {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a bool", s)
+	}
+	return b, nil
+}
+
+func toBytes32(s string) [32]byte {
+	var bytes32 [32]byte
+	copy(bytes32[:], []byte(s))
+	return bytes32
+}
+
+func toBytes(s string, fromType string) (interface{}, error) {
+	if fromType == "hex" {
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not hex: %w", s, err)
+		}
+		return b, nil
+	}
+	return []byte(s), nil
+}
@@ -0,0 +1,51 @@
+package simulator
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+)
+
+func TestConvertVar(t *testing.T) {
+	address := common.HexToAddress("0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419")
+
+	tests := []struct {
+		name    string
+		v       Var
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "string", v: Var{Value: "abc", Type: "string"}, want: "abc"},
+		{name: "strings", v: Var{Values: []string{"a", "b"}, Type: "string"}, want: []string{"a", "b"}},
+		{name: "empty string", v: Var{Type: "string"}, want: ""},
+		{name: "int", v: Var{Value: "123", Type: "int"}, want: big.NewInt(123)},
+		{name: "ints", v: Var{Values: []string{"1", "2"}, Type: "int"}, want: []*big.Int{big.NewInt(1), big.NewInt(2)}},
+		{name: "bad int", v: Var{Value: "1.5", Type: "int"}, wantErr: true},
+		{name: "float", v: Var{Value: "1.5", Type: "float"}, want: 1.5},
+		{name: "decimal", v: Var{Value: "1.50", Type: "decimal"}, want: decimal.RequireFromString("1.50")},
+		{name: "bool", v: Var{Value: "true", Type: "bool"}, want: true},
+		{name: "bad bool", v: Var{Value: "yes", Type: "bool"}, wantErr: true},
+		{name: "address", v: Var{Value: address.Hex(), Type: "address"}, want: address},
+		{name: "bytes", v: Var{Value: "ab", Type: "bytes"}, want: []byte("ab")},
+		{name: "hex bytes", v: Var{Value: "0x0102", Type: "bytes", FromType: "hex"}, want: []byte{1, 2}},
+		{name: "bad hex bytes", v: Var{Value: "0xzz", Type: "bytes", FromType: "hex"}, wantErr: true},
+		{name: "null", v: Var{Value: "x", Type: "null"}, want: nil},
+		{name: "keep", v: Var{Keep: map[string]interface{}{"a": 1}}, want: map[string]interface{}{"a": 1}},
+		{name: "no type", v: Var{Value: "abc"}, want: "abc"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ConvertVar(test.v)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want an error: %v", err, test.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
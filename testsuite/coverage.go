@@ -0,0 +1,313 @@
+package testsuite
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+// Branches of the tasks that have them. A conditional either lets its
+// downstream tasks run or fails them, and an aggregating task either sees no
+// errored values, tolerates them within allowedFaults or fails.
+const (
+	BranchTrue            = "true"
+	BranchFalse           = "false"
+	BranchNoFaults        = "no faults"
+	BranchFaultsTolerated = "faults tolerated"
+	BranchFaultsExceeded  = "faults exceeded"
+)
+
+func branchesOf(taskType pipeline.TaskType) []string {
+	switch simulator.TaskType(taskType) {
+	case simulator.TaskTypeConditional:
+		return []string{BranchTrue, BranchFalse}
+	case simulator.TaskTypeMedian, simulator.TaskTypeMean, simulator.TaskTypeMode, simulator.TaskTypeSum:
+		return []string{BranchNoFaults, BranchFaultsTolerated, BranchFaultsExceeded}
+	}
+	return nil
+}
+
+// Coverage collects what the pipeline tests exercised of each spec they ran.
+type Coverage struct {
+	Specs []*SpecCoverage `json:"specs"`
+	index map[string]*SpecCoverage
+}
+
+// SpecCoverage is what the tests of one spec exercised. Specs are told apart
+// by the spec file they were read from, or by the suite, and test, that
+// inlines them.
+type SpecCoverage struct {
+	Spec   string          `json:"spec"`
+	Tests  []string        `json:"tests"`
+	Tasks  []*TaskCoverage `json:"tasks"`
+	Totals CoverageTotals  `json:"totals"`
+
+	pipeline *pipeline.Pipeline
+}
+
+// TaskCoverage counts the runs of a task across tests. A run is counted as
+// failed when the task errored, which is its error path.
+type TaskCoverage struct {
+	Id       string   `json:"id"`
+	Type     string   `json:"type"`
+	Ran      int      `json:"ran"`
+	Mocked   int      `json:"mocked"`
+	Failed   int      `json:"failed"`
+	Skipped  int      `json:"skipped"`
+	Branches []Branch `json:"branches"`
+}
+
+// Branch is a path through a task and the number of runs that took it.
+type Branch struct {
+	Name string `json:"name"`
+	Hits int    `json:"hits"`
+}
+
+// CoverageTotals count the tasks that ran, the branches taken and the tasks
+// whose error path was hit, out of the spec's.
+type CoverageTotals struct {
+	Tasks         int `json:"tasks"`
+	TasksRun      int `json:"tasksRun"`
+	Branches      int `json:"branches"`
+	BranchesTaken int `json:"branchesTaken"`
+	ErrorPaths    int `json:"errorPaths"`
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{
+		Specs: []*SpecCoverage{},
+		index: map[string]*SpecCoverage{},
+	}
+}
+
+// Add counts the runs of the suite's pipeline tests, given their results in
+// the order Run returns them.
+func (c *Coverage) Add(suite *Suite, results []Result) {
+	for i, result := range results {
+		if result.Run == nil || i >= len(suite.Tests) {
+			continue
+		}
+		test := suite.Tests[i]
+
+		key := specKey(suite, test)
+		spec, ok := c.index[key]
+		if !ok {
+			spec = newSpecCoverage(key, result.Run.Pipeline)
+			c.index[key] = spec
+			c.Specs = append(c.Specs, spec)
+		}
+		spec.add(suite.Name+" - "+test.Name, result.Run)
+	}
+}
+
+func specKey(suite *Suite, test Test) string {
+	file := suite.File
+	if file == "" {
+		file = suite.Name
+	}
+
+	switch {
+	case test.SpecFile != "":
+		return suite.resolve(test.SpecFile)
+	case test.Spec != "":
+		return file + "#" + test.Name
+	case suite.SpecFile != "":
+		return suite.resolve(suite.SpecFile)
+	}
+	return file
+}
+
+func newSpecCoverage(key string, p *pipeline.Pipeline) *SpecCoverage {
+	spec := &SpecCoverage{
+		Spec:     key,
+		Tests:    []string{},
+		Tasks:    []*TaskCoverage{},
+		pipeline: p,
+	}
+	for _, task := range p.Tasks {
+		tc := &TaskCoverage{
+			Id:       task.DotID(),
+			Type:     task.Type().String(),
+			Branches: []Branch{},
+		}
+		for _, name := range branchesOf(task.Type()) {
+			tc.Branches = append(tc.Branches, Branch{Name: name})
+		}
+		spec.Tasks = append(spec.Tasks, tc)
+	}
+	spec.total()
+	return spec
+}
+
+func (s *SpecCoverage) add(test string, run *simulator.Run) {
+	s.Tests = append(s.Tests, test)
+
+	outcomes := map[string]simulator.TaskOutcome{}
+	for _, outcome := range run.Summarize().Tasks {
+		outcomes[outcome.Id] = outcome
+	}
+
+	for _, tc := range s.Tasks {
+		outcome, ok := outcomes[tc.Id]
+		if !ok {
+			continue
+		}
+
+		switch outcome.Status {
+		case simulator.StatusPending:
+			continue
+		case simulator.StatusSkipped:
+			tc.Skipped++
+			continue
+		case simulator.StatusFailed:
+			tc.Failed++
+		}
+		tc.Ran++
+		if taskRun, ok := run.Results[tc.Id]; ok && taskRun.Mocked {
+			tc.Mocked++
+		}
+
+		if branch := takenBranch(tc, outcome); branch != "" {
+			for i := range tc.Branches {
+				if tc.Branches[i].Name == branch {
+					tc.Branches[i].Hits++
+				}
+			}
+		}
+	}
+
+	s.total()
+}
+
+// takenBranch returns the branch the run of the task took, if it has
+// branches and it can be told.
+func takenBranch(tc *TaskCoverage, outcome simulator.TaskOutcome) string {
+	if simulator.TaskType(tc.Type) == simulator.TaskTypeConditional {
+		if outcome.Status == simulator.StatusSucceeded {
+			return BranchTrue
+		}
+		return BranchFalse
+	}
+
+	switch faults := outcome.Faults; {
+	case faults == nil:
+		return ""
+	case faults.Exceeded:
+		return BranchFaultsExceeded
+	case faults.Faults > 0:
+		return BranchFaultsTolerated
+	}
+	return BranchNoFaults
+}
+
+func (s *SpecCoverage) total() {
+	totals := CoverageTotals{Tasks: len(s.Tasks)}
+	for _, tc := range s.Tasks {
+		if tc.Ran > 0 {
+			totals.TasksRun++
+		}
+		if tc.Failed > 0 {
+			totals.ErrorPaths++
+		}
+		for _, branch := range tc.Branches {
+			totals.Branches++
+			if branch.Hits > 0 {
+				totals.BranchesTaken++
+			}
+		}
+	}
+	s.Totals = totals
+}
+
+// Untested lists the tasks that never ran and the branches never taken, as
+// "task" or "task: branch".
+func (s *SpecCoverage) Untested() []string {
+	untested := []string{}
+	for _, tc := range s.Tasks {
+		if tc.Ran == 0 {
+			untested = append(untested, tc.Id)
+			continue
+		}
+		for _, branch := range tc.Branches {
+			if branch.Hits == 0 {
+				untested = append(untested, tc.Id+": "+branch.Name)
+			}
+		}
+	}
+	return untested
+}
+
+// Colours of the tasks in the annotated graph
+const (
+	colorCovered   = "#d4edda"
+	colorPartial   = "#fff3cd"
+	colorUncovered = "#f8d7da"
+)
+
+func (tc *TaskCoverage) color() string {
+	if tc.Ran == 0 {
+		return colorUncovered
+	}
+	for _, branch := range tc.Branches {
+		if branch.Hits == 0 {
+			return colorPartial
+		}
+	}
+	return colorCovered
+}
+
+func (tc *TaskCoverage) label() string {
+	lines := []string{
+		fmt.Sprintf("%s (%s)", tc.Id, tc.Type),
+		fmt.Sprintf("ran %d, failed %d", tc.Ran, tc.Failed),
+	}
+	if tc.Skipped > 0 {
+		lines = append(lines, fmt.Sprintf("skipped %d", tc.Skipped))
+	}
+	for _, branch := range tc.Branches {
+		lines = append(lines, fmt.Sprintf("%s: %d", branch.Name, branch.Hits))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteDOT writes the spec's pipeline as DOT, with each task labelled with
+// its runs and branches and coloured green when fully covered, yellow when a
+// branch was never taken and red when it never ran. Edges that don't pass a
+// result are dashed.
+func (s *SpecCoverage) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", s.Spec)
+	b.WriteString("  node [shape=box, style=filled];\n")
+
+	for _, tc := range s.Tasks {
+		fmt.Fprintf(&b, "  %q [label=%q, fillcolor=%q];\n", tc.Id, tc.label(), tc.color())
+	}
+	for _, task := range s.pipeline.Tasks {
+		for _, input := range task.Inputs() {
+			style := ""
+			if !input.PropagateResult {
+				style = " [style=dashed]"
+			}
+			fmt.Fprintf(&b, "  %q -> %q%s;\n", input.InputTask.DotID(), task.DotID(), style)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteText writes a line of totals per spec, followed by what is untested.
+func (c *Coverage) WriteText(w io.Writer) {
+	for _, spec := range c.Specs {
+		t := spec.Totals
+		fmt.Fprintf(w, "%s: %d/%d tasks ran, %d/%d branches taken, %d/%d error paths hit, by %d tests\n",
+			spec.Spec, t.TasksRun, t.Tasks, t.BranchesTaken, t.Branches, t.ErrorPaths, t.Tasks, len(spec.Tests))
+		if untested := spec.Untested(); len(untested) > 0 {
+			fmt.Fprintf(w, "  untested: %s\n", strings.Join(untested, ", "))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package testsuite
+
+import (
+	"html/template"
+	"io"
+	"strings"
+)
+
+var coverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Spec coverage</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.missed { color: #a00; font-weight: bold; }
+pre { background: #f6f6f6; padding: 1em; overflow: auto; }
+</style>
+</head>
+<body>
+<h1>Spec coverage</h1>
+{{range .Specs}}
+<h2>{{.Spec}}</h2>
+<p>
+  {{.Totals.TasksRun}}/{{.Totals.Tasks}} tasks ran,
+  {{.Totals.BranchesTaken}}/{{.Totals.Branches}} branches taken,
+  {{.Totals.ErrorPaths}}/{{.Totals.Tasks}} error paths hit,
+  by {{len .Tests}} tests
+</p>
+<table>
+<tr><th>Task</th><th>Type</th><th>Ran</th><th>Mocked</th><th>Failed</th><th>Skipped</th><th>Branches</th></tr>
+{{range .Tasks}}
+<tr style="background: {{.Color}}">
+  <td>{{.Id}}</td>
+  <td>{{.Type}}</td>
+  <td>{{.Ran}}</td>
+  <td>{{.Mocked}}</td>
+  <td>{{.Failed}}</td>
+  <td>{{.Skipped}}</td>
+  <td>{{range .Branches}}<span{{if eq .Hits 0}} class="missed"{{end}}>{{.Name}}: {{.Hits}}</span><br>{{end}}</td>
+</tr>
+{{end}}
+</table>
+<details>
+<summary>Tests</summary>
+<ul>{{range .Tests}}<li>{{.}}</li>{{end}}</ul>
+</details>
+<details>
+<summary>Annotated DOT</summary>
+<pre>{{.DOT}}</pre>
+</details>
+{{end}}
+</body>
+</html>
+`))
+
+type htmlSpec struct {
+	*SpecCoverage
+	Tasks []htmlTask
+	DOT   string
+}
+
+type htmlTask struct {
+	*TaskCoverage
+	Color template.CSS
+}
+
+// WriteHTML writes the coverage as a standalone HTML page with a table of the
+// tasks of each spec, coloured as in the annotated graph, and the graph's
+// DOT.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	specs := []htmlSpec{}
+	for _, spec := range c.Specs {
+		var dot strings.Builder
+		if err := spec.WriteDOT(&dot); err != nil {
+			return err
+		}
+
+		tasks := []htmlTask{}
+		for _, tc := range spec.Tasks {
+			tasks = append(tasks, htmlTask{TaskCoverage: tc, Color: template.CSS(tc.color())})
+		}
+		specs = append(specs, htmlSpec{SpecCoverage: spec, Tasks: tasks, DOT: dot.String()})
+	}
+
+	return coverageTemplate.Execute(w, struct{ Specs []htmlSpec }{specs})
+}
@@ -0,0 +1,175 @@
+package testsuite
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+func coveragePipeline() *pipeline.Pipeline {
+	return &pipeline.Pipeline{Tasks: []pipeline.Task{
+		&pipeline.AnyTask{BaseTask: pipeline.NewBaseTask(0, "fetch", nil, nil, 0)},
+		&pipeline.ConditionalTask{BaseTask: pipeline.NewBaseTask(1, "check", nil, nil, 1)},
+	}}
+}
+
+// coverageRun is a run of the coverage pipeline with the given task runs.
+func coverageRun(results map[string]*simulator.TaskRun) *simulator.Run {
+	run := simulator.NewRun(coveragePipeline(), nil)
+	run.Results = results
+	return run
+}
+
+func TestCoverage(t *testing.T) {
+	failed := pipeline.Result{Error: errors.New("failed")}
+
+	tests := []struct {
+		name         string
+		runs         []map[string]*simulator.TaskRun
+		wantCounts   map[string][4]int
+		wantUntested []string
+		wantTotals   CoverageTotals
+		wantColors   []string
+	}{
+		{
+			name:         "nothing ran",
+			runs:         []map[string]*simulator.TaskRun{{}},
+			wantCounts:   map[string][4]int{"fetch": {}, "check": {}},
+			wantUntested: []string{"fetch", "check"},
+			wantTotals:   CoverageTotals{Tasks: 2, Branches: 2},
+			wantColors:   []string{colorUncovered, colorUncovered},
+		},
+		{
+			name: "one branch",
+			runs: []map[string]*simulator.TaskRun{
+				{"fetch": {Mocked: true}, "check": {}},
+			},
+			wantCounts:   map[string][4]int{"fetch": {1, 1, 0, 0}, "check": {1, 0, 0, 0}},
+			wantUntested: []string{"check: false"},
+			wantTotals:   CoverageTotals{Tasks: 2, TasksRun: 2, Branches: 2, BranchesTaken: 1},
+			wantColors:   []string{colorCovered, colorPartial},
+		},
+		{
+			name: "both branches and an error path",
+			runs: []map[string]*simulator.TaskRun{
+				{"fetch": {}, "check": {}},
+				{"fetch": {Result: failed}, "check": {Result: failed}},
+			},
+			wantCounts:   map[string][4]int{"fetch": {2, 0, 1, 0}, "check": {2, 0, 1, 0}},
+			wantUntested: []string{},
+			wantTotals:   CoverageTotals{Tasks: 2, TasksRun: 2, Branches: 2, BranchesTaken: 2, ErrorPaths: 2},
+			wantColors:   []string{colorCovered, colorCovered},
+		},
+		{
+			name: "skipped",
+			runs: []map[string]*simulator.TaskRun{
+				{"fetch": {Result: failed}, "check": {Skipped: true}},
+			},
+			wantCounts:   map[string][4]int{"fetch": {1, 0, 1, 0}, "check": {0, 0, 0, 1}},
+			wantUntested: []string{"check"},
+			wantTotals:   CoverageTotals{Tasks: 2, TasksRun: 1, Branches: 2, ErrorPaths: 1},
+			wantColors:   []string{colorCovered, colorUncovered},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			suite := &Suite{Name: "price", File: "suites/price.test.yaml", SpecFile: "price.toml"}
+			results := []Result{}
+			for i, taskRuns := range test.runs {
+				suite.Tests = append(suite.Tests, Test{Name: fmt.Sprintf("run %d", i)})
+				results = append(results, Result{Run: coverageRun(taskRuns)})
+			}
+			// Tests that didn't run a pipeline aren't counted
+			suite.Tests = append(suite.Tests, Test{Name: "task test"})
+			results = append(results, Result{})
+
+			coverage := NewCoverage()
+			coverage.Add(suite, results)
+			if len(coverage.Specs) != 1 {
+				t.Fatalf("%d specs, want 1", len(coverage.Specs))
+			}
+			spec := coverage.Specs[0]
+
+			if spec.Spec != "suites/price.toml" {
+				t.Errorf("spec %q, want %q", spec.Spec, "suites/price.toml")
+			}
+			if len(spec.Tests) != len(test.runs) {
+				t.Errorf("%d tests, want %d", len(spec.Tests), len(test.runs))
+			}
+			for _, tc := range spec.Tasks {
+				got := [4]int{tc.Ran, tc.Mocked, tc.Failed, tc.Skipped}
+				if got != test.wantCounts[tc.Id] {
+					t.Errorf("%s ran, mocked, failed, skipped %v, want %v", tc.Id, got, test.wantCounts[tc.Id])
+				}
+			}
+			if untested := spec.Untested(); !reflect.DeepEqual(untested, test.wantUntested) {
+				t.Errorf("untested %q, want %q", untested, test.wantUntested)
+			}
+			if spec.Totals != test.wantTotals {
+				t.Errorf("totals %+v, want %+v", spec.Totals, test.wantTotals)
+			}
+
+			var dot strings.Builder
+			if err := spec.WriteDOT(&dot); err != nil {
+				t.Fatal(err)
+			}
+			for i, tc := range spec.Tasks {
+				if !strings.Contains(dot.String(), `fillcolor="`+test.wantColors[i]+`"`) || tc.color() != test.wantColors[i] {
+					t.Errorf("%s colour %s, want %s", tc.Id, tc.color(), test.wantColors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSpecKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		suite Suite
+		test  Test
+		want  string
+	}{
+		{
+			name:  "suite spec file",
+			suite: Suite{Name: "price", File: "suites/price.test.yaml", SpecFile: "../specs/price.toml"},
+			want:  "specs/price.toml",
+		},
+		{
+			name:  "test spec file",
+			suite: Suite{Name: "price", File: "suites/price.test.yaml", SpecFile: "price.toml"},
+			test:  Test{Name: "median", SpecFile: "/specs/median.toml"},
+			want:  "/specs/median.toml",
+		},
+		{
+			name:  "inline test spec",
+			suite: Suite{Name: "price", File: "suites/price.test.yaml", SpecFile: "price.toml"},
+			test:  Test{Name: "median", Spec: "a [type=any]"},
+			want:  "suites/price.test.yaml#median",
+		},
+		{
+			name:  "inline test spec without a file",
+			suite: Suite{Name: "price"},
+			test:  Test{Name: "median", Spec: "a [type=any]"},
+			want:  "price#median",
+		},
+		{
+			name:  "inline suite spec",
+			suite: Suite{Name: "price", File: "suites/price.test.yaml", Spec: "a [type=any]"},
+			want:  "suites/price.test.yaml",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := specKey(&test.suite, test.test); got != test.want {
+				t.Errorf("%q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package testsuite
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	File     string          `xml:"file,attr,omitempty"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit writes the report as JUnit XML, with a testsuite per suite and
+// the failures' diffs as the text of each failure.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	root := junitTestSuites{
+		Tests:    len(r.Results),
+		Failures: r.Failed,
+		Time:     junitTime(r.Duration),
+	}
+
+	// Suites are keyed by file as well as name, as suites in different
+	// directories may share a name
+	type suiteKey struct{ file, name string }
+	suites := map[suiteKey]*junitTestSuite{}
+	durations := map[suiteKey]time.Duration{}
+	order := []suiteKey{}
+
+	for _, result := range r.Results {
+		key := suiteKey{result.File, result.Suite}
+		suite, ok := suites[key]
+		if !ok {
+			suite = &junitTestSuite{Name: result.Suite, File: result.File}
+			suites[key] = suite
+			order = append(order, key)
+		}
+
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: result.Suite,
+			Time:      junitTime(result.Duration),
+		}
+
+		if !result.Passed {
+			suite.Failures++
+			messages := []string{}
+			for _, failure := range result.Failures {
+				messages = append(messages, failure.String())
+			}
+			testCase.Failure = &junitFailure{
+				Message: "failed",
+				Text:    strings.Join(messages, "\n\n"),
+			}
+			if len(result.Failures) > 0 {
+				testCase.Failure.Message = result.Failures[0].Message
+			}
+		}
+
+		suite.Tests++
+		durations[key] += result.Duration
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for _, key := range order {
+		suites[key].Time = junitTime(durations[key])
+		root.Suites = append(root.Suites, *suites[key])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
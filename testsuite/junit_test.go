@@ -0,0 +1,111 @@
+package testsuite
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	tests := []struct {
+		name         string
+		results      []Result
+		wantSuites   []string
+		wantTests    []int
+		wantFailures []int
+		// wantMessages are the failure messages of each case, in order, with
+		// "" for a case that passed
+		wantMessages []string
+	}{
+		{
+			name:    "no results",
+			results: nil,
+		},
+		{
+			name: "suites in order",
+			results: []Result{
+				{Suite: "price", File: "price.test.yaml", Name: "median", Passed: true, Duration: time.Second},
+				{Suite: "tx", Name: "encodes", Failures: []Failure{{Message: "value doesn't match", Want: "1", Got: "2"}}},
+				{Suite: "price", File: "price.test.yaml", Name: "mean", Failures: []Failure{{Message: "expected an error"}, {Message: "other"}}},
+			},
+			wantSuites:   []string{"price", "tx"},
+			wantTests:    []int{2, 1},
+			wantFailures: []int{1, 1},
+			wantMessages: []string{"", "expected an error", "value doesn't match"},
+		},
+		{
+			name: "suites sharing a name in different files",
+			results: []Result{
+				{Suite: "price", File: "eth/price.test.yaml", Name: "median", Passed: true},
+				{Suite: "price", File: "polygon/price.test.yaml", Name: "median", Failures: []Failure{{Message: "value doesn't match"}}},
+				{Suite: "price", File: "eth/price.test.yaml", Name: "mean", Passed: true},
+			},
+			wantSuites:   []string{"price", "price"},
+			wantTests:    []int{2, 1},
+			wantFailures: []int{0, 1},
+			wantMessages: []string{"", "", "value doesn't match"},
+		},
+		{
+			name:         "failed without failures",
+			results:      []Result{{Suite: "price", Name: "median"}},
+			wantSuites:   []string{"price"},
+			wantTests:    []int{1},
+			wantFailures: []int{1},
+			wantMessages: []string{"failed"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := &Report{}
+			report.Add(test.results...)
+
+			var buf bytes.Buffer
+			if err := report.WriteJUnit(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			var got junitTestSuites
+			if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+			}
+			if got.Tests != len(test.results) || got.Failures != report.Failed {
+				t.Errorf("%d tests, %d failures, want %d, %d", got.Tests, got.Failures, len(test.results), report.Failed)
+			}
+
+			suites, tests, failures, messages := []string{}, []int{}, []int{}, []string{}
+			for _, suite := range got.Suites {
+				suites = append(suites, suite.Name)
+				tests = append(tests, suite.Tests)
+				failures = append(failures, suite.Failures)
+				for _, testCase := range suite.Cases {
+					message := ""
+					if testCase.Failure != nil {
+						message = testCase.Failure.Message
+					}
+					messages = append(messages, message)
+				}
+			}
+			if len(test.wantSuites) == 0 {
+				if len(suites) != 0 {
+					t.Errorf("suites %v, want none", suites)
+				}
+				return
+			}
+			if !reflect.DeepEqual(suites, test.wantSuites) {
+				t.Errorf("suites %v, want %v", suites, test.wantSuites)
+			}
+			if !reflect.DeepEqual(tests, test.wantTests) {
+				t.Errorf("tests %v, want %v", tests, test.wantTests)
+			}
+			if !reflect.DeepEqual(failures, test.wantFailures) {
+				t.Errorf("failures %v, want %v", failures, test.wantFailures)
+			}
+			if !reflect.DeepEqual(messages, test.wantMessages) {
+				t.Errorf("messages %q, want %q", messages, test.wantMessages)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package testsuite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/jobspec"
+	"github.com/shopspring/decimal"
+)
+
+// Mutation operators
+const (
+	OperatorJSONParsePath = "jsonparse-path"
+	OperatorMultiplyTimes = "multiply-times"
+	OperatorDivideDivisor = "divide-divisor"
+	OperatorLessThanSwap  = "lessthan-swap"
+	OperatorDropEdge      = "drop-edge"
+)
+
+// Statuses of a mutant once the tests have run against it
+const (
+	MutantKilled   = "killed"
+	MutantSurvived = "survived"
+	// MutantInvalid mutants don't parse, e.g. because dropping an edge left
+	// a task without its input
+	MutantInvalid = "invalid"
+)
+
+// Mutant is a small change to a spec's pipeline, of the kind a refactor or
+// typo would make, that its tests should notice.
+type Mutant struct {
+	Operator    string `json:"operator"`
+	Task        string `json:"task"`
+	Description string `json:"description"`
+
+	// source is the DOT the mutant is parsed from, which only differs from
+	// the spec's for mutants that change the graph
+	source string
+	// mutate changes the tasks of the parsed pipeline, if set
+	mutate func(p *pipeline.Pipeline)
+}
+
+// Pipeline parses the mutated pipeline.
+func (m Mutant) Pipeline() (*pipeline.Pipeline, error) {
+	p, err := pipeline.Parse(m.source)
+	if err != nil {
+		return nil, err
+	}
+	if m.mutate != nil {
+		m.mutate(p)
+	}
+	return p, nil
+}
+
+// Mutants generates the mutants of a pipeline given its DOT source.
+func Mutants(source string, p *pipeline.Pipeline) []Mutant {
+	mutants := []Mutant{}
+	add := func(operator, task, description string, mutate func(p *pipeline.Pipeline)) {
+		mutants = append(mutants, Mutant{
+			Operator:    operator,
+			Task:        task,
+			Description: description,
+			source:      source,
+			mutate:      mutate,
+		})
+	}
+
+	multiplies := []*pipeline.MultiplyTask{}
+
+	for _, task := range p.Tasks {
+		id := task.DotID()
+
+		switch t := task.(type) {
+		case *pipeline.JSONParseTask:
+			for _, path := range mutatePath(t.Path, t.Separator) {
+				path := path
+				add(OperatorJSONParsePath, id, fmt.Sprintf("path %q changed to %q", t.Path, path), func(p *pipeline.Pipeline) {
+					p.ByDotID(id).(*pipeline.JSONParseTask).Path = path
+				})
+			}
+
+		case *pipeline.MultiplyTask:
+			multiplies = append(multiplies, t)
+			if times, ok := scaleLiteral(t.Times); ok {
+				add(OperatorMultiplyTimes, id, fmt.Sprintf("times %s changed to %s", t.Times, times), func(p *pipeline.Pipeline) {
+					p.ByDotID(id).(*pipeline.MultiplyTask).Times = times
+				})
+			}
+
+		case *pipeline.DivideTask:
+			if divisor, ok := scaleLiteral(t.Divisor); ok {
+				add(OperatorDivideDivisor, id, fmt.Sprintf("divisor %s changed to %s", t.Divisor, divisor), func(p *pipeline.Pipeline) {
+					p.ByDotID(id).(*pipeline.DivideTask).Divisor = divisor
+				})
+			}
+
+		case *pipeline.LessThanTask:
+			if t.Left != t.Right {
+				add(OperatorLessThanSwap, id, fmt.Sprintf("left %q and right %q swapped", t.Left, t.Right), func(p *pipeline.Pipeline) {
+					lt := p.ByDotID(id).(*pipeline.LessThanTask)
+					lt.Left, lt.Right = lt.Right, lt.Left
+				})
+			}
+		}
+
+		for _, input := range task.Inputs() {
+			from := input.InputTask.DotID()
+			if mutated, ok := dropEdge(source, from, id); ok {
+				mutants = append(mutants, Mutant{
+					Operator:    OperatorDropEdge,
+					Task:        id,
+					Description: fmt.Sprintf("edge %s -> %s dropped", from, id),
+					source:      mutated,
+				})
+			}
+		}
+	}
+
+	// Swapping the times of two multiplies catches tests that only check
+	// their product
+	for i, a := range multiplies {
+		for _, b := range multiplies[i+1:] {
+			if a.Times == b.Times {
+				continue
+			}
+			aID, bID := a.DotID(), b.DotID()
+			add(OperatorMultiplyTimes, aID, fmt.Sprintf("times swapped with %s", bID), func(p *pipeline.Pipeline) {
+				ma, mb := p.ByDotID(aID).(*pipeline.MultiplyTask), p.ByDotID(bID).(*pipeline.MultiplyTask)
+				ma.Times, mb.Times = mb.Times, ma.Times
+			})
+		}
+	}
+
+	return mutants
+}
+
+// mutatePath returns paths that pick a different value: the parent of the
+// value, and for an array index the next element.
+func mutatePath(path, separator string) []string {
+	if path == "" || strings.Contains(path, "$(") {
+		return nil
+	}
+	if separator == "" {
+		separator = ","
+	}
+
+	segments := strings.Split(path, separator)
+	last := segments[len(segments)-1]
+	paths := []string{}
+
+	if len(segments) > 1 {
+		paths = append(paths, strings.Join(segments[:len(segments)-1], separator))
+	}
+	if index, err := strconv.Atoi(last); err == nil {
+		next := append(append([]string{}, segments[:len(segments)-1]...), strconv.Itoa(index+1))
+		paths = append(paths, strings.Join(next, separator))
+	}
+	return paths
+}
+
+// scaleLiteral multiplies a number by ten, the mistake made when a decimal
+// place is lost. Var references aren't mutated.
+func scaleLiteral(value string) (string, bool) {
+	d, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil || d.IsZero() {
+		return "", false
+	}
+	return d.Mul(decimal.NewFromInt(10)).String(), true
+}
+
+// dropEdge removes the edge between the tasks from the DOT, splitting a
+// chain such as `a -> b -> c` into `a` and `b -> c`.
+func dropEdge(source, from, to string) (string, bool) {
+	edge := regexp.MustCompile(`(^|[^\w.])(` + regexp.QuoteMeta(from) + `"?)\s*->\s*("?` + regexp.QuoteMeta(to) + `)\b`)
+	if !edge.MatchString(source) {
+		return "", false
+	}
+	return edge.ReplaceAllString(source, "$1$2;\n$3"), true
+}
+
+// MutantResult is how a spec's tests fared against a mutant.
+type MutantResult struct {
+	Mutant
+	Status string `json:"status"`
+	// KilledBy is the first test that failed against the mutant
+	KilledBy string `json:"killedBy,omitempty"`
+	// Error is why an invalid mutant didn't parse
+	Error string `json:"error,omitempty"`
+}
+
+// SpecMutations are the mutants of one spec. Specs are told apart the same
+// way as for coverage.
+type SpecMutations struct {
+	Spec    string         `json:"spec"`
+	Tests   []string       `json:"tests"`
+	Mutants []MutantResult `json:"mutants"`
+	// Skipped is why the spec wasn't mutated, e.g. because none of its tests
+	// pass as is
+	Skipped  string `json:"skipped,omitempty"`
+	Killed   int    `json:"killed"`
+	Survived int    `json:"survived"`
+	Invalid  int    `json:"invalid"`
+}
+
+// MutationReport collects the mutants of every spec the suites test.
+type MutationReport struct {
+	Specs    []*SpecMutations `json:"specs"`
+	Killed   int              `json:"killed"`
+	Survived int              `json:"survived"`
+	Invalid  int              `json:"invalid"`
+}
+
+// Score is the percentage of valid mutants the tests killed.
+func (r *MutationReport) Score() float64 {
+	if r.Killed+r.Survived == 0 {
+		return 100
+	}
+	return 100 * float64(r.Killed) / float64(r.Killed+r.Survived)
+}
+
+type specTest struct {
+	suite *Suite
+	test  Test
+}
+
+func (t specTest) name() string {
+	return t.suite.Name + " - " + t.test.Name
+}
+
+// Mutate runs the pipeline tests of the suites against mutants of the specs
+// they test. Only tests that pass against the spec as is are used, and each
+// mutant is given at most timeout to run all of them. Mutants never write
+// snapshots, as the run against the spec as is writes any missing ones.
+func Mutate(ctx context.Context, suites []*Suite, timeout time.Duration) *MutationReport {
+	report := &MutationReport{Specs: []*SpecMutations{}}
+
+	groups := map[string][]specTest{}
+	order := []string{}
+	for _, suite := range suites {
+		readOnly := *suite
+		readOnly.UpdateSnapshots = false
+
+		for _, test := range suite.Tests {
+			if test.IsTaskTest() {
+				continue
+			}
+			key := specKey(suite, test)
+			if _, ok := groups[key]; !ok {
+				order = append(order, key)
+			}
+			groups[key] = append(groups[key], specTest{&readOnly, test})
+		}
+	}
+
+	for _, key := range order {
+		if ctx.Err() != nil {
+			break
+		}
+		spec := mutateSpec(ctx, key, groups[key], timeout)
+		report.Specs = append(report.Specs, spec)
+		report.Killed += spec.Killed
+		report.Survived += spec.Survived
+		report.Invalid += spec.Invalid
+	}
+
+	return report
+}
+
+func mutateSpec(ctx context.Context, key string, tests []specTest, timeout time.Duration) *SpecMutations {
+	spec := &SpecMutations{
+		Spec:    key,
+		Tests:   []string{},
+		Mutants: []MutantResult{},
+	}
+
+	passing := []specTest{}
+	for _, t := range tests {
+		if result := RunTest(ctx, t.suite, t.test); result.Passed {
+			passing = append(passing, t)
+			spec.Tests = append(spec.Tests, t.name())
+		}
+	}
+	if len(passing) == 0 {
+		spec.Skipped = "none of its tests pass"
+		return spec
+	}
+
+	parsed, err := jobspec.Load([]byte(specOf(passing[0].suite, passing[0].test)))
+	if err != nil || parsed.ParseErr != nil {
+		spec.Skipped = "it doesn't parse"
+		return spec
+	}
+
+	for _, mutant := range Mutants(parsed.Source, parsed.Pipeline) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := runMutant(ctx, mutant, passing, timeout)
+		if ctx.Err() != nil {
+			// Cancelled rather than killed
+			break
+		}
+		switch result.Status {
+		case MutantKilled:
+			spec.Killed++
+		case MutantSurvived:
+			spec.Survived++
+		case MutantInvalid:
+			spec.Invalid++
+		}
+		spec.Mutants = append(spec.Mutants, result)
+	}
+
+	return spec
+}
+
+// runMutant runs the tests against the mutant until one fails. A mutant that
+// makes a test hang past the timeout is killed, as the test noticed.
+func runMutant(ctx context.Context, mutant Mutant, tests []specTest, timeout time.Duration) MutantResult {
+	result := MutantResult{Mutant: mutant, Status: MutantSurvived}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, t := range tests {
+		// Parse the mutant afresh for each test, as a run may change its
+		// tasks
+		p, err := mutant.Pipeline()
+		if err != nil {
+			result.Status = MutantInvalid
+			result.Error = err.Error()
+			return result
+		}
+
+		if r := runTest(ctx, t.suite, t.test, p); !r.Passed {
+			result.Status = MutantKilled
+			result.KilledBy = t.name()
+			return result
+		}
+	}
+	return result
+}
+
+// WriteText writes a line per spec with its mutants' statuses, followed by
+// the surviving mutants, which point at what the tests don't check.
+func (r *MutationReport) WriteText(w io.Writer) {
+	for _, spec := range r.Specs {
+		if spec.Skipped != "" {
+			fmt.Fprintf(w, "%s: skipped, %s\n", spec.Spec, spec.Skipped)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %d mutants, %d killed, %d survived, %d invalid, by %d tests\n",
+			spec.Spec, len(spec.Mutants), spec.Killed, spec.Survived, spec.Invalid, len(spec.Tests))
+		for _, mutant := range spec.Mutants {
+			if mutant.Status == MutantSurvived {
+				fmt.Fprintf(w, "  survived: %s %s: %s\n", mutant.Operator, mutant.Task, mutant.Description)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d killed, %d survived, %d invalid: mutation score %.0f%%\n", r.Killed, r.Survived, r.Invalid, r.Score())
+}
@@ -0,0 +1,155 @@
+package testsuite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+)
+
+func TestMutatePath(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		separator string
+		want      []string
+	}{
+		{name: "empty", path: "", want: nil},
+		{name: "var reference", path: "$(path)", want: nil},
+		{name: "single key", path: "price", want: []string{}},
+		{name: "nested key", path: "data,price", want: []string{"data"}},
+		{name: "array index", path: "data,0", want: []string{"data", "data,1"}},
+		{name: "top-level index", path: "3", want: []string{"4"}},
+		{name: "separator", path: "data.prices.1", separator: ".", want: []string{"data.prices", "data.prices.2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mutatePath(test.path, test.separator); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScaleLiteral(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   string
+		wantOk bool
+	}{
+		{value: "100", want: "1000", wantOk: true},
+		{value: " 0.5 ", want: "5", wantOk: true},
+		{value: "-2", want: "-20", wantOk: true},
+		{value: "0"},
+		{value: "$(times)"},
+		{value: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, ok := scaleLiteral(test.value)
+			if got != test.want || ok != test.wantOk {
+				t.Errorf("%q, %v, want %q, %v", got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
+
+func TestDropEdge(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		from, to string
+		want     string
+		wantOk   bool
+	}{
+		{name: "edge", source: "a -> b", from: "a", to: "b", want: "a;\nb", wantOk: true},
+		{name: "middle of a chain", source: "a -> b -> c", from: "b", to: "c", want: "a -> b;\nc", wantOk: true},
+		{name: "quoted IDs", source: `"a" -> "b"`, from: "a", to: "b", want: "\"a\";\n\"b\"", wantOk: true},
+		{name: "prefix of another ID", source: "fetch2 -> parse", from: "fetch", to: "parse"},
+		{name: "suffix of another ID", source: "prefetch -> parse", from: "fetch", to: "parse"},
+		{name: "no edge", source: "a -> b", from: "b", to: "a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := dropEdge(test.source, test.from, test.to)
+			if got != test.want || ok != test.wantOk {
+				t.Errorf("%q, %v, want %q, %v", got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
+
+func TestMutants(t *testing.T) {
+	base := func(id string) pipeline.BaseTask { return pipeline.NewBaseTask(0, id, nil, nil, 0) }
+
+	tests := []struct {
+		name  string
+		tasks []pipeline.Task
+		want  []string
+	}{
+		{
+			name:  "jsonparse",
+			tasks: []pipeline.Task{&pipeline.JSONParseTask{BaseTask: base("parse"), Path: "data,0"}},
+			want:  []string{"jsonparse-path parse", "jsonparse-path parse"},
+		},
+		{
+			name: "multiplies",
+			tasks: []pipeline.Task{
+				&pipeline.MultiplyTask{BaseTask: base("a"), Times: "100"},
+				&pipeline.MultiplyTask{BaseTask: base("b"), Times: "$(times)"},
+				&pipeline.MultiplyTask{BaseTask: base("c"), Times: "100"},
+			},
+			want: []string{"multiply-times a", "multiply-times c", "multiply-times a", "multiply-times b"},
+		},
+		{
+			name: "divide and lessthan",
+			tasks: []pipeline.Task{
+				&pipeline.DivideTask{BaseTask: base("divide"), Divisor: "3"},
+				&pipeline.LessThanTask{BaseTask: base("lt"), Left: "1", Right: "2"},
+				&pipeline.LessThanTask{BaseTask: base("same"), Left: "1", Right: "1"},
+			},
+			want: []string{"divide-divisor divide", "lessthan-swap lt"},
+		},
+		{
+			name:  "nothing to mutate",
+			tasks: []pipeline.Task{&pipeline.AnyTask{BaseTask: base("any")}},
+			want:  []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for _, mutant := range Mutants("", &pipeline.Pipeline{Tasks: test.tasks}) {
+				got = append(got, mutant.Operator+" "+mutant.Task)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMutationScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		report MutationReport
+		want   float64
+	}{
+		{name: "no mutants", want: 100},
+		{name: "only invalid", report: MutationReport{Invalid: 2}, want: 100},
+		{name: "all killed", report: MutationReport{Killed: 3}, want: 100},
+		{name: "some survived", report: MutationReport{Killed: 3, Survived: 1, Invalid: 5}, want: 75},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.report.Score(); got != test.want {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package testsuite
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report collects the results of one or more suites.
+type Report struct {
+	Results  []Result      `json:"results"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (r *Report) Add(results ...Result) {
+	for _, result := range results {
+		r.Results = append(r.Results, result)
+		r.Duration += result.Duration
+		if result.Passed {
+			r.Passed++
+		} else {
+			r.Failed++
+		}
+	}
+}
+
+// OK reports whether every test passed.
+func (r *Report) OK() bool {
+	return r.Failed == 0
+}
+
+// WriteText writes a human readable report, with the failures' diffs.
+func (r *Report) WriteText(w io.Writer) {
+	for _, result := range r.Results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s  %s - %s (%v)\n", status, result.Suite, result.Name, result.Duration.Round(time.Millisecond))
+
+		for _, failure := range result.Failures {
+			fmt.Fprintf(w, "      %s\n", strings.ReplaceAll(failure.String(), "\n", "\n      "))
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d passed, %d failed\n", r.Passed, r.Failed)
+}
@@ -0,0 +1,316 @@
+package testsuite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+// Result is the outcome of one test.
+type Result struct {
+	Suite    string        `json:"suite"`
+	File     string        `json:"file,omitempty"`
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Failures []Failure     `json:"failures"`
+	Duration time.Duration `json:"duration"`
+	// Run is the pipeline run of a pipeline test, for tools that build on
+	// the runner. It is nil for task tests and tests that couldn't start.
+	Run *simulator.Run `json:"-"`
+}
+
+// Failure describes a check that didn't pass. Want and Got are set when a
+// value was compared, as the JSON the node would serialise them to.
+type Failure struct {
+	Message string `json:"message"`
+	Want    string `json:"want,omitempty"`
+	Got     string `json:"got,omitempty"`
+}
+
+func (f Failure) String() string {
+	if f.Want == "" && f.Got == "" {
+		return f.Message
+	}
+	return fmt.Sprintf("%s\n  want: %s\n   got: %s", f.Message, f.Want, f.Got)
+}
+
+// Run runs every test in the suite.
+func Run(ctx context.Context, suite *Suite) []Result {
+	results := make([]Result, 0, len(suite.Tests))
+	for _, test := range suite.Tests {
+		results = append(results, RunTest(ctx, suite, test))
+	}
+	return results
+}
+
+// RunTest runs a single test of the suite.
+func RunTest(ctx context.Context, suite *Suite, test Test) Result {
+	return runTest(ctx, suite, test, nil)
+}
+
+// runTest runs the test, with a pipeline test running p rather than its spec
+// if p is set.
+func runTest(ctx context.Context, suite *Suite, test Test, p *pipeline.Pipeline) Result {
+	start := time.Now()
+
+	result := Result{
+		Suite: suite.Name,
+		File:  suite.File,
+		Name:  test.Name,
+	}
+
+	var err error
+	func() {
+		// Tasks' panics are caught as they run, but loading the vars and
+		// inputs can panic too, e.g. in a fuzzing reproducer
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("panicked: %v", rec)
+			}
+		}()
+		if test.IsTaskTest() {
+			result.Failures, err = runTaskTest(ctx, suite, test)
+		} else {
+			result.Run, result.Failures, err = runPipelineTest(ctx, suite, test, p)
+		}
+	}()
+	if err != nil {
+		result.Failures = append(result.Failures, Failure{Message: err.Error()})
+	}
+
+	result.Passed = len(result.Failures) == 0
+	result.Duration = time.Since(start)
+	return result
+}
+
+// runTaskTest runs the task the same way api/task does, with the vars and
+// inputs round-tripped through the encoding the var-helper applies.
+func runTaskTest(ctx context.Context, suite *Suite, test Test) ([]Failure, error) {
+	vars, err := simulator.LoadVars(test.Vars, test.JobRun, test.JobSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]interface{}, 0, len(test.Inputs))
+	for i, input := range test.Inputs {
+		value, err := simulator.LoadVar(input)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		inputs = append(inputs, value)
+	}
+
+	options, err := suite.Registry.ResolveOptions(test.Task, test.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	chains, err := chain.NewChains(suite.Chains)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := simulator.RunTask(ctx, simulator.TaskType(test.Task), options, vars, inputs, simulator.ExecuteOptions{
+		Chains: chains,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	got := result.Value
+	if test.MockResponse != nil {
+		got = test.MockResponse
+	}
+
+	failures := []Failure{}
+
+	if test.ExpectError && result.Error == nil {
+		failures = append(failures, Failure{Message: "expected an error"})
+	}
+	switch {
+	case test.ExpectNoPanic && result.Panicked(), test.ExpectNoTimeout && result.TimedOut():
+		failures = append(failures, Failure{Message: result.Error.Error()})
+	case test.ExpectNoError && result.Error != nil:
+		failures = append(failures, Failure{Message: fmt.Sprintf("expected no error, got: %v", result.Error)})
+	}
+
+	// api/task fails on results it can't serialise, so there is nothing to
+	// compare them with
+	got64, err := simulator.ToBase64(got)
+	if err != nil {
+		failures = append(failures, Failure{Message: fmt.Sprintf("value can't be serialised: %v", err)})
+	} else if test.Want64 != "" {
+		if got64 != test.Want64 {
+			failures = append(failures, Failure{Message: "value (base64) differs", Want: test.Want64, Got: got64})
+		}
+	} else if test.Want != nil {
+		failure, err := compare("value differs", *test.Want, got)
+		if err != nil {
+			return nil, err
+		}
+		if failure != nil {
+			if result.Error != nil {
+				failure.Message = fmt.Sprintf("value differs, task errored: %v", result.Error)
+			}
+			failures = append(failures, *failure)
+		}
+	}
+
+	if result.SideEffectData != nil {
+		if _, err := simulator.ToBase64(result.SideEffectData); err != nil {
+			failures = append(failures, Failure{Message: fmt.Sprintf("side effect data can't be serialised: %v", err)})
+			return failures, nil
+		}
+	}
+
+	if test.WantSideEffectData != nil {
+		failure, err := compare("side effect data differs", *test.WantSideEffectData, result.SideEffectData)
+		if err != nil {
+			return nil, err
+		}
+		if failure != nil {
+			failures = append(failures, *failure)
+		}
+	}
+
+	return failures, nil
+}
+
+func runPipelineTest(ctx context.Context, suite *Suite, test Test, p *pipeline.Pipeline) (*simulator.Run, []Failure, error) {
+	var err error
+	if p == nil {
+		if p, err = simulator.Parse(specOf(suite, test)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if errs := suite.Registry.ResolvePipeline(p); len(errs) > 0 {
+		return nil, nil, errs[0]
+	}
+
+	vars, err := simulator.LoadVars(test.Vars, test.JobRun, test.JobSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mocks := make(map[string]interface{}, len(test.Mocks))
+	for id, mock := range test.Mocks {
+		if mocks[id], err = simulator.LoadVar(mock); err != nil {
+			return nil, nil, fmt.Errorf("mock %s: %w", id, err)
+		}
+	}
+
+	chains, err := chain.NewChains(suite.Chains)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	run, err := simulator.PrepareRun(p, vars, mocks, simulator.RunOptions{
+		Faults:    test.Faults,
+		FaultSeed: test.FaultSeed,
+		Chains:    chains,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := run.Execute(ctx); err != nil {
+		return run, nil, err
+	}
+
+	failures := []Failure{}
+
+	summary := run.Summarize()
+	if test.ExpectError && summary.Succeeded {
+		failures = append(failures, Failure{Message: "expected the run to fail"})
+	} else if !test.ExpectError && !summary.Succeeded && len(test.Errors) == 0 {
+		failures = append(failures, Failure{Message: fmt.Sprintf("the run failed: %s", summary.Reason)})
+	}
+
+	for id, want := range test.Results {
+		taskRun, ok := run.Results[id]
+		if !ok {
+			failures = append(failures, Failure{Message: fmt.Sprintf("task %s did not run", id)})
+			continue
+		}
+
+		failure, err := compare(fmt.Sprintf("task %s value differs", id), want, taskRun.Result.Value)
+		if err != nil {
+			return run, nil, err
+		}
+		if failure != nil {
+			if taskRun.Result.Error != nil {
+				failure.Message = fmt.Sprintf("task %s value differs, task errored: %v", id, taskRun.Result.Error)
+			}
+			failures = append(failures, *failure)
+		}
+	}
+
+	for id, want := range test.Errors {
+		taskRun, ok := run.Results[id]
+		switch {
+		case !ok:
+			failures = append(failures, Failure{Message: fmt.Sprintf("task %s did not run", id)})
+		case taskRun.Result.Error == nil:
+			failures = append(failures, Failure{Message: fmt.Sprintf("task %s did not fail", id)})
+		case !strings.Contains(taskRun.Result.Error.Error(), want):
+			failures = append(failures, Failure{
+				Message: fmt.Sprintf("task %s failed with a different error", id),
+				Want:    want,
+				Got:     taskRun.Result.Error.Error(),
+			})
+		}
+	}
+
+	if test.Snapshot != "" {
+		changes, err := CheckSnapshot(run, suite.resolve(test.Snapshot), suite.UpdateSnapshots)
+		if err != nil {
+			return run, nil, fmt.Errorf("snapshot: %w", err)
+		}
+		for _, change := range changes {
+			message := fmt.Sprintf("snapshot: task %s %s changed", change.Id, change.Kind)
+			if change.Kind == ChangeAdded || change.Kind == ChangeRemoved {
+				message = "snapshot: " + change.String()
+			}
+			failures = append(failures, Failure{Message: message, Want: change.Want, Got: change.Got})
+		}
+	}
+
+	return run, failures, nil
+}
+
+// specOf returns the spec a pipeline test runs.
+func specOf(suite *Suite, test Test) string {
+	if test.Spec != "" {
+		return test.Spec
+	}
+	return suite.Spec
+}
+
+// compare compares the value with the wanted one as serialised by the node,
+// as the Cypress tests compare their base64 encodings.
+func compare(message string, want Var, got interface{}) (*Failure, error) {
+	wantValue, err := simulator.ConvertVar(want)
+	if err != nil {
+		return nil, err
+	}
+
+	wantJSON, err := simulator.MarshalAsJsonSerializable(wantValue)
+	if err != nil {
+		return nil, err
+	}
+
+	gotJSON, err := simulator.MarshalAsJsonSerializable(got)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(wantJSON) == string(gotJSON) {
+		return nil, nil
+	}
+	return &Failure{Message: message, Want: string(wantJSON), Got: string(gotJSON)}, nil
+}
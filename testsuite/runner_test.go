@@ -0,0 +1,77 @@
+package testsuite
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunTaskTest(t *testing.T) {
+	tests := []struct {
+		name        string
+		test        Test
+		wantFailure string
+	}{
+		{
+			name: "value",
+			test: Test{
+				Task:    "multiply",
+				Options: map[string]interface{}{"input": "3", "times": "2"},
+				Want:    &Var{Value: "6", Type: "decimal"},
+			},
+		},
+		{
+			name: "value differs",
+			test: Test{
+				Task:    "multiply",
+				Options: map[string]interface{}{"input": "3", "times": "2"},
+				Want:    &Var{Value: "7", Type: "decimal"},
+			},
+			wantFailure: "value differs",
+		},
+		{
+			name: "expected error",
+			test: Test{
+				Task:        "divide",
+				Options:     map[string]interface{}{"input": "3", "divisor": "0"},
+				ExpectError: true,
+			},
+		},
+		{
+			name: "expected no error",
+			test: Test{
+				Task:          "divide",
+				Options:       map[string]interface{}{"input": "3", "divisor": "0"},
+				ExpectNoError: true,
+			},
+			wantFailure: "expected no error",
+		},
+		{
+			name: "no error, panic or timeout",
+			test: Test{
+				Task:            "divide",
+				Options:         map[string]interface{}{"input": "3", "divisor": "2"},
+				ExpectNoError:   true,
+				ExpectNoPanic:   true,
+				ExpectNoTimeout: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.test.Name = test.name
+			result := RunTest(context.Background(), &Suite{}, test.test)
+
+			if test.wantFailure == "" {
+				if !result.Passed {
+					t.Errorf("failed: %v", result.Failures)
+				}
+				return
+			}
+			if len(result.Failures) != 1 || !strings.Contains(result.Failures[0].Message, test.wantFailure) {
+				t.Errorf("failures %v, want one containing %q", result.Failures, test.wantFailure)
+			}
+		})
+	}
+}
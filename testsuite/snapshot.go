@@ -0,0 +1,197 @@
+package testsuite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+// Snapshot is the typed output of every task of a run, kept in a golden file
+// to catch regressions when a spec is refactored or the Chainlink fork is
+// bumped.
+type Snapshot struct {
+	Tasks []TaskSnapshot `json:"tasks"`
+}
+
+// TaskSnapshot is the output of a task. Type is the Go type of the value, as
+// the JSON the node serialises it to can't tell e.g. a decimal from a
+// string.
+type TaskSnapshot struct {
+	Id             string          `json:"id"`
+	Type           string          `json:"type"`
+	Value          json.RawMessage `json:"value"`
+	Error          string          `json:"error,omitempty"`
+	SideEffectData json.RawMessage `json:"sideEffectData,omitempty"`
+}
+
+// Kinds of snapshot changes
+const (
+	ChangeAdded          = "added"
+	ChangeRemoved        = "removed"
+	ChangeType           = "type"
+	ChangeValue          = "value"
+	ChangeError          = "error"
+	ChangeSideEffectData = "sideEffectData"
+)
+
+// SnapshotChange is a difference between a golden snapshot and a run.
+type SnapshotChange struct {
+	Id   string `json:"id"`
+	Kind string `json:"kind"`
+	Want string `json:"want"`
+	Got  string `json:"got"`
+}
+
+func (c SnapshotChange) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("task %s is new", c.Id)
+	case ChangeRemoved:
+		return fmt.Sprintf("task %s is gone", c.Id)
+	}
+	return fmt.Sprintf("task %s %s changed from %s to %s", c.Id, c.Kind, c.Want, c.Got)
+}
+
+// TakeSnapshot snapshots the results of the run, in execution order.
+func TakeSnapshot(run *simulator.Run) (*Snapshot, error) {
+	snapshot := &Snapshot{Tasks: []TaskSnapshot{}}
+
+	for _, task := range run.Pipeline.Tasks {
+		taskRun, ok := run.Results[task.DotID()]
+		if !ok {
+			continue
+		}
+
+		value, err := simulator.MarshalAsJsonSerializable(taskRun.Result.Value)
+		if err != nil {
+			return nil, fmt.Errorf("task %s: %w", task.DotID(), err)
+		}
+
+		ts := TaskSnapshot{
+			Id:    task.DotID(),
+			Type:  fmt.Sprintf("%T", taskRun.Result.Value),
+			Value: value,
+		}
+		if taskRun.Result.Error != nil {
+			ts.Error = taskRun.Result.Error.Error()
+		}
+		if taskRun.Result.SideEffectData != nil {
+			if ts.SideEffectData, err = simulator.MarshalAsJsonSerializable(taskRun.Result.SideEffectData); err != nil {
+				return nil, fmt.Errorf("task %s: %w", task.DotID(), err)
+			}
+		}
+
+		snapshot.Tasks = append(snapshot.Tasks, ts)
+	}
+
+	return snapshot, nil
+}
+
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// Write writes the snapshot as indented JSON, creating its directory.
+func (s *Snapshot) Write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Diff lists how the run's snapshot differs from the golden one: the tasks
+// added or removed, and for the others every one of type, value, error and
+// side effect data that changed.
+func (s *Snapshot) Diff(got *Snapshot) []SnapshotChange {
+	changes := []SnapshotChange{}
+
+	golden := map[string]TaskSnapshot{}
+	for _, task := range s.Tasks {
+		golden[task.Id] = task
+	}
+	seen := map[string]bool{}
+
+	for _, task := range got.Tasks {
+		seen[task.Id] = true
+		want, ok := golden[task.Id]
+		if !ok {
+			changes = append(changes, SnapshotChange{Id: task.Id, Kind: ChangeAdded, Got: string(compact(task.Value))})
+			continue
+		}
+
+		if want.Type != task.Type {
+			changes = append(changes, SnapshotChange{Id: task.Id, Kind: ChangeType, Want: want.Type, Got: task.Type})
+		}
+		if w, g := compact(want.Value), compact(task.Value); !bytes.Equal(w, g) {
+			changes = append(changes, SnapshotChange{Id: task.Id, Kind: ChangeValue, Want: string(w), Got: string(g)})
+		}
+		if want.Error != task.Error {
+			changes = append(changes, SnapshotChange{Id: task.Id, Kind: ChangeError, Want: fmt.Sprintf("%q", want.Error), Got: fmt.Sprintf("%q", task.Error)})
+		}
+		if w, g := compact(want.SideEffectData), compact(task.SideEffectData); !bytes.Equal(w, g) {
+			changes = append(changes, SnapshotChange{Id: task.Id, Kind: ChangeSideEffectData, Want: string(w), Got: string(g)})
+		}
+	}
+
+	for _, task := range s.Tasks {
+		if !seen[task.Id] {
+			changes = append(changes, SnapshotChange{Id: task.Id, Kind: ChangeRemoved, Want: string(compact(task.Value))})
+		}
+	}
+
+	return changes
+}
+
+// compact normalises the JSON so that formatting doesn't count as a change,
+// and a missing value is null. Invalid JSON, which a hand-edited golden file
+// may hold, is left as is.
+func compact(data json.RawMessage) []byte {
+	if len(data) == 0 {
+		return []byte("null")
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// CheckSnapshot compares the run with the golden snapshot at the path. The
+// snapshot is written instead when it doesn't exist yet, or update is set, in
+// which case there are no changes.
+func CheckSnapshot(run *simulator.Run, path string, update bool) ([]SnapshotChange, error) {
+	got, err := TakeSnapshot(run)
+	if err != nil {
+		return nil, err
+	}
+
+	if update {
+		return nil, got.Write(path)
+	}
+
+	golden, err := LoadSnapshot(path)
+	if os.IsNotExist(err) {
+		return nil, got.Write(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return golden.Diff(got), nil
+}
@@ -0,0 +1,165 @@
+package testsuite
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pickleyd/chainlink/core/services/pipeline"
+	"github.com/pickleyd/jobspecviz/simulator"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	golden := &Snapshot{Tasks: []TaskSnapshot{
+		{Id: "fetch", Type: "string", Value: json.RawMessage(`{"price": 1}`)},
+		{Id: "parse", Type: "decimal.Decimal", Value: json.RawMessage(`"1"`)},
+	}}
+
+	tests := []struct {
+		name string
+		got  []TaskSnapshot
+		want []SnapshotChange
+	}{
+		{
+			name: "unchanged but formatted differently",
+			got: []TaskSnapshot{
+				{Id: "fetch", Type: "string", Value: json.RawMessage(`{"price":1}`)},
+				{Id: "parse", Type: "decimal.Decimal", Value: json.RawMessage(`"1"`)},
+			},
+			want: []SnapshotChange{},
+		},
+		{
+			name: "type and value",
+			got: []TaskSnapshot{
+				{Id: "fetch", Type: "string", Value: json.RawMessage(`{"price":1}`)},
+				{Id: "parse", Type: "float64", Value: json.RawMessage(`1.0`)},
+			},
+			want: []SnapshotChange{
+				{Id: "parse", Kind: ChangeType, Want: "decimal.Decimal", Got: "float64"},
+				{Id: "parse", Kind: ChangeValue, Want: `"1"`, Got: `1.0`},
+			},
+		},
+		{
+			name: "error and side effect data",
+			got: []TaskSnapshot{
+				{Id: "fetch", Type: "string", Value: json.RawMessage(`{"price":1}`), SideEffectData: json.RawMessage(`{"a":1}`)},
+				{Id: "parse", Type: "decimal.Decimal", Value: json.RawMessage(`"1"`), Error: "bad"},
+			},
+			want: []SnapshotChange{
+				{Id: "fetch", Kind: ChangeSideEffectData, Want: "null", Got: `{"a":1}`},
+				{Id: "parse", Kind: ChangeError, Want: `""`, Got: `"bad"`},
+			},
+		},
+		{
+			name: "added and removed",
+			got: []TaskSnapshot{
+				{Id: "fetch", Type: "string", Value: json.RawMessage(`{"price":1}`)},
+				{Id: "median", Type: "decimal.Decimal", Value: json.RawMessage(`"2"`)},
+			},
+			want: []SnapshotChange{
+				{Id: "median", Kind: ChangeAdded, Got: `"2"`},
+				{Id: "parse", Kind: ChangeRemoved, Want: `"1"`},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := golden.Diff(&Snapshot{Tasks: test.got}); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckSnapshot(t *testing.T) {
+	run := func(price string, err error) *simulator.Run {
+		return coverageRun(map[string]*simulator.TaskRun{
+			"fetch": {Result: pipeline.Result{Value: price, Error: err}},
+		})
+	}
+
+	tests := []struct {
+		name        string
+		golden      *simulator.Run
+		run         *simulator.Run
+		update      bool
+		wantKinds   []string
+		wantWritten bool
+	}{
+		{
+			name:        "no golden file",
+			run:         run("1", nil),
+			wantWritten: true,
+		},
+		{
+			name:   "unchanged",
+			golden: run("1", nil),
+			run:    run("1", nil),
+		},
+		{
+			name:      "changed",
+			golden:    run("1", nil),
+			run:       run("2", errors.New("bad")),
+			wantKinds: []string{ChangeValue, ChangeError},
+		},
+		{
+			name:        "update",
+			golden:      run("1", nil),
+			run:         run("2", nil),
+			update:      true,
+			wantWritten: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshots", "price.json")
+			if test.golden != nil {
+				snapshot, err := TakeSnapshot(test.golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := snapshot.Write(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before, _ := os.ReadFile(path)
+
+			changes, err := CheckSnapshot(test.run, path, test.update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kinds := []string{}
+			for _, change := range changes {
+				kinds = append(kinds, change.Kind)
+			}
+			if len(kinds) != len(test.wantKinds) || (len(kinds) > 0 && !reflect.DeepEqual(kinds, test.wantKinds)) {
+				t.Errorf("changes %v, want %v", kinds, test.wantKinds)
+			}
+
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written := string(before) != string(after); written != test.wantWritten {
+				t.Errorf("wrote the snapshot: %v, want %v", written, test.wantWritten)
+			}
+
+			// Whatever was written must now match the run
+			golden, err := LoadSnapshot(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.wantWritten {
+				got, _ := TakeSnapshot(test.run)
+				if changes := golden.Diff(got); len(changes) != 0 {
+					t.Errorf("written snapshot differs from the run: %v", changes)
+				}
+			}
+		})
+	}
+}
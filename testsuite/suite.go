@@ -0,0 +1,313 @@
+package testsuite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pickleyd/jobspecviz/chain"
+	"github.com/pickleyd/jobspecviz/contracts"
+	"github.com/pickleyd/jobspecviz/simulator"
+	"gopkg.in/yaml.v3"
+)
+
+type Var = simulator.Var
+
+// Suite is a file of tests. It is written in YAML or JSON, e.g.
+//
+//	name: divide
+//	tests:
+//	  - name: precision
+//	    task: divide
+//	    inputs: [{ value: "12345.67", type: float }]
+//	    options: { divisor: "1000", precision: "2" }
+//	    want: { value: "12.35", type: decimal }
+type Suite struct {
+	Name string `yaml:"name" json:"name"`
+	// Spec is the TOML of the pipeline the pipeline tests run, unless they
+	// set their own
+	Spec string `yaml:"spec,omitempty" json:"spec"`
+	// SpecFile is a spec file to read Spec from instead, relative to the
+	// suite
+	SpecFile string `yaml:"specFile,omitempty" json:"specFile"`
+	// ABIs is a file or directory of contract ABIs, relative to the suite,
+	// that abi attributes can refer to as Contract.method
+	ABIs string `yaml:"abis,omitempty" json:"abis"`
+	// Chains answer the tests' ethcall and ethtx tasks, see chain.Config.
+	// Every test starts with them afresh.
+	Chains []chain.Config `yaml:"chains,omitempty" json:"chains"`
+	Tests  []Test         `yaml:"tests" json:"tests"`
+	// Registry holds the contracts of ABIs
+	Registry *contracts.Registry `yaml:"-" json:"-"`
+	// File is the path the suite was loaded from
+	File string `yaml:"-" json:"-"`
+	// UpdateSnapshots rewrites the tests' golden snapshots rather than
+	// comparing the runs with them
+	UpdateSnapshots bool `yaml:"-" json:"-"`
+}
+
+// Test runs either a single task, when Task is set, or a whole pipeline. The
+// task fields mirror the Cypress tests' Test type.
+type Test struct {
+	Name string `yaml:"name" json:"name"`
+
+	Task         string                 `yaml:"task,omitempty" json:"task"`
+	Options      map[string]interface{} `yaml:"options,omitempty" json:"options"`
+	Inputs       []Var                  `yaml:"inputs,omitempty" json:"inputs"`
+	MockResponse interface{}            `yaml:"mockResponse,omitempty" json:"mockResponse"`
+
+	Vars    map[string]Var `yaml:"vars,omitempty" json:"vars"`
+	JobRun  map[string]Var `yaml:"jobRun,omitempty" json:"jobRun"`
+	JobSpec map[string]Var `yaml:"jobSpec,omitempty" json:"jobSpec"`
+
+	Want   *Var   `yaml:"want,omitempty" json:"want"`
+	Want64 string `yaml:"want64,omitempty" json:"want64"`
+	// ExpectError expects the task, or for a pipeline test the run, to fail
+	ExpectError        bool `yaml:"expectError,omitempty" json:"expectError"`
+	WantSideEffectData *Var `yaml:"wantSideEffectData,omitempty" json:"wantSideEffectData"`
+	// ExpectNoError, ExpectNoPanic and ExpectNoTimeout fail a task test if
+	// the task errors, panics or times out, whatever else it does. Fuzzing
+	// sets them on the reproducers of its findings.
+	ExpectNoError   bool `yaml:"expectNoError,omitempty" json:"expectNoError"`
+	ExpectNoPanic   bool `yaml:"expectNoPanic,omitempty" json:"expectNoPanic"`
+	ExpectNoTimeout bool `yaml:"expectNoTimeout,omitempty" json:"expectNoTimeout"`
+
+	Spec      string            `yaml:"spec,omitempty" json:"spec"`
+	SpecFile  string            `yaml:"specFile,omitempty" json:"specFile"`
+	Mocks     map[string]Var    `yaml:"mocks,omitempty" json:"mocks"`
+	Faults    []simulator.Fault `yaml:"faults,omitempty" json:"faults"`
+	FaultSeed int64             `yaml:"faultSeed,omitempty" json:"faultSeed"`
+	// Results are the values wanted from tasks, by dot ID
+	Results map[string]Var `yaml:"results,omitempty" json:"results"`
+	// Errors are the tasks wanted to fail, by dot ID, with a substring of the
+	// error. An empty string accepts any error.
+	Errors map[string]string `yaml:"errors,omitempty" json:"errors"`
+	// Snapshot is a golden file of every task's output, relative to the
+	// suite, that the run is compared with. It is written on the first run.
+	Snapshot string `yaml:"snapshot,omitempty" json:"snapshot"`
+}
+
+// IsTaskTest reports whether the test runs a single task rather than a
+// pipeline.
+func (t Test) IsTaskTest() bool {
+	return t.Task != ""
+}
+
+// LoadFile loads a suite from a .yaml, .yml or .json file, usually named as
+// a suite (see IsSuiteFile).
+func LoadFile(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	suite, err := Load(data, filepath.Ext(path) == ".json")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	suite.File = path
+	if suite.Name == "" {
+		suite.Name = suiteName(path)
+	}
+
+	if suite.SpecFile != "" {
+		if suite.Spec, err = suite.readSpec(suite.SpecFile); err != nil {
+			return nil, err
+		}
+	}
+	if suite.ABIs != "" {
+		if suite.Registry, err = contracts.LoadPath(suite.resolve(suite.ABIs)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for i, test := range suite.Tests {
+		if test.SpecFile != "" {
+			if suite.Tests[i].Spec, err = suite.readSpec(test.SpecFile); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return suite, nil
+}
+
+// LoadFailure is the failed result standing in for the tests of a suite that
+// couldn't be loaded, so that the other suites can still run.
+func LoadFailure(path string, err error) Result {
+	return Result{
+		Suite:    suiteName(path),
+		File:     path,
+		Name:     "load",
+		Failures: []Failure{{Message: err.Error()}},
+	}
+}
+
+// suiteName names a suite without a name after its file.
+func suiteName(path string) string {
+	suffix := suiteSuffix(path)
+	if suffix == "" {
+		suffix = filepath.Ext(path)
+	}
+	return strings.TrimSuffix(filepath.Base(path), suffix)
+}
+
+func (s *Suite) readSpec(specFile string) (string, error) {
+	data, err := os.ReadFile(s.resolve(specFile))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", s.File, err)
+	}
+	return string(data), nil
+}
+
+// resolve returns the path of a file relative to the suite.
+func (s *Suite) resolve(path string) string {
+	if filepath.IsAbs(path) || s.File == "" {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(filepath.Dir(s.File), path)
+}
+
+// Files returns the paths of the files the suite reads, so that it can be
+// re-run when one of them changes: its spec files, its ABIs, which may be a
+// directory, and its tests' golden snapshots.
+func (s *Suite) Files() []string {
+	files := []string{}
+	if s.SpecFile != "" {
+		files = append(files, s.resolve(s.SpecFile))
+	}
+	if s.ABIs != "" {
+		files = append(files, s.resolve(s.ABIs))
+	}
+	for _, test := range s.Tests {
+		if test.SpecFile != "" {
+			files = append(files, s.resolve(test.SpecFile))
+		}
+		if test.Snapshot != "" {
+			files = append(files, s.resolve(test.Snapshot))
+		}
+	}
+	return files
+}
+
+// TestFiles returns the paths of the files one test reads, so that only the
+// tests reading a changed file need re-running: its spec file, or the
+// suite's if a pipeline test has no spec of its own, the suite's ABIs and
+// its golden snapshot.
+func (s *Suite) TestFiles(test Test) []string {
+	files := []string{}
+	switch {
+	case test.SpecFile != "":
+		files = append(files, s.resolve(test.SpecFile))
+	case !test.IsTaskTest() && test.Spec == "" && s.SpecFile != "":
+		files = append(files, s.resolve(s.SpecFile))
+	}
+	if s.ABIs != "" {
+		files = append(files, s.resolve(s.ABIs))
+	}
+	if test.Snapshot != "" {
+		files = append(files, s.resolve(test.Snapshot))
+	}
+	return files
+}
+
+// Load parses a suite from YAML, or JSON. Unknown fields are rejected so that
+// typos don't silently disable checks.
+func Load(data []byte, isJSON bool) (*Suite, error) {
+	suite := &Suite{}
+
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(suite); err != nil {
+			return nil, err
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(suite); err != nil {
+			return nil, err
+		}
+	}
+
+	if suite.Spec != "" && suite.SpecFile != "" {
+		return nil, fmt.Errorf("suite sets both spec and specFile")
+	}
+	for i, test := range suite.Tests {
+		if test.Name == "" {
+			return nil, fmt.Errorf("test %d has no name", i)
+		}
+		hasSpec := test.Spec != "" || test.SpecFile != "" || suite.Spec != "" || suite.SpecFile != ""
+		if !test.IsTaskTest() && !hasSpec {
+			return nil, fmt.Errorf("test %q sets neither a task nor a spec", test.Name)
+		}
+		if test.Spec != "" && test.SpecFile != "" {
+			return nil, fmt.Errorf("test %q sets both spec and specFile", test.Name)
+		}
+		if !test.IsTaskTest() && (test.ExpectNoError || test.ExpectNoPanic || test.ExpectNoTimeout) {
+			return nil, fmt.Errorf("test %q sets expectNoError, expectNoPanic or expectNoTimeout without a task", test.Name)
+		}
+		if test.ExpectError && test.ExpectNoError {
+			return nil, fmt.Errorf("test %q sets both expectError and expectNoError", test.Name)
+		}
+	}
+
+	return suite, nil
+}
+
+// Find returns the suite files among the paths, walking directories for
+// files named as suites. Files given directly are taken as suites whatever
+// their name.
+func Find(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && IsSuiteFile(p) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// suiteSuffixes are the endings of suite file names. A bare .json or .yaml
+// is more likely an ABI, a var file or a mock chain than a suite.
+var suiteSuffixes = []string{
+	".test.yaml", ".test.yml", ".test.json",
+	".suite.yaml", ".suite.yml", ".suite.json",
+}
+
+// IsSuiteFile reports whether the file is named as a suite, e.g.
+// median.test.yaml or feeds.suite.json.
+func IsSuiteFile(path string) bool {
+	return suiteSuffix(path) != ""
+}
+
+// suiteSuffix returns the suite ending of the file name, or "" if it isn't
+// named as a suite.
+func suiteSuffix(path string) string {
+	for _, suffix := range suiteSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+package testsuite
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		isJSON  bool
+		wantErr string
+	}{
+		{
+			name: "yaml task test",
+			data: `
+name: divide
+tests:
+  - name: precision
+    task: divide
+    options: { divisor: "1000" }
+    want: { value: "12.35", type: decimal }
+`,
+		},
+		{
+			name:   "json pipeline test",
+			data:   `{"spec": "a [type=any]", "tests": [{"name": "run"}]}`,
+			isJSON: true,
+		},
+		{
+			name:    "unknown yaml field",
+			data:    "tests:\n  - name: a\n    task: any\n    wnat: { value: 1 }\n",
+			wantErr: "wnat",
+		},
+		{
+			name:    "unknown json field",
+			data:    `{"tests": [{"name": "a", "task": "any", "expectErorr": true}]}`,
+			isJSON:  true,
+			wantErr: "expectErorr",
+		},
+		{
+			name:    "unnamed test",
+			data:    "tests:\n  - task: any\n",
+			wantErr: "has no name",
+		},
+		{
+			name:    "neither task nor spec",
+			data:    "tests:\n  - name: a\n",
+			wantErr: "neither a task nor a spec",
+		},
+		{
+			name:    "expectNoError without a task",
+			data:    "spec: a [type=any]\ntests:\n  - name: a\n    expectNoError: true\n",
+			wantErr: "without a task",
+		},
+		{
+			name:    "expectError and expectNoError",
+			data:    "tests:\n  - name: a\n    task: any\n    expectError: true\n    expectNoError: true\n",
+			wantErr: "both expectError and expectNoError",
+		},
+		{
+			name:    "spec and specFile",
+			data:    "tests:\n  - name: a\n    spec: a [type=any]\n    specFile: a.toml\n",
+			wantErr: "both spec and specFile",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load([]byte(test.data), test.isJSON)
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("error %v", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("error %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name      string
+		want      Var
+		got       interface{}
+		wantEqual bool
+	}{
+		{name: "equal strings", want: Var{Value: "abc", Type: "string"}, got: "abc", wantEqual: true},
+		{name: "different strings", want: Var{Value: "abc", Type: "string"}, got: "abd"},
+		{name: "equal bools", want: Var{Value: "true", Type: "bool"}, got: true, wantEqual: true},
+		{name: "string is not a bool", want: Var{Value: "true", Type: "bool"}, got: "true"},
+		{name: "null", want: Var{Type: "null"}, got: nil, wantEqual: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			failure, err := compare("differs", test.want, test.got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (failure == nil) != test.wantEqual {
+				t.Errorf("failure %v, want equal: %v", failure, test.wantEqual)
+			}
+			if failure != nil && (failure.Want == "" || failure.Got == "") {
+				t.Errorf("failure %+v doesn't show both values", failure)
+			}
+		})
+	}
+}
+
+func TestIsSuiteFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "tests/median.test.yaml", want: true},
+		{path: "median.test.yml", want: true},
+		{path: "feeds.suite.json", want: true},
+		{path: "feeds.suite.yaml", want: true},
+		{path: "abis/Oracle.json"},
+		{path: "vars.yaml"},
+		{path: "chains.yml"},
+		{path: "snapshots/feed.golden"},
+		{path: "spec.toml"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if got := IsSuiteFile(test.path); got != test.want {
+				t.Errorf("%v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	spec := write("specs/feed.toml", "a [type=any]\n")
+	abi := write("abis/Oracle.json", "[]")
+	path := write("feed.test.yaml", `
+specFile: specs/feed.toml
+abis: abis
+tests:
+  - name: run
+    snapshot: snapshots/run.golden
+  - name: other spec
+    specFile: specs/feed.toml
+`)
+
+	suite, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suite.Name != "feed" {
+		t.Errorf("name %q, want feed", suite.Name)
+	}
+	if suite.Spec != "a [type=any]\n" {
+		t.Errorf("spec %q", suite.Spec)
+	}
+
+	want := []string{spec, filepath.Dir(abi), filepath.Join(dir, "snapshots/run.golden"), spec}
+	if got := suite.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("files %v, want %v", got, want)
+	}
+
+	wantTests := [][]string{
+		{spec, filepath.Dir(abi), filepath.Join(dir, "snapshots/run.golden")},
+		{spec, filepath.Dir(abi)},
+	}
+	for i, test := range suite.Tests {
+		if got := suite.TestFiles(test); !reflect.DeepEqual(got, wantTests[i]) {
+			t.Errorf("%s: files %v, want %v", test.Name, got, wantTests[i])
+		}
+	}
+}
+
+func TestLoadFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "feed.test.yaml")
+	if err := os.WriteFile(path, []byte("tests: [\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("loaded a suite that doesn't parse")
+	}
+	result := LoadFailure(path, err)
+	if result.Suite != "feed" || result.File != path || result.Passed {
+		t.Errorf("result %+v, want a failure of the feed suite in %s", result, path)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].Message != err.Error() {
+		t.Errorf("failures %v, want %q", result.Failures, err)
+	}
+}